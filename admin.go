@@ -0,0 +1,93 @@
+package main
+
+import (
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+/* ---------- 管理接口 ---------- */
+
+// adminAuth 校验 ADMIN_TOKEN，支持 Authorization: Bearer <token> 或 X-Admin-Token 请求头
+func adminAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		got := c.GetHeader("X-Admin-Token")
+		if got == "" {
+			got = strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		}
+		if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			respondError(c, http.StatusUnauthorized, ErrUnauthorized, "管理接口认证失败", nil)
+			return
+		}
+		c.Next()
+	}
+}
+
+// 注册 /admin 路由，未配置 ADMIN_TOKEN 时不开放
+func registerAdminRoutes(r *gin.Engine) {
+	token := getEnvWithDefault("ADMIN_TOKEN", "")
+	if token == "" {
+		log.Printf("未配置 ADMIN_TOKEN，管理接口未启用")
+		return
+	}
+
+	admin := r.Group("/admin", adminAuth(token))
+	if rdb != nil { // 死信保存在 Redis 中
+		admin.GET("/dead_letters", listDeadLettersHandler)
+		admin.POST("/dead_letters/retry", retryDeadLettersHandler)
+		admin.DELETE("/dead_letters", clearDeadLettersHandler)
+	}
+	admin.GET("/export", exportBackupHandler)
+	admin.POST("/import", importBackupHandler)
+	admin.GET("/settings", getSettingsHandler)
+	admin.PATCH("/settings", patchSettingsHandler)
+	admin.GET("/aliases", listAliasesHandler)
+	admin.PUT("/aliases/:alias", putAliasHandler)
+	admin.DELETE("/aliases/:alias", deleteAliasHandler)
+	admin.GET("/audit_log", listAuditHandler)
+}
+
+// GET /admin/dead_letters?limit=100
+func listDeadLettersHandler(c *gin.Context) {
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", "100"), 10, 64)
+	if err != nil || limit <= 0 {
+		respondError(c, http.StatusBadRequest, ErrInvalidArgument, "limit 参数无效", nil)
+		return
+	}
+
+	ctx := c.Request.Context()
+	jobs, err := listDeadLetters(ctx, limit)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrInternal, "查询失败", err)
+		return
+	}
+	total, _ := rdb.LLen(ctx, keyDeadLetter).Result()
+	pending, _ := rdb.ZCard(ctx, keyRetryQueue).Result()
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   gin.H{"total": total, "pending_retries": pending, "items": jobs},
+	})
+}
+
+// POST /admin/dead_letters/retry
+func retryDeadLettersHandler(c *gin.Context) {
+	count, err := requeueDeadLetters(c.Request.Context())
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrInternal, "重新投递失败", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success", "data": gin.H{"requeued": count}})
+}
+
+// DELETE /admin/dead_letters
+func clearDeadLettersHandler(c *gin.Context) {
+	if err := rdb.Del(c.Request.Context(), keyDeadLetter).Err(); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrInternal, "清空失败", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
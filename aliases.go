@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+)
+
+/* ---------- 号码别名 ---------- */
+
+// PhoneAlias 号码别名：查询接口可以用易记的名称（如 staging-sim-3）代替号码，
+// 测试脚本不必硬编码号码，换卡后只需修改映射
+type PhoneAlias struct {
+	Alias     string `json:"alias"`
+	Phone     string `json:"phone"`
+	UpdatedAt int64  `json:"updated_at"`
+}
+
+// 别名以字母或数字开头，只包含字母、数字和 . _ -，且不能是纯数字（避免与号码混淆）
+var aliasPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+func validateAlias(alias string) error {
+	if !aliasPattern.MatchString(alias) || !isAliasCandidate(alias) {
+		return fmt.Errorf("别名只能包含字母、数字和 . _ -，不超过 64 个字符且不能是纯数字: %s", alias)
+	}
+	return nil
+}
+
+// 纯数字（可带 +）的参数一定是号码，不查询别名，避免每次查询多一次往返
+func isAliasCandidate(s string) bool {
+	return strings.TrimLeft(s, "+0123456789") != ""
+}
+
+// 未使用 Redis 和 SQL 时别名只保存在本进程内
+var memoryAliases = struct {
+	sync.RWMutex
+	m map[string]PhoneAlias
+}{m: make(map[string]PhoneAlias)}
+
+// 别名 HASH，field 为别名，值为别名 JSON（启用存储加密时为密文）
+func aliasesKey() string {
+	return redisKey("phone_aliases")
+}
+
+// resolvePhoneAlias 将查询参数中的别名换成号码，不是别名时原样返回；
+// 读取失败时只记录日志并按号码处理，不影响查询
+func resolvePhoneAlias(ctx context.Context, name string) string {
+	if !isAliasCandidate(name) {
+		return name
+	}
+	a, err := getAlias(ctx, name)
+	if err != nil {
+		log.Printf("读取号码别名 %s 失败: %v", name, err)
+		return name
+	}
+	if a == nil {
+		return name
+	}
+	return a.Phone
+}
+
+// 别名优先保存在 SQL 历史存储中，其次是 Redis
+func getAlias(ctx context.Context, alias string) (*PhoneAlias, error) {
+	if s := sqlHistoryStore(); s != nil {
+		return s.getAlias(ctx, alias)
+	}
+	if rdb == nil {
+		memoryAliases.RLock()
+		defer memoryAliases.RUnlock()
+		if a, ok := memoryAliases.m[alias]; ok {
+			return &a, nil
+		}
+		return nil, nil
+	}
+	v, err := rdb.HGet(ctx, aliasesKey(), alias).Result()
+	if err == redis.Nil {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var a PhoneAlias
+	if err := openJSON(v, &a); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// 读取全部别名，按别名排序
+func listAliases(ctx context.Context) ([]PhoneAlias, error) {
+	if s := sqlHistoryStore(); s != nil {
+		return s.listAliases(ctx)
+	}
+	aliases := []PhoneAlias{}
+	if rdb == nil {
+		memoryAliases.RLock()
+		for _, a := range memoryAliases.m {
+			aliases = append(aliases, a)
+		}
+		memoryAliases.RUnlock()
+	} else {
+		values, err := rdb.HGetAll(ctx, aliasesKey()).Result()
+		if err != nil {
+			return nil, err
+		}
+		for alias, v := range values {
+			var a PhoneAlias
+			if err := openJSON(v, &a); err != nil {
+				log.Printf("跳过无法解析的号码别名 %s: %v", alias, err)
+				continue
+			}
+			aliases = append(aliases, a)
+		}
+	}
+	sort.Slice(aliases, func(i, j int) bool { return aliases[i].Alias < aliases[j].Alias })
+	return aliases, nil
+}
+
+// 新增或覆盖别名
+func setAlias(ctx context.Context, a PhoneAlias) error {
+	if s := sqlHistoryStore(); s != nil {
+		return s.setAlias(ctx, a)
+	}
+	if rdb == nil {
+		memoryAliases.Lock()
+		defer memoryAliases.Unlock()
+		memoryAliases.m[a.Alias] = a
+		return nil
+	}
+	return rdb.HSet(ctx, aliasesKey(), a.Alias, sealJSON(a)).Err()
+}
+
+// 删除别名，不存在时返回 false
+func deleteAlias(ctx context.Context, alias string) (bool, error) {
+	if s := sqlHistoryStore(); s != nil {
+		return s.deleteAlias(ctx, alias)
+	}
+	if rdb == nil {
+		memoryAliases.Lock()
+		defer memoryAliases.Unlock()
+		_, ok := memoryAliases.m[alias]
+		delete(memoryAliases.m, alias)
+		return ok, nil
+	}
+	n, err := rdb.HDel(ctx, aliasesKey(), alias).Result()
+	return n > 0, err
+}
+
+/* ---------- SQL 别名表 ---------- */
+
+func (s *SQLStore) getAlias(ctx context.Context, alias string) (*PhoneAlias, error) {
+	a := PhoneAlias{Alias: alias}
+	err := s.db.QueryRowContext(ctx, s.rebind(`SELECT phone, updated_at FROM phone_aliases WHERE alias = ?`), alias).
+		Scan(&a.Phone, &a.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if a.Phone, err = openValue(a.Phone); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (s *SQLStore) listAliases(ctx context.Context) ([]PhoneAlias, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT alias, phone, updated_at FROM phone_aliases ORDER BY alias`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	aliases := []PhoneAlias{}
+	for rows.Next() {
+		var a PhoneAlias
+		if err := rows.Scan(&a.Alias, &a.Phone, &a.UpdatedAt); err != nil {
+			return nil, err
+		}
+		if a.Phone, err = openValue(a.Phone); err != nil {
+			return nil, err
+		}
+		aliases = append(aliases, a)
+	}
+	return aliases, rows.Err()
+}
+
+// 先删后插，避免各方言 upsert 写法不同
+func (s *SQLStore) setAlias(ctx context.Context, a PhoneAlias) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, s.rebind(`DELETE FROM phone_aliases WHERE alias = ?`), a.Alias); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, s.rebind(`INSERT INTO phone_aliases (alias, phone, updated_at) VALUES (?, ?, ?)`),
+		a.Alias, sealValue(a.Phone), a.UpdatedAt); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SQLStore) deleteAlias(ctx context.Context, alias string) (bool, error) {
+	res, err := s.db.ExecContext(ctx, s.rebind(`DELETE FROM phone_aliases WHERE alias = ?`), alias)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+/* ---------- 别名管理接口 ---------- */
+
+// GET /admin/aliases
+func listAliasesHandler(c *gin.Context) {
+	aliases, err := listAliases(c.Request.Context())
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrInternal, "读取号码别名失败", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success", "data": aliases})
+}
+
+// PUT /admin/aliases/:alias {"phone": "13800138000"}
+// 别名已存在时覆盖原来的号码
+func putAliasHandler(c *gin.Context) {
+	var req struct {
+		Phone string `json:"phone" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, ErrInvalidArgument, "参数错误", err)
+		return
+	}
+	alias := c.Param("alias")
+	if err := validateAlias(alias); err != nil {
+		respondError(c, http.StatusBadRequest, ErrInvalidArgument, "参数错误", err)
+		return
+	}
+	a := PhoneAlias{Alias: alias, Phone: strings.TrimSpace(req.Phone), UpdatedAt: time.Now().UnixMilli()}
+	if err := setAlias(c.Request.Context(), a); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrInternal, "保存号码别名失败", err)
+		return
+	}
+	log.Printf("号码别名 %s → %s", a.Alias, a.Phone)
+	c.JSON(http.StatusOK, gin.H{"status": "success", "data": a})
+}
+
+// DELETE /admin/aliases/:alias
+func deleteAliasHandler(c *gin.Context) {
+	ok, err := deleteAlias(c.Request.Context(), c.Param("alias"))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrInternal, "删除号码别名失败", err)
+		return
+	} else if !ok {
+		respondError(c, http.StatusNotFound, ErrNotFound, "号码别名不存在", nil)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/* ---------- Apprise 风格通知 URL ---------- */
+
+// 每个 URL 生成一个实例，标识为 apprise-1、apprise-2…，可用于路由规则
+func init() { registerForwarderSet("apprise", newAppriseForwarders) }
+
+// 从 APPRISE_URLS 创建转发通道，多个 URL 用逗号、空白或换行分隔
+func newAppriseForwarders() map[string]Forwarder {
+	raw := strings.NewReplacer(",", " ", "\n", " ").Replace(getEnvWithDefault("APPRISE_URLS", ""))
+	instances := make(map[string]Forwarder)
+	for i, item := range strings.Fields(raw) {
+		f, err := parseAppriseURL(item)
+		if err != nil {
+			log.Fatalf("APPRISE_URLS 第 %d 项无效: %v", i+1, err)
+		}
+		instances[fmt.Sprintf("apprise-%d", i+1)] = f
+	}
+	return instances
+}
+
+// URL 路径去掉首尾斜杠后按 / 拆分
+func appriseSegments(u *url.URL) []string {
+	var out []string
+	for _, s := range strings.Split(strings.Trim(u.Path, "/"), "/") {
+		if s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// scheme 以 s 结尾（如 gotifys、jsons）时使用 https
+func appriseHTTPBase(u *url.URL, secureScheme string) string {
+	proto := "http"
+	if u.Scheme == secureScheme {
+		proto = "https"
+	}
+	return proto + "://" + u.Host
+}
+
+// parseAppriseURL 将单个 Apprise URL 转换为内部转发通道
+func parseAppriseURL(raw string) (Forwarder, error) {
+	// tgram://{bot_token}/{chat_id}：bot token 含冒号，无法按标准 URL 解析
+	if rest, ok := strings.CutPrefix(raw, "tgram://"); ok {
+		parts := strings.Split(strings.Trim(rest, "/"), "/")
+		if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("tgram 格式应为 tgram://bot_token/chat_id")
+		}
+		if len(parts) > 2 {
+			log.Printf("Apprise tgram 只使用第一个 chat_id: %s", parts[1])
+		}
+		return (&TelegramForwarder{BotToken: parts[0], ChatID: parts[1]}).setup(), nil
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	// 保留查询参数中的 +（请求头参数 +X-Header、号码 +86…）
+	q, _ := url.ParseQuery(strings.ReplaceAll(u.RawQuery, "+", "%2B"))
+	segs := appriseSegments(u)
+	password, _ := u.User.Password()
+
+	switch u.Scheme {
+	case "slack": // slack://{tokenA}/{tokenB}/{tokenC}
+		if len(segs) != 2 {
+			return nil, fmt.Errorf("slack 格式应为 slack://tokenA/tokenB/tokenC")
+		}
+		hook := fmt.Sprintf("https://hooks.slack.com/services/%s/%s/%s", u.Host, segs[0], segs[1])
+		return (&SlackForwarder{WebhookURL: hook}).setup(), nil
+
+	case "discord": // discord://{webhook_id}/{webhook_token}
+		if len(segs) != 1 {
+			return nil, fmt.Errorf("discord 格式应为 discord://webhook_id/webhook_token")
+		}
+		hook := fmt.Sprintf("https://discord.com/api/webhooks/%s/%s", u.Host, segs[0])
+		return (&DiscordForwarder{WebhookURL: hook, Username: u.User.Username()}).setup(), nil
+
+	case "mailto", "mailtos": // mailto://{user}:{password}@{smtp_host}[:port]?to=a@b.com&from=c@d.com
+		return parseAppriseMail(u, q, password)
+
+	case "json", "jsons": // json://{host}[:port]/{path}?+X-Header=value
+		headers := make(map[string]string)
+		for k, v := range q {
+			if strings.HasPrefix(k, "+") && len(v) > 0 {
+				headers[strings.TrimPrefix(k, "+")] = v[0]
+			}
+		}
+		target := appriseHTTPBase(u, "jsons") + u.Path
+		if u.User != nil {
+			target = strings.Replace(target, "://", "://"+u.User.String()+"@", 1)
+		}
+		return &WebhookForwarder{
+			URLs:       []string{target},
+			Headers:    headers,
+			MaxRetries: 3,
+			Backoff:    time.Second,
+			client:     &http.Client{Timeout: 5 * time.Second},
+		}, nil
+
+	case "bark", "barks": // bark://{host}/{device_key}?sound=&group=
+		if len(segs) == 0 {
+			return nil, fmt.Errorf("bark 缺少 device_key")
+		}
+		group := q.Get("group")
+		if group == "" {
+			group = "sms-forward"
+		}
+		return (&BarkForwarder{
+			Server:    appriseHTTPBase(u, "barks"),
+			DeviceKey: segs[0],
+			Sound:     q.Get("sound"),
+			Group:     group,
+		}).setup(), nil
+
+	case "pover": // pover://{user_key}@{app_token}?priority=
+		if u.User == nil {
+			return nil, fmt.Errorf("pover 格式应为 pover://user_key@app_token")
+		}
+		priority, _ := strconv.Atoi(q.Get("priority"))
+		return (&PushoverForwarder{AppToken: u.Host, UserKey: u.User.Username(), DefaultPriority: priority}).setup(), nil
+
+	case "gotify", "gotifys": // gotify://{host}/{app_token}?priority=
+		if len(segs) == 0 {
+			return nil, fmt.Errorf("gotify 缺少应用 token")
+		}
+		priority := 5
+		if p, err := strconv.Atoi(q.Get("priority")); err == nil {
+			priority = p
+		}
+		base := appriseHTTPBase(u, "gotifys")
+		if len(segs) > 1 { // 部署在子路径下
+			base += "/" + strings.Join(segs[:len(segs)-1], "/")
+		}
+		return (&GotifyForwarder{ServerURL: base, AppToken: segs[len(segs)-1], Priority: priority}).setup(), nil
+
+	case "ntfy", "ntfys": // ntfy://{topic}（ntfy.sh）或 ntfy://{host}/{topic}?token=&tags=&priority=
+		server, topic := "https://ntfy.sh", u.Host
+		if len(segs) > 0 {
+			server, topic = appriseHTTPBase(u, "ntfys"), segs[0]
+		}
+		priority := 3
+		if p, err := strconv.Atoi(q.Get("priority")); err == nil {
+			priority = p
+		}
+		tags := splitAndTrim(q.Get("tags"))
+		if len(tags) == 0 {
+			tags = []string{"envelope"}
+		}
+		return (&NtfyForwarder{Server: server, Topic: topic, Token: q.Get("token"), Tags: tags, Priority: priority}).setup(), nil
+
+	case "dingtalk": // dingtalk://{token}/ 或 dingtalk://{secret}@{token}/
+		hook := "https://oapi.dingtalk.com/robot/send?access_token=" + url.QueryEscape(u.Host)
+		return (&DingTalkForwarder{WebhookURL: hook, Secret: u.User.Username()}).setup(), nil
+
+	case "feishu", "lark": // feishu://{token} / lark://{token}，可用 ?secret= 指定签名密钥
+		base := "https://open.feishu.cn"
+		if u.Scheme == "lark" {
+			base = "https://open.larksuite.com"
+		}
+		return (&FeishuForwarder{WebhookURL: base + "/open-apis/bot/v2/hook/" + u.Host, Secret: q.Get("secret")}).setup(), nil
+
+	case "schan": // schan://{sendkey}/
+		return (&ServerChanForwarder{SendKey: u.Host}).setup(), nil
+
+	case "pushdeer", "pushdeers": // pushdeer://{pushkey} 或 pushdeer://{host}/{pushkey}
+		if len(segs) == 0 {
+			return (&PushDeerForwarder{Server: "https://api2.pushdeer.com", PushKey: u.Host}).setup(), nil
+		}
+		return (&PushDeerForwarder{Server: appriseHTTPBase(u, "pushdeers"), PushKey: segs[0]}).setup(), nil
+
+	case "gchat": // gchat://{workspace}/{webhook_key}/{webhook_token}
+		if len(segs) != 2 {
+			return nil, fmt.Errorf("gchat 格式应为 gchat://workspace/key/token")
+		}
+		hook := fmt.Sprintf("https://chat.googleapis.com/v1/spaces/%s/messages?key=%s&token=%s",
+			u.Host, url.QueryEscape(segs[0]), url.QueryEscape(segs[1]))
+		return (&GoogleChatForwarder{WebhookURL: hook}).setup(), nil
+
+	case "signal", "signals": // signal://{host}:{port}/{from_number}/{to}/{to2}
+		if len(segs) < 2 {
+			return nil, fmt.Errorf("signal 格式应为 signal://host:port/from/to")
+		}
+		return (&SignalForwarder{APIURL: appriseHTTPBase(u, "signals"), Number: segs[0], Recipients: segs[1:]}).setup(), nil
+	}
+	return nil, fmt.Errorf("不支持的 Apprise 协议: %s", u.Scheme)
+}
+
+// mailto://{user}:{password}@{smtp_host}[:port]?to=&from=&mode=
+func parseAppriseMail(u *url.URL, q url.Values, password string) (Forwarder, error) {
+	host := u.Hostname()
+	if smtpHost := q.Get("smtp"); smtpHost != "" {
+		host = smtpHost
+	}
+	port := u.Port()
+	mode := "starttls"
+	if u.Scheme == "mailto" {
+		mode = "none"
+	}
+	if port == "" {
+		port = map[string]string{"none": "25", "starttls": "587"}[mode]
+	}
+	if port == "465" {
+		mode = "tls"
+	}
+	if m := q.Get("mode"); m != "" {
+		mode = strings.ToLower(m)
+	}
+
+	username := ""
+	if u.User != nil {
+		username = u.User.Username()
+	}
+	from := q.Get("from")
+	if from == "" {
+		from = username
+		if !strings.Contains(from, "@") {
+			from = username + "@" + u.Hostname()
+		}
+	}
+	to := splitAndTrim(q.Get("to"))
+	if len(to) == 0 {
+		to = []string{from}
+	}
+
+	return (&EmailForwarder{
+		Host:     host,
+		Port:     port,
+		Username: username,
+		Password: password,
+		From:     from,
+		To:       to,
+		TLSMode:  mode,
+	}).setup(), nil
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+/* ---------- S3 / MinIO 冷归档 ---------- */
+
+// archiveSource 可归档、可按保留规则清理的历史存储，目前只有 SQL 后端实现
+type archiveSource interface {
+	archiveBatch(ctx context.Context, cutoff, now int64, limit int) ([]SMSRecord, error)
+	purgeArchived(ctx context.Context, ids []int64) error
+	purgeRetained(ctx context.Context, now int64) (int64, error)
+}
+
+// s3Archiver 定期将超过保留时间的历史记录打包为 gzip 压缩的 JSONL 上传到 S3，上传成功后再删除
+type s3Archiver struct {
+	client    *s3.Client
+	bucket    string
+	prefix    string
+	after     time.Duration // 写入多久后归档
+	batchSize int
+	source    archiveSource
+}
+
+// 已启用的归档，未配置时为 nil
+var archiver *s3Archiver
+
+// 当前存储中可归档的 SQL 历史存储，未启用时返回 nil
+func historyArchiveSource() archiveSource {
+	if s := sqlHistoryStore(); s != nil {
+		return s
+	}
+	return nil
+}
+
+// 配置了 ARCHIVE_S3_BUCKET 时启动归档协程；需要启用 SQL 历史存储
+func initArchiver() {
+	bucket := getEnvWithDefault("ARCHIVE_S3_BUCKET", "")
+	if bucket == "" {
+		return
+	}
+	source := historyArchiveSource()
+	if source == nil {
+		log.Printf("归档需要启用 SQL 历史存储 (STORAGE_BACKEND=sqlite/postgres/mysql)，已跳过")
+		return
+	}
+
+	// 凭证与区域走 AWS 标准配置（AWS_ACCESS_KEY_ID、AWS_REGION 等），与 AWS 转发一致
+	cfg, err := awsconfig.LoadDefaultConfig(context.Background())
+	if err != nil {
+		log.Printf("加载AWS配置失败，归档已跳过: %v", err)
+		return
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1" // MinIO 不校验区域，但签名需要
+	}
+	endpoint := getEnvWithDefault("ARCHIVE_S3_ENDPOINT", "")
+	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+		if endpoint != "" { // MinIO 等兼容服务，默认使用 path-style 访问
+			o.BaseEndpoint = aws.String(endpoint)
+			o.UsePathStyle = getEnvWithDefault("ARCHIVE_S3_PATH_STYLE", "true") == "true"
+		}
+	})
+
+	batchSize, _ := strconv.Atoi(getEnvWithDefault("ARCHIVE_BATCH_SIZE", "1000"))
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+	a := &s3Archiver{
+		client:    client,
+		bucket:    bucket,
+		prefix:    getEnvWithDefault("ARCHIVE_S3_PREFIX", "sms-archive/"),
+		after:     getEnvDuration("ARCHIVE_AFTER", 7*24*time.Hour),
+		batchSize: batchSize,
+		source:    source,
+	}
+	interval := getEnvDuration("ARCHIVE_INTERVAL", time.Hour)
+	archiver = a
+	go a.run(interval)
+	log.Printf("S3归档已启用 (bucket: %s, 保留 %s, 间隔 %s)", bucket, a.after, interval)
+}
+
+func (a *s3Archiver) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if n, err := a.archiveOnce(context.Background()); err != nil {
+			log.Printf("S3归档失败 (本轮已归档 %d 条): %v", n, err)
+		} else if n > 0 {
+			log.Printf("S3归档: 已归档 %d 条记录", n)
+		}
+	}
+}
+
+// 分批归档全部到期记录（含保留规则到期的记录），返回已归档条数；任一批失败时停止，未删除的记录下一轮重新归档
+func (a *s3Archiver) archiveOnce(ctx context.Context) (int, error) {
+	cutoff := time.Now().Add(-a.after).UnixMilli()
+	total := 0
+	for {
+		records, err := a.source.archiveBatch(ctx, cutoff, time.Now().UnixMilli(), a.batchSize)
+		if err != nil {
+			return total, fmt.Errorf("读取待归档记录失败: %w", err)
+		}
+		if len(records) == 0 {
+			return total, nil
+		}
+		if err := a.upload(ctx, records); err != nil {
+			return total, fmt.Errorf("上传归档失败: %w", err)
+		}
+
+		ids := make([]int64, len(records))
+		for i, rec := range records {
+			ids[i] = rec.ID
+		}
+		if err := a.source.purgeArchived(ctx, ids); err != nil {
+			return total, fmt.Errorf("删除已归档记录失败: %w", err)
+		}
+		total += len(records)
+		if len(records) < a.batchSize {
+			return total, nil
+		}
+	}
+}
+
+// 归档对象的 key：<前缀>年/月/日/<首条 id>-<末条 id>.jsonl.gz，日期取首条记录的写入时间（UTC）；
+// 同一批记录的 key 固定，删除失败后重新归档只会覆盖原对象
+func (a *s3Archiver) objectKey(records []SMSRecord) string {
+	first, last := records[0], records[len(records)-1]
+	day := time.UnixMilli(first.CreatedAt).UTC().Format("2006/01/02")
+	prefix := a.prefix
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	return fmt.Sprintf("%s%s/%d-%d.jsonl.gz", prefix, day, first.ID, last.ID)
+}
+
+// 每条记录一行 JSON，gzip 压缩后上传
+func (a *s3Archiver) upload(ctx context.Context, records []SMSRecord) error {
+	return a.put(ctx, a.objectKey(records), records)
+}
+
+func (a *s3Archiver) put(ctx context.Context, key string, records []SMSRecord) error {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	enc := json.NewEncoder(zw)
+	for _, rec := range records {
+		if err := enc.Encode(rec); err != nil {
+			return err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	_, err := a.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(a.bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(buf.Bytes()),
+		ContentType: aws.String("application/gzip"), // 不设置 Content-Encoding，避免下载时被客户端自动解压
+	})
+	return err
+}
+
+// purgePhone 从全部归档对象中删除号码的记录（phone 为存储中的号码），返回删除条数：
+// 逐个下载对象，含该号码的记录时去掉后重新上传，全部为该号码的记录时删除对象
+func (a *s3Archiver) purgePhone(ctx context.Context, phone string) (int, error) {
+	removed := 0
+	pages := s3.NewListObjectsV2Paginator(a.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(a.bucket),
+		Prefix: aws.String(a.prefix),
+	})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return removed, fmt.Errorf("列出归档对象失败: %w", err)
+		}
+		for _, obj := range page.Contents {
+			n, err := a.purgeObject(ctx, aws.ToString(obj.Key), phone)
+			if err != nil {
+				return removed, fmt.Errorf("处理归档对象 %s 失败: %w", aws.ToString(obj.Key), err)
+			}
+			removed += n
+		}
+	}
+	return removed, nil
+}
+
+func (a *s3Archiver) purgeObject(ctx context.Context, key, phone string) (int, error) {
+	out, err := a.client.GetObject(ctx, &s3.GetObjectInput{Bucket: aws.String(a.bucket), Key: aws.String(key)})
+	if err != nil {
+		return 0, err
+	}
+	defer out.Body.Close()
+	zr, err := gzip.NewReader(out.Body)
+	if err != nil {
+		return 0, err
+	}
+	var kept []SMSRecord
+	removed := 0
+	dec := json.NewDecoder(zr)
+	for dec.More() {
+		var rec SMSRecord
+		if err := dec.Decode(&rec); err != nil {
+			return 0, err
+		}
+		if rec.From == phone {
+			removed++
+		} else {
+			kept = append(kept, rec)
+		}
+	}
+	switch {
+	case removed == 0:
+		return 0, nil
+	case len(kept) == 0:
+		_, err = a.client.DeleteObject(ctx, &s3.DeleteObjectInput{Bucket: aws.String(a.bucket), Key: aws.String(key)})
+	default:
+		err = a.put(ctx, key, kept)
+	}
+	return removed, err
+}
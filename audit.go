@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+/* ---------- 审计日志 ---------- */
+
+// AuditEntry 一次需要留档的管理操作，例如按号码清除数据
+type AuditEntry struct {
+	ID        string   `json:"id"`
+	Action    string   `json:"action"`
+	Subject   string   `json:"subject"`          // 操作对象；号码只记录摘要，见 auditPhoneSubject
+	Result    any      `json:"result,omitempty"` // 操作结果，如各类数据的删除数量
+	Errors    []string `json:"errors,omitempty"` // 未完成的步骤
+	RequestID string   `json:"request_id,omitempty"`
+	ClientIP  string   `json:"client_ip,omitempty"`
+	CreatedAt int64    `json:"created_at"`
+}
+
+// 审计操作
+const auditPhonePurge = "phone.purge"
+
+// 保留的审计记录条数，超出时丢弃最早的记录；0 表示不限制
+var auditLogMax int64 = 10000
+
+// 未使用 Redis 时审计记录只保存在本进程内，最新的在前
+var memoryAuditLog = struct {
+	sync.Mutex
+	entries []AuditEntry
+}{}
+
+// 审计记录 LIST，最新的在前，值为记录 JSON（启用存储加密时为密文）；不设过期时间
+func auditLogKey() string {
+	return redisKey("audit_log")
+}
+
+func initAudit() {
+	auditLogMax, _ = strconv.ParseInt(getEnvWithDefault("AUDIT_LOG_MAX", "10000"), 10, 64)
+}
+
+// 号码在审计记录中的标识：以 PHONE_HASH_KEY 为密钥的 HMAC-SHA256（未配置时密钥为空），
+// 清除数据后审计日志中不再有号码本身，需要核对时对号码计算同样的摘要
+func auditPhoneSubject(phone string) string {
+	mac := hmac.New(sha256.New, phoneHashKey)
+	mac.Write([]byte(phone))
+	return "phone:" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// writeAudit 写入一条审计记录并输出日志，返回记录 ID
+func writeAudit(ctx context.Context, c *gin.Context, entry AuditEntry) (string, error) {
+	entry.ID = randomHex(8)
+	entry.CreatedAt = time.Now().UnixMilli()
+	if c != nil {
+		entry.RequestID = c.GetString(ctxRequestID)
+		entry.ClientIP = c.ClientIP()
+	}
+	log.Printf("审计: %s %s (记录 %s, 请求 %s, 来源 %s, 未完成 %d 项)", entry.Action, entry.Subject, entry.ID, entry.RequestID, entry.ClientIP, len(entry.Errors))
+
+	if rdb == nil {
+		memoryAuditLog.Lock()
+		defer memoryAuditLog.Unlock()
+		memoryAuditLog.entries = append([]AuditEntry{entry}, memoryAuditLog.entries...)
+		if auditLogMax > 0 && int64(len(memoryAuditLog.entries)) > auditLogMax {
+			memoryAuditLog.entries = memoryAuditLog.entries[:auditLogMax]
+		}
+		return entry.ID, nil
+	}
+	pipe := rdb.TxPipeline()
+	pipe.LPush(ctx, auditLogKey(), sealJSON(entry))
+	if auditLogMax > 0 {
+		pipe.LTrim(ctx, auditLogKey(), 0, auditLogMax-1)
+	}
+	_, err := pipe.Exec(ctx)
+	return entry.ID, err
+}
+
+// 读取最近的审计记录，最新的在前
+func listAudit(ctx context.Context, limit int64) ([]AuditEntry, error) {
+	entries := []AuditEntry{}
+	if rdb == nil {
+		memoryAuditLog.Lock()
+		defer memoryAuditLog.Unlock()
+		for i, e := range memoryAuditLog.entries {
+			if int64(i) >= limit {
+				break
+			}
+			entries = append(entries, e)
+		}
+		return entries, nil
+	}
+	items, err := rdb.LRange(ctx, auditLogKey(), 0, limit-1).Result()
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		var e AuditEntry
+		if err := openJSON(item, &e); err != nil {
+			log.Printf("跳过无法解析的审计记录: %v", err)
+			continue
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// GET /admin/audit_log?limit=100
+func listAuditHandler(c *gin.Context) {
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", "100"), 10, 64)
+	if err != nil || limit <= 0 {
+		respondError(c, http.StatusBadRequest, ErrInvalidArgument, "limit 参数无效", nil)
+		return
+	}
+	entries, err := listAudit(c.Request.Context(), limit)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrInternal, "查询失败", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success", "data": entries})
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+)
+
+/* ---------- 备份导出 / 导入 ---------- */
+
+// 备份文件格式版本
+const backupVersion = 1
+
+// 每批从 SQL 读取的历史条数
+const backupHistoryBatch = 1000
+
+// BackupKey 备份中的一个 Redis key，key 不含 KEY_PREFIX，可导入到使用其他前缀的实例
+type BackupKey struct {
+	Key   string          `json:"key"`
+	Type  string          `json:"type"`             // string / hash / list / set / zset
+	TTL   int64           `json:"ttl_ms,omitempty"` // 剩余有效期（毫秒），0 表示不过期
+	Value json.RawMessage `json:"value"`
+}
+
+// zset 成员
+type backupZMember struct {
+	Member string  `json:"member"`
+	Score  float64 `json:"score"`
+}
+
+// BackupArchive 备份文件
+type BackupArchive struct {
+	Version    int         `json:"version"`
+	ExportedAt int64       `json:"exported_at"`
+	Redis      []BackupKey `json:"redis"`
+	History    []SMSRecord `json:"history"` // 仅启用 SQL 历史存储时导出
+}
+
+// 导出的 Redis key 范围（不含 KEY_PREFIX），不导出接收队列等临时数据
+func backupKeyPatterns() []string {
+	return []string{
+		"sms:*",
+		"latest_sms:*",
+		"sms_history:*",
+		"forward_status:*",
+		globEscaper.Replace(strings.TrimPrefix(keyRetryQueue, keyPrefix)),
+		globEscaper.Replace(strings.TrimPrefix(keyDeadLetter, keyPrefix)),
+	}
+}
+
+// 读取一个 key 的类型、剩余有效期和值；不支持的类型返回 nil
+func dumpRedisKey(ctx context.Context, key string) (*BackupKey, error) {
+	typ, err := rdb.Type(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+	var value any
+	switch typ {
+	case "string":
+		value, err = rdb.Get(ctx, key).Result()
+	case "hash":
+		value, err = rdb.HGetAll(ctx, key).Result()
+	case "list":
+		value, err = rdb.LRange(ctx, key, 0, -1).Result()
+	case "set":
+		value, err = rdb.SMembers(ctx, key).Result()
+	case "zset":
+		var zs []redis.Z
+		if zs, err = rdb.ZRangeWithScores(ctx, key, 0, -1).Result(); err == nil {
+			members := make([]backupZMember, len(zs))
+			for i, z := range zs {
+				members[i] = backupZMember{Member: fmt.Sprint(z.Member), Score: z.Score}
+			}
+			value = members
+		}
+	default: // 已过期（none）或非本服务写入的类型
+		return nil, nil
+	}
+	if err == redis.Nil {
+		return nil, nil // 读取期间过期
+	} else if err != nil {
+		return nil, err
+	}
+
+	entry := &BackupKey{Key: strings.TrimPrefix(key, keyPrefix), Type: typ}
+	if ttl, err := rdb.PTTL(ctx, key).Result(); err == nil && ttl > 0 {
+		entry.TTL = ttl.Milliseconds()
+	}
+	entry.Value, _ = json.Marshal(value)
+	return entry, nil
+}
+
+// 写入一个 key，已存在时覆盖
+func restoreRedisKey(ctx context.Context, entry BackupKey) error {
+	key := redisKey(entry.Key)
+	_, err := rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, key)
+		switch entry.Type {
+		case "string":
+			var v string
+			if err := json.Unmarshal(entry.Value, &v); err != nil {
+				return err
+			}
+			pipe.Set(ctx, key, v, 0)
+		case "hash":
+			var v map[string]string
+			if err := json.Unmarshal(entry.Value, &v); err != nil {
+				return err
+			}
+			if len(v) > 0 {
+				pipe.HSet(ctx, key, v)
+			}
+		case "list", "set":
+			var v []string
+			if err := json.Unmarshal(entry.Value, &v); err != nil {
+				return err
+			}
+			values := make([]interface{}, len(v))
+			for i := range v {
+				values[i] = v[i]
+			}
+			if len(values) > 0 && entry.Type == "list" {
+				pipe.RPush(ctx, key, values...)
+			} else if len(values) > 0 {
+				pipe.SAdd(ctx, key, values...)
+			}
+		case "zset":
+			var v []backupZMember
+			if err := json.Unmarshal(entry.Value, &v); err != nil {
+				return err
+			}
+			zs := make([]*redis.Z, len(v))
+			for i, m := range v {
+				zs[i] = &redis.Z{Member: m.Member, Score: m.Score}
+			}
+			if len(zs) > 0 {
+				pipe.ZAdd(ctx, key, zs...)
+			}
+		default:
+			return fmt.Errorf("不支持的类型: %s", entry.Type)
+		}
+		if entry.TTL > 0 {
+			pipe.PExpire(ctx, key, time.Duration(entry.TTL)*time.Millisecond)
+		}
+		return nil
+	})
+	return err
+}
+
+// GET /admin/export
+// 逐条写出 JSON，历史记录较多时不必一次性读入内存；中途出错时输出不完整，导入会失败
+func exportBackupHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var keys []string
+	if rdb != nil {
+		for _, pattern := range backupKeyPatterns() {
+			found, err := scanPattern(ctx, rdb, globEscaper.Replace(keyPrefix)+pattern)
+			if err != nil {
+				respondError(c, http.StatusInternalServerError, ErrInternal, "扫描 Redis 失败", err)
+				return
+			}
+			keys = append(keys, found...)
+		}
+	}
+
+	c.Header("Content-Type", "application/json; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="sms-backup-%s.json"`, time.Now().Format("20060102-150405")))
+	w := c.Writer
+	fmt.Fprintf(w, `{"version":%d,"exported_at":%d,"redis":[`, backupVersion, time.Now().UnixMilli())
+
+	written := 0
+	for _, key := range keys {
+		entry, err := dumpRedisKey(ctx, key)
+		if err != nil {
+			log.Printf("导出失败: 读取 %s 出错: %v", key, err)
+			return
+		}
+		if entry == nil {
+			continue
+		}
+		if written > 0 {
+			w.WriteString(",")
+		}
+		data, _ := json.Marshal(entry)
+		w.Write(data)
+		written++
+	}
+
+	w.WriteString(`],"history":[`)
+	if s := sqlHistoryStore(); s != nil {
+		var afterID int64
+		first := true
+		for {
+			records, err := s.exportHistory(ctx, afterID, backupHistoryBatch)
+			if err != nil {
+				log.Printf("导出失败: 读取历史出错: %v", err)
+				return
+			}
+			for _, rec := range records {
+				if !first {
+					w.WriteString(",")
+				}
+				first = false
+				data, _ := json.Marshal(rec)
+				w.Write(data)
+			}
+			if len(records) < backupHistoryBatch {
+				break
+			}
+			afterID = records[len(records)-1].ID
+		}
+	}
+	w.WriteString("]}")
+}
+
+// POST /admin/import
+// 请求体为导出的备份文件；Redis key 覆盖写入，历史记录跳过已存在的
+func importBackupHandler(c *gin.Context) {
+	var archive BackupArchive
+	if err := json.NewDecoder(c.Request.Body).Decode(&archive); err != nil {
+		respondError(c, http.StatusBadRequest, ErrInvalidArgument, "备份文件解析失败", err)
+		return
+	}
+	if archive.Version != backupVersion {
+		respondError(c, http.StatusBadRequest, ErrInvalidArgument, "不支持的备份版本", fmt.Sprintf("version=%d", archive.Version))
+		return
+	}
+
+	ctx := c.Request.Context()
+	restored, skipped := 0, 0
+	if rdb != nil {
+		for _, entry := range archive.Redis {
+			if err := restoreRedisKey(ctx, entry); err != nil {
+				log.Printf("导入 Redis key %s 失败: %v", entry.Key, err)
+				skipped++
+				continue
+			}
+			restored++
+		}
+	} else {
+		skipped += len(archive.Redis)
+	}
+
+	imported := 0
+	if s := sqlHistoryStore(); s != nil {
+		n, err := s.importHistory(ctx, archive.History)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, ErrInternal, "导入历史失败", err)
+			return
+		}
+		imported = n
+	} else {
+		skipped += len(archive.History)
+	}
+
+	log.Printf("导入备份完成: Redis key %d 个, 历史 %d 条, 跳过 %d", restored, imported, skipped)
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   gin.H{"redis_keys": restored, "history": imported, "skipped": skipped},
+	})
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"log"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+/* ---------- bbolt 嵌入式存储 ---------- */
+
+var (
+	boltBucketSMS    = []byte("sms")    // key: 号码 + \x00 + 接收时间（大端 8 字节），value: boltEntry JSON
+	boltBucketLatest = []byte("latest") // key: 号码，value: sms 桶中最新记录的 key
+)
+
+// bbolt 中保存的记录，ExpiresAt 用于模拟 Redis 的 TTL
+type boltEntry struct {
+	Record    SMSRecord `json:"record"`
+	ExpiresAt int64     `json:"expires_at"` // 毫秒时间戳
+}
+
+// BoltStorage 单文件嵌入式存储，无需 Redis，适合小型 VPS / 树莓派部署
+type BoltStorage struct {
+	db *bolt.DB
+}
+
+// 打开 bbolt 数据库并启动过期清理协程
+func openBoltStorage(path string, sweepInterval time.Duration) (*BoltStorage, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{boltBucketSMS, boltBucketLatest} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	s := &BoltStorage{db: db}
+	go s.runSweeper(sweepInterval)
+	return s, nil
+}
+
+// 号码的 key 前缀
+func boltPhonePrefix(phone string) []byte {
+	return append([]byte(phone), 0)
+}
+
+func boltSMSKey(phone string, receivedAt int64) []byte {
+	return binary.BigEndian.AppendUint64(boltPhonePrefix(phone), uint64(receivedAt))
+}
+
+// 定位到小于 end 的最后一个 key，用于从新到旧遍历号码的记录
+func boltSeekBefore(c *bolt.Cursor, end []byte) ([]byte, []byte) {
+	if k, _ := c.Seek(end); k == nil {
+		return c.Last()
+	}
+	return c.Prev()
+}
+
+// 解析记录并带上缓存过期时间，已过期返回 false
+func decodeBoltEntry(data []byte, now int64) (SMSRecord, bool) {
+	var e boltEntry
+	if err := json.Unmarshal(data, &e); err != nil || e.ExpiresAt <= now {
+		return SMSRecord{}, false
+	}
+	e.Record.ExpiresAt = e.ExpiresAt
+	return e.Record, true
+}
+
+// SaveSMS 与 Redis 一致，不含验证码的短信不保存；记录到期即删除，已过期的记录也不保存
+func (s *BoltStorage) SaveSMS(ctx context.Context, rec SMSRecord) error {
+	if rec.Code == "" || rec.ttl() <= 0 {
+		return nil
+	}
+	key := boltSMSKey(rec.From, rec.ReceivedAt)
+	data, _ := json.Marshal(boltEntry{Record: rec, ExpiresAt: time.Now().Add(rec.ttl()).UnixMilli()})
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(boltBucketSMS).Put(key, data); err != nil {
+			return err
+		}
+		if err := tx.Bucket(boltBucketLatest).Put([]byte(rec.From), key); err != nil {
+			return err
+		}
+		return boltTrimHistory(tx, rec.From)
+	})
+}
+
+// 号码记录超过 SMS_HISTORY_MAX 时删除最早的记录
+func boltTrimHistory(tx *bolt.Tx, phone string) error {
+	if smsHistoryMax <= 0 {
+		return nil
+	}
+	b := tx.Bucket(boltBucketSMS)
+	prefix := boltPhonePrefix(phone)
+
+	var stale [][]byte
+	kept := 0
+	c := b.Cursor()
+	k, _ := boltSeekBefore(c, append(bytes.Clone(prefix), 0xff))
+	for ; k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Prev() {
+		if kept++; kept > smsHistoryMax {
+			stale = append(stale, bytes.Clone(k))
+		}
+	}
+	for _, k := range stale {
+		if err := b.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *BoltStorage) GetLatest(ctx context.Context, phone string) (*SMSRecord, error) {
+	var rec *SMSRecord
+	err := s.db.View(func(tx *bolt.Tx) error {
+		key := tx.Bucket(boltBucketLatest).Get([]byte(phone))
+		if key == nil {
+			return nil
+		}
+		if r, ok := decodeBoltEntry(tx.Bucket(boltBucketSMS).Get(key), time.Now().UnixMilli()); ok {
+			rec = &r
+		}
+		return nil
+	})
+	return rec, err
+}
+
+// GetHistory 从号码前缀的末尾向前遍历，key 按接收时间排序，无需额外排序
+func (s *BoltStorage) GetHistory(ctx context.Context, phone string, limit int, before int64) ([]SMSRecord, error) {
+	records := []SMSRecord{}
+	prefix := boltPhonePrefix(phone)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(boltBucketSMS).Cursor()
+		now := time.Now().UnixMilli()
+
+		// 从 before（或前缀末尾）之前的最后一个 key 开始向前取
+		end := append(bytes.Clone(prefix), 0xff)
+		if before > 0 {
+			end = boltSMSKey(phone, before)
+		}
+		k, v := boltSeekBefore(c, end)
+		for ; k != nil && bytes.HasPrefix(k, prefix) && len(records) < limit; k, v = c.Prev() {
+			if rec, ok := decodeBoltEntry(v, now); ok {
+				records = append(records, rec)
+			}
+		}
+		return nil
+	})
+	return records, err
+}
+
+func (s *BoltStorage) Delete(ctx context.Context, phone string) error {
+	prefix := boltPhonePrefix(phone)
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(boltBucketSMS)
+		var keys [][]byte // 遍历时删除会跳过元素，先收集再删除
+		c := b.Cursor()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			keys = append(keys, bytes.Clone(k))
+		}
+		for _, k := range keys {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		return tx.Bucket(boltBucketLatest).Delete([]byte(phone))
+	})
+}
+
+// ConsumeLatest 在同一个写事务中读取并删除 latest 指针，记录本身保留为历史
+func (s *BoltStorage) ConsumeLatest(ctx context.Context, phone string) (*SMSRecord, error) {
+	var rec *SMSRecord
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		latest := tx.Bucket(boltBucketLatest)
+		key := latest.Get([]byte(phone))
+		if key == nil {
+			return nil
+		}
+		if r, ok := decodeBoltEntry(tx.Bucket(boltBucketSMS).Get(key), time.Now().UnixMilli()); ok {
+			rec = &r
+		}
+		return latest.Delete([]byte(phone))
+	})
+	return rec, err
+}
+
+func (s *BoltStorage) DeleteRecord(ctx context.Context, phone string, receivedAt int64) (bool, error) {
+	key := boltSMSKey(phone, receivedAt)
+	found := false
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(boltBucketSMS)
+		if found = b.Get(key) != nil; !found {
+			return nil
+		}
+		if err := b.Delete(key); err != nil {
+			return err
+		}
+		latest := tx.Bucket(boltBucketLatest)
+		if bytes.Equal(latest.Get([]byte(phone)), key) {
+			return latest.Delete([]byte(phone))
+		}
+		return nil
+	})
+	return found, err
+}
+
+// FindByCode 遍历全部记录比对，适用于单机的小规模部署
+func (s *BoltStorage) FindByCode(ctx context.Context, code string, since int64, limit int) ([]SMSRecord, error) {
+	return findByCodeScan(ctx, s, code, since, limit)
+}
+
+// ListActivePhones 遍历全部记录统计，适用于单机的小规模部署
+func (s *BoltStorage) ListActivePhones(ctx context.Context) ([]ActivePhone, error) {
+	return listActivePhonesScan(ctx, s, s.GetLatest)
+}
+
+// 遍历全部未过期记录（供迁移使用），同一号码按接收时间升序
+func (s *BoltStorage) eachRecord(ctx context.Context, fn func(SMSRecord) error) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		now := time.Now().UnixMilli()
+		return tx.Bucket(boltBucketSMS).ForEach(func(k, v []byte) error {
+			var e boltEntry
+			if err := json.Unmarshal(v, &e); err != nil || e.ExpiresAt <= now {
+				return nil
+			}
+			e.Record.ExpiresAt = e.ExpiresAt
+			return fn(e.Record)
+		})
+	})
+}
+
+// 定期删除过期记录，模拟 Redis 的 key 过期
+func (s *BoltStorage) runSweeper(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if n, err := s.sweep(); err != nil {
+			log.Printf("bbolt过期清理失败: %v", err)
+		} else if n > 0 {
+			log.Printf("bbolt过期清理: 删除 %d 条记录", n)
+		}
+	}
+}
+
+func (s *BoltStorage) sweep() (int, error) {
+	removed := 0
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		now := time.Now().UnixMilli()
+		smsBucket, latest := tx.Bucket(boltBucketSMS), tx.Bucket(boltBucketLatest)
+
+		var expired [][]byte
+		if err := smsBucket.ForEach(func(k, v []byte) error {
+			if _, ok := decodeBoltEntry(v, now); !ok {
+				expired = append(expired, bytes.Clone(k))
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+		for _, k := range expired {
+			if err := smsBucket.Delete(k); err != nil {
+				return err
+			}
+		}
+
+		// 指向已删除记录的 latest 一并清理
+		var stale [][]byte
+		if err := latest.ForEach(func(phone, key []byte) error {
+			if smsBucket.Get(key) == nil {
+				stale = append(stale, bytes.Clone(phone))
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+		for _, phone := range stale {
+			if err := latest.Delete(phone); err != nil {
+				return err
+			}
+		}
+		removed = len(expired)
+		return nil
+	})
+	return removed, err
+}
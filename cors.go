@@ -0,0 +1,110 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+/* ---------- 跨域（CORS） ---------- */
+
+// CORSConfig 跨域策略，允许浏览器中的看板和测试工具直接调用接口
+type CORSConfig struct {
+	Origins          []string // 允许的来源，"*" 表示任意来源，支持 https://*.example.com 形式的子域名通配
+	Methods          string
+	Headers          string
+	ExposeHeaders    string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+// 从环境变量读取跨域策略，未配置 CORS_ALLOWED_ORIGINS 时返回 nil（不启用）
+func loadCORSConfig() *CORSConfig {
+	origins := splitAndTrim(getEnvWithDefault("CORS_ALLOWED_ORIGINS", ""))
+	if len(origins) == 0 {
+		return nil
+	}
+	cfg := &CORSConfig{
+		Origins:          origins,
+		Methods:          strings.Join(splitAndTrim(getEnvWithDefault("CORS_ALLOWED_METHODS", "GET,POST,PATCH,DELETE,OPTIONS")), ", "),
+		Headers:          strings.Join(splitAndTrim(getEnvWithDefault("CORS_ALLOWED_HEADERS", "Content-Type,Authorization,X-Admin-Token,If-None-Match")), ", "),
+		ExposeHeaders:    strings.Join(splitAndTrim(getEnvWithDefault("CORS_EXPOSE_HEADERS", "ETag,X-Request-ID,X-RateLimit-Limit,X-RateLimit-Remaining,X-RateLimit-Reset,Retry-After")), ", "),
+		AllowCredentials: getEnvWithDefault("CORS_ALLOW_CREDENTIALS", "false") == "true",
+		MaxAge:           getEnvDuration("CORS_MAX_AGE", 12*time.Hour),
+	}
+	if cfg.AllowCredentials && cfg.allowsAnyOrigin() {
+		log.Printf("警告: CORS_ALLOW_CREDENTIALS 与任意来源同时启用，将回显请求的 Origin，任意网站都可以携带凭据调用接口")
+	}
+	return cfg
+}
+
+func (cfg *CORSConfig) allowsAnyOrigin() bool {
+	for _, o := range cfg.Origins {
+		if o == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// 来源是否在允许列表中
+func (cfg *CORSConfig) allowOrigin(origin string) bool {
+	for _, o := range cfg.Origins {
+		if o == "*" || strings.EqualFold(o, origin) {
+			return true
+		}
+		if scheme, domain, ok := strings.Cut(o, "://*."); ok && // https://*.example.com
+			strings.HasPrefix(origin, scheme+"://") && strings.HasSuffix(strings.ToLower(origin), "."+strings.ToLower(domain)) {
+			return true
+		}
+	}
+	return false
+}
+
+// corsMiddleware 为允许的来源添加跨域响应头，并直接响应预检请求；需在注册路由前通过 r.Use 启用，
+// 这样未注册 OPTIONS 方法的路由也能完成预检
+func corsMiddleware(cfg *CORSConfig) gin.HandlerFunc {
+	maxAge := strconv.Itoa(int(cfg.MaxAge.Seconds()))
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+		c.Writer.Header().Add("Vary", "Origin")
+		if !cfg.allowOrigin(origin) {
+			if c.Request.Method == http.MethodOptions {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Next() // 不加跨域头，由浏览器拦截响应
+			return
+		}
+
+		h := c.Writer.Header()
+		if cfg.allowsAnyOrigin() && !cfg.AllowCredentials {
+			h.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			h.Set("Access-Control-Allow-Origin", origin)
+		}
+		if cfg.AllowCredentials {
+			h.Set("Access-Control-Allow-Credentials", "true")
+		}
+		if cfg.ExposeHeaders != "" {
+			h.Set("Access-Control-Expose-Headers", cfg.ExposeHeaders)
+		}
+
+		if c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != "" {
+			h.Set("Access-Control-Allow-Methods", cfg.Methods)
+			h.Set("Access-Control-Allow-Headers", cfg.Headers)
+			h.Set("Access-Control-Max-Age", maxAge)
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+/* ---------- 翻页游标 ---------- */
+
+// 列表接口返回的 next_cursor 是不透明的字符串，内容为上一页最后一条记录的时间与 key，
+// 下一页从该记录之后开始。与 offset / before 相比，翻页过程中有新记录写入或接收时间相同的记录也不会重复或遗漏
+
+// 游标所属的接口，不同接口的游标不能混用
+const (
+	cursorHistory = "history"
+	cursorSearch  = "search"
+	cursorDevices = "devices"
+)
+
+// 同一毫秒内同一号码的记录通常只有一条，历史接口使用游标时只多取这么多条用于跳过
+const cursorTieSlack = 16
+
+type pageCursor struct {
+	Scope string `json:"s"`
+	At    int64  `json:"t"` // 接收时间或创建时间（毫秒）
+	Key   string `json:"k"` // 记录 key：SQL 为自增 ID，其他后端为 cache key，设备为设备 ID
+}
+
+var errInvalidCursor = errors.New("cursor 无效或不属于该接口")
+
+func encodeCursor(scope string, at int64, key string) string {
+	data, _ := json.Marshal(pageCursor{Scope: scope, At: at, Key: key})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(scope, s string) (*pageCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	var cur pageCursor
+	if err := json.Unmarshal(data, &cur); err != nil || cur.Scope != scope || cur.At <= 0 {
+		return nil, errInvalidCursor
+	}
+	return &cur, nil
+}
+
+// 读取 cursor 查询参数，未传时返回 nil；无效时已写入 400 响应，返回 false
+func bindCursor(c *gin.Context, scope string) (*pageCursor, bool) {
+	raw := c.Query("cursor")
+	if raw == "" {
+		return nil, true
+	}
+	cur, err := decodeCursor(scope, raw)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrInvalidCursor, "cursor 参数错误", err)
+		return nil, false
+	}
+	return cur, true
+}
+
+// 短信记录在游标中的 key
+func recordCursorKey(rec SMSRecord) string {
+	if rec.ID > 0 {
+		return strconv.FormatInt(rec.ID, 10)
+	}
+	if rec.CacheKey != "" {
+		return rec.CacheKey
+	}
+	return smsCacheKey(rec.From, rec.ReceivedAt)
+}
+
+func recordCursor(scope string, rec SMSRecord) string {
+	return encodeCursor(scope, rec.ReceivedAt, recordCursorKey(rec))
+}
+
+// after 跳过 records 开头与游标时间相同、排在游标记录之前（含）的记录；records 需按接收时间倒序、从游标时间（含）开始读取。
+// 接收时间相同的记录按各后端的固定顺序排列，跳到游标记录为止，游标记录已被删除时跳过全部同一时间的记录
+func (cur *pageCursor) after(records []SMSRecord) []SMSRecord {
+	tie := 0
+	for tie < len(records) && records[tie].ReceivedAt == cur.At {
+		if recordCursorKey(records[tie]) == cur.Key {
+			return records[tie+1:]
+		}
+		tie++
+	}
+	return records[tie:]
+}
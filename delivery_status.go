@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+/* ---------- 转发投递状态 ---------- */
+
+// 投递状态
+const (
+	deliveryPending   = "pending"   // 已进入队列，尚未投递
+	deliveryRetrying  = "retrying"  // 投递失败，等待重试
+	deliveryDelivered = "delivered" // 投递成功
+	deliveryFailed    = "failed"    // 投递失败且未启用重试
+	deliveryDead      = "dead"      // 超过最大重试次数，已进入死信列表
+)
+
+// DeliveryStatus 单条短信在某个通道上的投递情况
+type DeliveryStatus struct {
+	Channel     string `json:"channel"`
+	Status      string `json:"status"`
+	Attempts    int    `json:"attempts"`
+	LastError   string `json:"last_error,omitempty"`
+	UpdatedAt   int64  `json:"updated_at"`
+	DeliveredAt int64  `json:"delivered_at,omitempty"`
+}
+
+// 投递状态保存时长
+var deliveryStatusTTL = 24 * time.Hour
+
+// 投递状态的 Redis key，HASH 结构，field 为通道标识
+func deliveryStatusKey(cacheKey string) string {
+	return redisKey("forward_status:" + cacheKey)
+}
+
+func initDeliveryStatus() {
+	deliveryStatusTTL = getEnvDuration("FORWARD_STATUS_TTL", 24*time.Hour)
+}
+
+// updateDeliveryStatus 读取并更新某条短信在某个通道上的投递状态；
+// 没有 cache key 的消息（如不含验证码的告警短信）及未使用 Redis 时不记录
+func updateDeliveryStatus(cacheKey, channel string, update func(*DeliveryStatus)) {
+	if cacheKey == "" || channel == "" || rdb == nil {
+		return
+	}
+	ctx := context.Background()
+	key := deliveryStatusKey(cacheKey)
+
+	st := DeliveryStatus{Channel: channel}
+	if data, err := rdb.HGet(ctx, key, channel).Result(); err == nil {
+		_ = json.Unmarshal([]byte(data), &st)
+	}
+	update(&st)
+	st.UpdatedAt = time.Now().UnixMilli()
+
+	data, _ := json.Marshal(st)
+	pipe := rdb.TxPipeline()
+	pipe.HSet(ctx, key, channel, data)
+	pipe.Expire(ctx, key, deliveryStatusTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("记录投递状态失败 (%s/%s): %v", cacheKey, channel, err)
+	}
+}
+
+// 任务进入队列
+func markDeliveryPending(cacheKey, channel string) {
+	updateDeliveryStatus(cacheKey, channel, func(st *DeliveryStatus) {
+		st.Status = deliveryPending
+	})
+}
+
+// 记录一次投递结果
+func markDeliveryResult(cacheKey, channel string, err error) {
+	updateDeliveryStatus(cacheKey, channel, func(st *DeliveryStatus) {
+		st.Attempts++
+		if err != nil {
+			st.Status = deliveryRetrying
+			if !retryCfg.Enabled {
+				st.Status = deliveryFailed
+			}
+			st.LastError = err.Error()
+			return
+		}
+		st.Status = deliveryDelivered
+		st.DeliveredAt = time.Now().UnixMilli()
+	})
+}
+
+// 任务进入死信列表
+func markDeliveryDead(cacheKey, channel string) {
+	updateDeliveryStatus(cacheKey, channel, func(st *DeliveryStatus) {
+		st.Status = deliveryDead
+	})
+}
+
+// GET /api/forward_status/:cache_key
+func getForwardStatus(c *gin.Context) {
+	if rdb == nil {
+		respondError(c, http.StatusServiceUnavailable, ErrRedisRequired, "未使用 Redis，不记录投递状态", nil)
+		return
+	}
+	cacheKey := c.Param("cache_key")
+	items, err := rdb.HGetAll(c.Request.Context(), deliveryStatusKey(cacheKey)).Result()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrInternal, "查询失败", err)
+		return
+	}
+	if len(items) == 0 {
+		respondError(c, http.StatusNotFound, ErrNotFound, "未找到该短信的投递记录", nil)
+		return
+	}
+
+	channels := make([]DeliveryStatus, 0, len(items))
+	for _, data := range items {
+		var st DeliveryStatus
+		if err := json.Unmarshal([]byte(data), &st); err == nil {
+			channels = append(channels, st)
+		}
+	}
+	sort.Slice(channels, func(i, j int) bool { return channels[i].Channel < channels[j].Channel })
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   gin.H{"cache_key": cacheKey, "channels": channels},
+	})
+}
@@ -0,0 +1,342 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+)
+
+/* ---------- 转发设备 ---------- */
+
+// Device 注册的转发设备（装有短信转发应用的手机）：接收短信时携带设备令牌，
+// 服务端校验后把设备 ID 记录到短信上，停用的设备不能再上报
+type Device struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Phone      string `json:"phone,omitempty"` // 设备中 SIM 卡的号码
+	Token      string `json:"token,omitempty"` // 只在注册时返回
+	Disabled   bool   `json:"disabled"`
+	CreatedAt  int64  `json:"created_at"`
+	DisabledAt int64  `json:"disabled_at,omitempty"`
+}
+
+// 保存的设备信息，令牌只保存 SHA-256 摘要
+type deviceEntry struct {
+	Device
+	TokenHash string `json:"token_hash"`
+}
+
+// 接收请求中传递设备令牌的请求头，也可使用请求体的 device_token 字段
+const headerDeviceToken = "X-Device-Token"
+
+// 为 true 时接收短信必须携带有效的设备令牌
+var deviceAuthRequired bool
+
+// 未使用 Redis 时设备只保存在本进程内，重启后需重新注册
+var memoryDevices = struct {
+	sync.RWMutex
+	m map[string]deviceEntry // 设备 ID → 设备
+}{m: make(map[string]deviceEntry)}
+
+// 设备 HASH，field 为设备 ID，值为设备 JSON（启用存储加密时为密文）
+func devicesKey() string {
+	return redisKey("devices")
+}
+
+// 令牌摘要 → 设备 ID 的索引，用于接收短信时查找设备
+func deviceTokensKey() string {
+	return redisKey("device_tokens")
+}
+
+func initDevices() {
+	deviceAuthRequired = getEnvWithDefault("DEVICE_AUTH_REQUIRED", "false") == "true"
+	if deviceAuthRequired && getEnvWithDefault("ADMIN_TOKEN", "") == "" {
+		log.Fatalf("DEVICE_AUTH_REQUIRED=true 需要配置 ADMIN_TOKEN，否则无法注册设备")
+	}
+}
+
+func hashDeviceToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// 读取全部设备，按注册时间排序
+func listDevices(ctx context.Context) ([]Device, error) {
+	devices := []Device{}
+	if rdb == nil {
+		memoryDevices.RLock()
+		defer memoryDevices.RUnlock()
+		for _, e := range memoryDevices.m {
+			devices = append(devices, e.Device)
+		}
+	} else {
+		values, err := rdb.HGetAll(ctx, devicesKey()).Result()
+		if err != nil {
+			return nil, err
+		}
+		for id, v := range values {
+			var e deviceEntry
+			if err := openJSON(v, &e); err != nil {
+				log.Printf("跳过无法解析的设备 %s: %v", id, err)
+				continue
+			}
+			devices = append(devices, e.Device)
+		}
+	}
+	sort.Slice(devices, func(i, j int) bool {
+		if devices[i].CreatedAt != devices[j].CreatedAt {
+			return devices[i].CreatedAt < devices[j].CreatedAt
+		}
+		return devices[i].ID < devices[j].ID
+	})
+	return devices, nil
+}
+
+// 按 ID 读取设备，不存在时返回 nil
+func getDevice(ctx context.Context, id string) (*deviceEntry, error) {
+	if rdb == nil {
+		memoryDevices.RLock()
+		defer memoryDevices.RUnlock()
+		if e, ok := memoryDevices.m[id]; ok {
+			return &e, nil
+		}
+		return nil, nil
+	}
+	v, err := rdb.HGet(ctx, devicesKey(), id).Result()
+	if err == redis.Nil {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var e deviceEntry
+	if err := openJSON(v, &e); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// 按令牌查找设备，令牌无效时返回 nil
+func findDeviceByToken(ctx context.Context, token string) (*deviceEntry, error) {
+	hash := hashDeviceToken(token)
+	if rdb == nil {
+		memoryDevices.RLock()
+		defer memoryDevices.RUnlock()
+		for _, e := range memoryDevices.m {
+			if e.TokenHash == hash {
+				return &e, nil
+			}
+		}
+		return nil, nil
+	}
+	id, err := rdb.HGet(ctx, deviceTokensKey(), hash).Result()
+	if err == redis.Nil {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return getDevice(ctx, id)
+}
+
+func saveDevice(ctx context.Context, e deviceEntry) error {
+	e.Token = ""
+	if rdb == nil {
+		memoryDevices.Lock()
+		defer memoryDevices.Unlock()
+		memoryDevices.m[e.ID] = e
+		return nil
+	}
+	// 两个 key 不在同一个 slot，不使用事务
+	_, err := rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, devicesKey(), e.ID, sealJSON(e))
+		pipe.HSet(ctx, deviceTokensKey(), e.TokenHash, e.ID)
+		return nil
+	})
+	return err
+}
+
+// 设备令牌校验失败的原因，HTTP 与 gRPC 接收接口分别转换为各自的错误响应
+type deviceAuthError struct {
+	status  int
+	code    ErrorCode
+	message string
+	details any
+}
+
+// 校验设备令牌，返回设备 ID；令牌为空且未要求设备认证时返回空
+func verifyDeviceToken(ctx context.Context, token string) (string, *deviceAuthError) {
+	if token == "" {
+		if deviceAuthRequired {
+			return "", &deviceAuthError{http.StatusUnauthorized, ErrDeviceTokenNeeded, "缺少设备令牌", nil}
+		}
+		return "", nil
+	}
+	e, err := findDeviceByToken(ctx, token)
+	if err != nil {
+		return "", &deviceAuthError{http.StatusInternalServerError, ErrInternal, "读取设备失败", err}
+	}
+	if e == nil {
+		return "", &deviceAuthError{http.StatusUnauthorized, ErrDeviceTokenBad, "设备令牌无效", nil}
+	}
+	if e.Disabled {
+		return "", &deviceAuthError{http.StatusForbidden, ErrDeviceDisabled, "设备已停用", e.ID}
+	}
+	return e.ID, nil
+}
+
+// 校验接收请求携带的设备令牌，返回设备 ID；未携带令牌且未要求设备认证时返回空。
+// 校验失败时已写入响应，返回 false
+func authenticateDevice(c *gin.Context, bodyToken string) (string, bool) {
+	token := c.GetHeader(headerDeviceToken)
+	if token == "" {
+		token = bodyToken
+	}
+	id, authErr := verifyDeviceToken(c.Request.Context(), token)
+	if authErr != nil {
+		respondError(c, authErr.status, authErr.code, authErr.message, authErr.details)
+		return "", false
+	}
+	return id, true
+}
+
+/* ---------- 设备接口 ---------- */
+
+// 设备接口需要管理令牌，未配置 ADMIN_TOKEN 时不注册
+func registerDeviceRoutes(api *gin.RouterGroup) {
+	token := getEnvWithDefault("ADMIN_TOKEN", "")
+	if token == "" {
+		return
+	}
+	devices := api.Group("/devices", adminAuth(token))
+	devices.POST("", createDevice)
+	devices.GET("", listDevicesHandler)
+	devices.PATCH("/:id", updateDevice)
+}
+
+// 注册设备的请求体
+type deviceRequest struct {
+	Name  string `json:"name" binding:"required"`
+	Phone string `json:"phone"`
+}
+
+// POST /api/devices
+// 响应中的 token 只返回这一次，需配置到设备的转发应用中
+func createDevice(c *gin.Context) {
+	var req deviceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, ErrInvalidArgument, "参数错误", err)
+		return
+	}
+	token := randomHex(24)
+	e := deviceEntry{
+		Device: Device{
+			ID:        randomHex(8),
+			Name:      strings.TrimSpace(req.Name),
+			Phone:     strings.TrimSpace(req.Phone),
+			CreatedAt: time.Now().UnixMilli(),
+		},
+		TokenHash: hashDeviceToken(token),
+	}
+	if err := saveDevice(c.Request.Context(), e); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrInternal, "保存设备失败", err)
+		return
+	}
+	log.Printf("已注册设备 %s (%s)", e.ID, e.Name)
+	dev := e.Device
+	dev.Token = token
+	c.JSON(http.StatusCreated, gin.H{"status": "success", "data": dev})
+}
+
+// GET /api/devices?limit=100&cursor=<上一页的 next_cursor>
+// 按注册时间排序
+func listDevicesHandler(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit <= 0 {
+		respondError(c, http.StatusBadRequest, ErrInvalidArgument, "limit 参数错误", nil)
+		return
+	}
+	if limit > 500 {
+		limit = 500
+	}
+	cursor, ok := bindCursor(c, cursorDevices)
+	if !ok {
+		return
+	}
+
+	devices, err := listDevices(c.Request.Context())
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrInternal, "读取设备失败", err)
+		return
+	}
+	if cursor != nil {
+		i := sort.Search(len(devices), func(i int) bool {
+			d := devices[i]
+			return d.CreatedAt > cursor.At || (d.CreatedAt == cursor.At && d.ID > cursor.Key)
+		})
+		devices = devices[i:]
+	}
+	hasMore := len(devices) > limit
+	if hasMore {
+		devices = devices[:limit]
+	}
+	pagination := gin.H{"limit": limit, "has_more": hasMore}
+	if hasMore {
+		last := devices[len(devices)-1]
+		pagination["next_cursor"] = encodeCursor(cursorDevices, last.CreatedAt, last.ID)
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success", "data": devices, "pagination": pagination})
+}
+
+// 修改设备的请求体，只包含需要修改的字段
+type deviceUpdate struct {
+	Name     *string `json:"name"`
+	Disabled *bool   `json:"disabled"`
+}
+
+// PATCH /api/devices/:id {"disabled": true}
+// 停用后该设备的令牌立即失效，重新启用后恢复
+func updateDevice(c *gin.Context) {
+	var req deviceUpdate
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, ErrInvalidArgument, "参数错误", err)
+		return
+	}
+	e, err := getDevice(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrInternal, "读取设备失败", err)
+		return
+	}
+	if e == nil {
+		respondError(c, http.StatusNotFound, ErrNotFound, "设备不存在", nil)
+		return
+	}
+	if req.Name != nil {
+		if name := strings.TrimSpace(*req.Name); name != "" {
+			e.Name = name
+		}
+	}
+	if req.Disabled != nil && *req.Disabled != e.Disabled {
+		e.Disabled = *req.Disabled
+		if e.Disabled {
+			e.DisabledAt = time.Now().UnixMilli()
+			log.Printf("设备 %s 已停用", e.ID)
+		} else {
+			e.DisabledAt = 0
+			log.Printf("设备 %s 已重新启用", e.ID)
+		}
+	}
+	if err := saveDevice(c.Request.Context(), *e); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrInternal, "保存设备失败", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success", "data": e.Device})
+}
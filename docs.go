@@ -0,0 +1,68 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+)
+
+/* ---------- OpenAPI 文档与 Swagger UI ---------- */
+
+// 接口文档以 YAML 维护，新增或修改接口时同步更新 openapi.yaml
+//
+//go:embed openapi.yaml
+var openapiYAML []byte
+
+// Swagger UI 页面，静态资源从 SWAGGER_UI_URL 加载，页面本身随程序发布
+const swaggerUIPage = `<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<title>SMS Forwarder API</title>
+<link rel="stylesheet" href="%[1]s/swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="%[1]s/swagger-ui-bundle.js"></script>
+<script>
+window.ui = SwaggerUIBundle({url: "%[2]s", dom_id: "#swagger-ui", deepLinking: true});
+</script>
+</body>
+</html>
+`
+
+// 将内嵌的 YAML 文档转换为 JSON，格式错误属于发布问题，直接退出
+func loadOpenAPISpec() []byte {
+	var doc map[string]any
+	if err := yaml.Unmarshal(openapiYAML, &doc); err != nil {
+		log.Fatalf("解析 openapi.yaml 失败: %v", err)
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		log.Fatalf("转换 OpenAPI 文档失败: %v", err)
+	}
+	return data
+}
+
+// 注册 /openapi.json 与 /docs，API_DOCS_ENABLED=false 时不开放
+func registerDocsRoutes(r *gin.Engine) {
+	if getEnvWithDefault("API_DOCS_ENABLED", "true") != "true" {
+		return
+	}
+	spec := loadOpenAPISpec()
+	assets := strings.TrimSuffix(getEnvWithDefault("SWAGGER_UI_URL", "https://unpkg.com/swagger-ui-dist@5"), "/")
+	page := fmt.Sprintf(swaggerUIPage, assets, "/openapi.json")
+
+	r.GET("/openapi.json", func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json; charset=utf-8", spec)
+	})
+	r.GET("/docs", func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(page))
+	})
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+)
+
+/* ---------- 存储加密（AES-GCM） ---------- */
+
+// 加密值的前缀，带版本号便于以后更换格式；不带前缀的值视为未加密的旧数据
+const encryptedPrefix = "enc:v1:"
+
+var (
+	storageAEAD     cipher.AEAD   // 加密使用的密钥，为 nil 表示不加密
+	storageOpenAEAD []cipher.AEAD // 解密时依次尝试：当前密钥 + 轮换前的旧密钥
+)
+
+// 加载加密密钥，需在 initStorage 之前调用：
+//   - STORAGE_ENCRYPTION_KEY：base64 编码的 16/24/32 字节密钥
+//   - STORAGE_ENCRYPTION_KMS_KEY：经 AWS KMS 加密的数据密钥（base64），启动时调用 KMS 解密
+//   - STORAGE_ENCRYPTION_PREVIOUS_KEYS：轮换前的旧密钥，逗号分隔，只用于解密
+func initEncryption() {
+	key, err := loadEncryptionKey()
+	if err != nil {
+		log.Fatalf("加载存储加密密钥失败: %v", err)
+	}
+	if key == nil {
+		return
+	}
+	if storageAEAD, err = newAEAD(key); err != nil {
+		log.Fatalf("存储加密密钥无效: %v", err)
+	}
+	storageOpenAEAD = []cipher.AEAD{storageAEAD}
+
+	for _, s := range strings.Split(getEnvWithDefault("STORAGE_ENCRYPTION_PREVIOUS_KEYS", ""), ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		old, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			log.Fatalf("STORAGE_ENCRYPTION_PREVIOUS_KEYS 解码失败: %v", err)
+		}
+		aead, err := newAEAD(old)
+		if err != nil {
+			log.Fatalf("STORAGE_ENCRYPTION_PREVIOUS_KEYS 中的密钥无效: %v", err)
+		}
+		storageOpenAEAD = append(storageOpenAEAD, aead)
+	}
+	log.Printf("存储加密已启用 (AES-%d-GCM)", len(key)*8)
+}
+
+// 读取明文密钥，两者都未配置时返回 nil
+func loadEncryptionKey() ([]byte, error) {
+	if s := getEnvWithDefault("STORAGE_ENCRYPTION_KEY", ""); s != "" {
+		return base64.StdEncoding.DecodeString(s)
+	}
+	s := getEnvWithDefault("STORAGE_ENCRYPTION_KMS_KEY", "")
+	if s == "" {
+		return nil, nil
+	}
+	blob, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("STORAGE_ENCRYPTION_KMS_KEY 解码失败: %w", err)
+	}
+	// 凭证与区域走 AWS 标准配置，与 AWS 转发一致
+	cfg, err := awsconfig.LoadDefaultConfig(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	out, err := kms.NewFromConfig(cfg).Decrypt(context.Background(), &kms.DecryptInput{CiphertextBlob: blob})
+	if err != nil {
+		return nil, fmt.Errorf("KMS 解密数据密钥失败: %w", err)
+	}
+	return out.Plaintext, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// 加密一个存储值，未启用加密或值为空时原样返回
+func sealValue(plain string) string {
+	if storageAEAD == nil || plain == "" {
+		return plain
+	}
+	nonce := make([]byte, storageAEAD.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		panic(err) // 系统随机数不可用，无法安全加密
+	}
+	sealed := storageAEAD.Seal(nonce, nonce, []byte(plain), nil)
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed)
+}
+
+// 解密一个存储值；未加密的值原样返回，便于开启加密前写入的数据继续可读
+func openValue(s string) (string, error) {
+	if !strings.HasPrefix(s, encryptedPrefix) {
+		return s, nil
+	}
+	if len(storageOpenAEAD) == 0 {
+		return "", errors.New("数据已加密，但未配置 STORAGE_ENCRYPTION_KEY")
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(s, encryptedPrefix))
+	if err != nil {
+		return "", fmt.Errorf("密文解码失败: %w", err)
+	}
+	for _, aead := range storageOpenAEAD {
+		n := aead.NonceSize()
+		if len(sealed) < n {
+			break
+		}
+		if plain, err := aead.Open(nil, sealed[:n], sealed[n:], nil); err == nil {
+			return string(plain), nil
+		}
+	}
+	return "", errors.New("解密失败，密钥不匹配或数据已损坏")
+}
+
+// 序列化为 JSON 后加密
+func sealJSON(v any) string {
+	data, _ := json.Marshal(v)
+	return sealValue(string(data))
+}
+
+// 解密后解析 JSON
+func openJSON(s string, v any) error {
+	plain, err := openValue(s)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(plain), v)
+}
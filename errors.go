@@ -0,0 +1,109 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+/* ---------- 错误响应 ---------- */
+
+// ErrorCode 错误码：客户端按 code 分支处理，message 为面向人的中文说明，可能调整措辞
+type ErrorCode string
+
+// 错误码目录，新增错误码时同步更新 ReadME.md 与 openapi.yaml
+const (
+	ErrInvalidArgument     ErrorCode = "invalid_argument"       // 400 参数错误
+	ErrInvalidCursor       ErrorCode = "invalid_cursor"         // 400 翻页游标无效
+	ErrNoCodeFound         ErrorCode = "no_code_found"          // 400 短信中未找到验证码
+	ErrSubscriptionLimit   ErrorCode = "subscription_limit"     // 400 号码的订阅数已达上限
+	ErrUnauthorized        ErrorCode = "unauthorized"           // 401 管理令牌错误
+	ErrDeviceTokenNeeded   ErrorCode = "device_token_required"  // 401 缺少设备令牌
+	ErrDeviceTokenBad      ErrorCode = "device_token_invalid"   // 401 设备令牌无效
+	ErrDeviceDisabled      ErrorCode = "device_disabled"        // 403 设备已停用
+	ErrNotFound            ErrorCode = "not_found"              // 404 资源不存在
+	ErrSMSNotFound         ErrorCode = "sms_not_found"          // 404 没有符合条件的短信
+	ErrWaitTimeout         ErrorCode = "wait_timeout"           // 404 等待超时
+	ErrIdempotencyConflict ErrorCode = "idempotency_conflict"   // 409 相同幂等 key 的请求正在处理
+	ErrPayloadTooLarge     ErrorCode = "payload_too_large"      // 413 请求体过大
+	ErrUnsupportedEncode   ErrorCode = "unsupported_encoding"   // 415 不支持的 Content-Encoding
+	ErrIdempotencyMismatch ErrorCode = "idempotency_key_reused" // 422 幂等 key 已用于内容不同的短信
+	ErrRateLimited         ErrorCode = "rate_limited"           // 429 超出限制
+	ErrInternal            ErrorCode = "internal_error"         // 500 存储或内部错误
+	ErrSQLRequired         ErrorCode = "sql_history_required"   // 501 需要 SQL 历史存储
+	ErrRedisRequired       ErrorCode = "redis_required"         // 501 / 503 需要 Redis
+	ErrQueueFull           ErrorCode = "queue_full"             // 503 异步处理队列已满
+)
+
+// APIError 统一的错误响应体
+type APIError struct {
+	Code      ErrorCode `json:"code"`
+	Message   string    `json:"message"`
+	Details   any       `json:"details,omitempty"` // 错误详情，通常为底层错误信息
+	RequestID string    `json:"request_id,omitempty"`
+}
+
+// respondError 返回错误响应并中止后续处理。旧路径 /api 保持原来的 {"error", "message"} 格式，
+// 兼容已部署的转发器和脚本；/api/v1 及其他接口返回 APIError，message 按 Accept-Language 本地化（见 i18n.go）
+func respondError(c *gin.Context, status int, code ErrorCode, message string, details any) {
+	if err, ok := details.(error); ok {
+		details = err.Error()
+	}
+	if isLegacyAPI(c) {
+		body := gin.H{"error": message}
+		switch d := details.(type) {
+		case nil:
+		case string:
+			body["message"] = d
+		default:
+			body["details"] = d
+		}
+		c.AbortWithStatusJSON(status, body)
+		return
+	}
+	lang := requestLanguage(c)
+	if d, ok := details.(string); ok {
+		details = localizeMessage(lang, d)
+	}
+	c.Header("Content-Language", lang)
+	c.Header("Vary", "Accept-Language")
+	c.AbortWithStatusJSON(status, APIError{Code: code, Message: localizeMessage(lang, message), Details: details, RequestID: c.GetString(ctxRequestID)})
+}
+
+// 是否为旧路径 /api 的请求；管理接口等未经版本路由组的请求不算
+func isLegacyAPI(c *gin.Context) bool {
+	v, ok := c.Get(ctxAPIVersion)
+	return ok && v == apiVersionLegacy
+}
+
+/* ---------- 请求 ID ---------- */
+
+const (
+	headerRequestID = "X-Request-ID"
+	ctxRequestID    = "request_id"
+)
+
+// requestID 沿用调用方传入的 X-Request-ID（不超过 64 个可见字符），否则随机生成；
+// 写入响应头和错误响应体，便于对照服务端日志
+func requestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(headerRequestID)
+		if !printableToken(id, 64) {
+			id = randomHex(8)
+		}
+		c.Set(ctxRequestID, id)
+		c.Header(headerRequestID, id)
+		c.Next()
+	}
+}
+
+// 非空、不超过 max 字节且只包含可见 ASCII 字符
+func printableToken(s string, max int) bool {
+	return s != "" && len(s) <= max && strings.IndexFunc(s, func(r rune) bool { return r <= ' ' || r > '~' }) < 0
+}
+
+// 统一处理未注册的路由，返回与其他错误相同的格式
+func notFoundHandler(c *gin.Context) {
+	respondError(c, http.StatusNotFound, ErrNotFound, "接口不存在", c.Request.Method+" "+c.Request.URL.Path)
+}
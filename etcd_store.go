@@ -0,0 +1,416 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+/* ---------- etcd 存储与动态配置 ---------- */
+
+// 已连接的 etcd 客户端，未配置 ETCD_ENDPOINTS 且未使用 etcd 存储时为 nil
+var etcdClient *clientv3.Client
+
+// etcd 中所有 key 的前缀，与 KEY_PREFIX 类似，便于多个服务共用一个集群
+var etcdPrefix = "sms-forward/"
+
+// 是否需要连接 etcd：使用 etcd 存储或配置了 ETCD_ENDPOINTS（仅用于动态配置）
+func etcdEnabled() bool {
+	return storageBackend() == "etcd" || getEnvWithDefault("ETCD_ENDPOINTS", "") != ""
+}
+
+// 连接 etcd，需在 initStorage 之前调用
+func initEtcd() {
+	endpoints := splitAndTrim(getEnvWithDefault("ETCD_ENDPOINTS", "localhost:2379"))
+	etcdPrefix = getEnvWithDefault("ETCD_PREFIX", "sms-forward/")
+
+	cfg := clientv3.Config{
+		Endpoints:   endpoints,
+		Username:    getEnvWithDefault("ETCD_USERNAME", ""),
+		Password:    getEnvWithDefault("ETCD_PASSWORD", ""),
+		DialTimeout: getEnvDuration("ETCD_DIAL_TIMEOUT", 5*time.Second),
+	}
+	tlsCfg, err := etcdTLSConfig()
+	if err != nil {
+		log.Fatalf("etcd TLS 配置无效: %v", err)
+	}
+	cfg.TLS = tlsCfg
+
+	client, err := clientv3.New(cfg)
+	if err != nil {
+		log.Fatalf("etcd连接失败: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.DialTimeout)
+	defer cancel()
+	if _, err := client.Status(ctx, endpoints[0]); err != nil {
+		log.Fatalf("etcd连接失败: %v", err)
+	}
+	etcdClient = client
+	log.Printf("etcd连接成功 (地址: %s, 前缀: %s, TLS: %t)", strings.Join(endpoints, ","), etcdPrefix, tlsCfg != nil)
+}
+
+// 构造 etcd TLS 配置，未配置证书时返回 nil；Kubernetes 中的 etcd 通常要求客户端证书
+func etcdTLSConfig() (*tls.Config, error) {
+	caFile := getEnvWithDefault("ETCD_CA_CERT", "")
+	certFile := getEnvWithDefault("ETCD_CLIENT_CERT", "")
+	keyFile := getEnvWithDefault("ETCD_CLIENT_KEY", "")
+	if caFile == "" && certFile == "" {
+		return nil, nil
+	}
+	tlsCfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("读取 ETCD_CA_CERT 失败: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("ETCD_CA_CERT 中没有有效的 PEM 证书: %s", caFile)
+		}
+		tlsCfg.RootCAs = pool
+	}
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("加载 ETCD_CLIENT_CERT / ETCD_CLIENT_KEY 失败: %w", err)
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+	return tlsCfg, nil
+}
+
+/* ---------- etcd 存储 ---------- */
+
+// etcd 中保存的记录，ExpiresAt 为最新验证码的过期时间；记录本身由 lease 在历史保留时长后删除
+type etcdEntry struct {
+	Record    SMSRecord `json:"record"`
+	ExpiresAt int64     `json:"expires_at"` // 毫秒时间戳
+}
+
+// EtcdStorage 以 etcd 为主存储，适合已运行 etcd 但没有 Redis 的 Kubernetes 部署：
+//   - <前缀>sms/<号码>/<接收时间，20 位补零>：记录，lease 为历史保留时长
+//   - <前缀>latest/<号码>：最新记录的 key，lease 为缓存有效期
+type EtcdStorage struct {
+	client *clientv3.Client
+	prefix string
+}
+
+func (s *EtcdStorage) phonePrefix(phone string) string {
+	return s.prefix + "sms/" + phone + "/"
+}
+
+func (s *EtcdStorage) smsKey(phone string, receivedAt int64) string {
+	return fmt.Sprintf("%s%020d", s.phonePrefix(phone), receivedAt)
+}
+
+func (s *EtcdStorage) latestKey(phone string) string {
+	return s.prefix + "latest/" + phone
+}
+
+// 解析记录并带上缓存过期时间，已过期返回 false
+func decodeEtcdEntry(data []byte, now int64) (SMSRecord, bool) {
+	var e etcdEntry
+	if err := openJSON(string(data), &e); err != nil || e.ExpiresAt <= now {
+		return SMSRecord{}, false
+	}
+	e.Record.ExpiresAt = e.ExpiresAt
+	return e.Record, true
+}
+
+// SaveSMS 与 Redis 一致，不含验证码的短信不保存；记录与 latest 在同一事务中写入
+func (s *EtcdStorage) SaveSMS(ctx context.Context, rec SMSRecord) error {
+	ttl := rec.ttl()
+	if rec.Code == "" || ttl <= 0 {
+		return nil
+	}
+	keep := rec.historyTTL()
+	if keep < ttl {
+		keep = ttl
+	}
+	expiresAt := time.Now().Add(ttl)
+
+	// lease 以秒为单位，向上取整，避免提前删除
+	recordLease, err := s.client.Grant(ctx, int64((keep+time.Second-1)/time.Second))
+	if err != nil {
+		return err
+	}
+	latestLease, err := s.client.Grant(ctx, int64((ttl+time.Second-1)/time.Second))
+	if err != nil {
+		return err
+	}
+
+	key := s.smsKey(rec.From, rec.ReceivedAt)
+	value := sealJSON(etcdEntry{Record: rec, ExpiresAt: expiresAt.UnixMilli()})
+	if _, err := s.client.Txn(ctx).Then(
+		clientv3.OpPut(key, value, clientv3.WithLease(recordLease.ID)),
+		clientv3.OpPut(s.latestKey(rec.From), key, clientv3.WithLease(latestLease.ID)),
+	).Commit(); err != nil {
+		return err
+	}
+	return s.trimHistory(ctx, rec.From)
+}
+
+// 号码记录超过 SMS_HISTORY_MAX 时删除最早的记录
+func (s *EtcdStorage) trimHistory(ctx context.Context, phone string) error {
+	if smsHistoryMax <= 0 {
+		return nil
+	}
+	resp, err := s.client.Get(ctx, s.phonePrefix(phone),
+		clientv3.WithPrefix(), clientv3.WithKeysOnly(), clientv3.WithSort(clientv3.SortByKey, clientv3.SortDescend))
+	if err != nil {
+		return err
+	}
+	for i := smsHistoryMax; i < len(resp.Kvs); i++ {
+		if _, err := s.client.Delete(ctx, string(resp.Kvs[i].Key)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *EtcdStorage) GetLatest(ctx context.Context, phone string) (*SMSRecord, error) {
+	resp, err := s.client.Get(ctx, s.latestKey(phone))
+	if err != nil || len(resp.Kvs) == 0 {
+		return nil, err
+	}
+	if resp, err = s.client.Get(ctx, string(resp.Kvs[0].Value)); err != nil || len(resp.Kvs) == 0 {
+		return nil, err
+	}
+	if rec, ok := decodeEtcdEntry(resp.Kvs[0].Value, time.Now().UnixMilli()); ok {
+		return &rec, nil
+	}
+	return nil, nil
+}
+
+// GetHistory 历史中包含缓存已过期但仍在保留时长内的记录；key 按接收时间排序，倒序取前 limit 条即可
+func (s *EtcdStorage) GetHistory(ctx context.Context, phone string, limit int, before int64) ([]SMSRecord, error) {
+	start := s.phonePrefix(phone)
+	end := clientv3.GetPrefixRangeEnd(start)
+	if before > 0 {
+		end = s.smsKey(phone, before)
+	}
+	resp, err := s.client.Get(ctx, start, clientv3.WithRange(end),
+		clientv3.WithSort(clientv3.SortByKey, clientv3.SortDescend), clientv3.WithLimit(int64(limit)))
+	if err != nil {
+		return nil, err
+	}
+	records := []SMSRecord{}
+	for _, kv := range resp.Kvs {
+		var e etcdEntry
+		if err := openJSON(string(kv.Value), &e); err != nil {
+			log.Printf("解析etcd记录 %s 失败: %v", kv.Key, err)
+			continue
+		}
+		records = append(records, e.Record)
+	}
+	return records, nil
+}
+
+func (s *EtcdStorage) Delete(ctx context.Context, phone string) error {
+	_, err := s.client.Txn(ctx).Then(
+		clientv3.OpDelete(s.phonePrefix(phone), clientv3.WithPrefix()),
+		clientv3.OpDelete(s.latestKey(phone)),
+	).Commit()
+	return err
+}
+
+// ConsumeLatest 以 latest 的值为条件在事务中删除 latest 并读取记录，条件不满足说明已被其他请求消费
+func (s *EtcdStorage) ConsumeLatest(ctx context.Context, phone string) (*SMSRecord, error) {
+	latest := s.latestKey(phone)
+	resp, err := s.client.Get(ctx, latest)
+	if err != nil || len(resp.Kvs) == 0 {
+		return nil, err
+	}
+	key := string(resp.Kvs[0].Value)
+	txn, err := s.client.Txn(ctx).
+		If(clientv3.Compare(clientv3.Value(latest), "=", key)).
+		Then(clientv3.OpDelete(latest), clientv3.OpGet(key)).
+		Commit()
+	if err != nil || !txn.Succeeded {
+		return nil, err
+	}
+	kvs := txn.Responses[1].GetResponseRange().Kvs
+	if len(kvs) == 0 {
+		return nil, nil
+	}
+	if rec, ok := decodeEtcdEntry(kvs[0].Value, time.Now().UnixMilli()); ok {
+		return &rec, nil
+	}
+	return nil, nil
+}
+
+// DeleteRecord 删除记录，latest 指向该记录时在同一事务中一并删除
+func (s *EtcdStorage) DeleteRecord(ctx context.Context, phone string, receivedAt int64) (bool, error) {
+	key := s.smsKey(phone, receivedAt)
+	latest := s.latestKey(phone)
+	resp, err := s.client.Txn(ctx).
+		If(clientv3.Compare(clientv3.Value(latest), "=", key)).
+		Then(clientv3.OpDelete(key), clientv3.OpDelete(latest)).
+		Else(clientv3.OpDelete(key)).
+		Commit()
+	if err != nil {
+		return false, err
+	}
+	return resp.Responses[0].GetResponseDeleteRange().Deleted > 0, nil
+}
+
+// FindByCode 遍历全部记录比对，适用于单机的小规模部署
+func (s *EtcdStorage) FindByCode(ctx context.Context, code string, since int64, limit int) ([]SMSRecord, error) {
+	return findByCodeScan(ctx, s, code, since, limit)
+}
+
+// ListActivePhones 遍历全部记录统计，适用于单机的小规模部署
+func (s *EtcdStorage) ListActivePhones(ctx context.Context) ([]ActivePhone, error) {
+	return listActivePhonesScan(ctx, s, s.GetLatest)
+}
+
+// 遍历全部记录（供迁移使用），同一号码按接收时间升序；缓存已过期的记录 ExpiresAt 保持原值，只写入历史
+func (s *EtcdStorage) eachRecord(ctx context.Context, fn func(SMSRecord) error) error {
+	const batch = 1000
+	prefix := s.prefix + "sms/"
+	end := clientv3.GetPrefixRangeEnd(prefix)
+	key := prefix
+	for {
+		resp, err := s.client.Get(ctx, key, clientv3.WithRange(end),
+			clientv3.WithSort(clientv3.SortByKey, clientv3.SortAscend), clientv3.WithLimit(batch))
+		if err != nil {
+			return err
+		}
+		for _, kv := range resp.Kvs {
+			var e etcdEntry
+			if err := openJSON(string(kv.Value), &e); err != nil {
+				return fmt.Errorf("解析etcd记录 %s 失败: %w", kv.Key, err)
+			}
+			e.Record.ExpiresAt = e.ExpiresAt
+			if err := fn(e.Record); err != nil {
+				return err
+			}
+		}
+		if len(resp.Kvs) < batch {
+			return nil
+		}
+		key = string(resp.Kvs[len(resp.Kvs)-1].Key) + "\x00"
+	}
+}
+
+/* ---------- etcd 动态配置 ---------- */
+
+// 可在 etcd 中动态修改的配置：value 与对应规则文件格式相同（YAML / JSON），删除 key 即清空规则
+var etcdConfigKeys = map[string]func([]byte) error{
+	"config/routing": func(data []byte) error {
+		if len(data) == 0 {
+			routing.Store(nil)
+			return nil
+		}
+		cfg, err := parseRoutingConfig(data)
+		if err != nil {
+			return err
+		}
+		routing.Store(cfg)
+		return nil
+	},
+	"config/retention": func(data []byte) error {
+		if len(data) == 0 {
+			retentionRules.Store(nil)
+			return nil
+		}
+		cfg, err := parseRetentionConfig(data)
+		if err != nil {
+			return err
+		}
+		setRetentionRules(cfg)
+		return nil
+	},
+	"config/extraction": func(data []byte) error {
+		if len(data) == 0 { // 恢复为启动时的规则
+			extractionSettings.Store(baseExtraction.Load())
+			return nil
+		}
+		s, err := parseExtractionConfig(data)
+		if err != nil {
+			return err
+		}
+		extractionSettings.Store(s)
+		return nil
+	},
+}
+
+// 读取 etcd 中的路由、保留与提取规则并监听变更，存在时覆盖规则文件；需在 initRouting、initRetention、initSettings 之后调用
+func initEtcdConfig() {
+	if etcdClient == nil || getEnvWithDefault("ETCD_CONFIG_WATCH", "true") != "true" {
+		return
+	}
+	prefix := etcdPrefix + "config/"
+	rev, err := loadEtcdConfig(prefix)
+	if err != nil {
+		log.Fatalf("读取etcd动态配置失败: %v", err)
+	}
+	go watchEtcdConfig(prefix, rev)
+	log.Printf("etcd动态配置已启用: %s", prefix)
+}
+
+// 读取当前全部配置并应用，返回读取时的 revision
+func loadEtcdConfig(prefix string) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	resp, err := etcdClient.Get(ctx, prefix, clientv3.WithPrefix())
+	if err != nil {
+		return 0, err
+	}
+	for _, kv := range resp.Kvs {
+		applyEtcdConfig(string(kv.Key), kv.Value)
+	}
+	return resp.Header.Revision, nil
+}
+
+// 监听配置变更；解析失败时保留原规则
+func watchEtcdConfig(prefix string, rev int64) {
+	for {
+		for wresp := range etcdClient.Watch(context.Background(), prefix, clientv3.WithPrefix(), clientv3.WithRev(rev+1)) {
+			if err := wresp.Err(); err != nil {
+				log.Printf("监听etcd动态配置出错: %v", err)
+				continue
+			}
+			for _, ev := range wresp.Events {
+				value := ev.Kv.Value
+				if ev.Type == clientv3.EventTypeDelete {
+					value = nil
+				}
+				applyEtcdConfig(string(ev.Kv.Key), value)
+			}
+			rev = wresp.Header.Revision
+		}
+
+		// 通道关闭（如 revision 已被压缩），重新读取全部配置后继续监听
+		time.Sleep(time.Second)
+		if latest, err := loadEtcdConfig(prefix); err != nil {
+			log.Printf("重新读取etcd动态配置失败: %v", err)
+		} else {
+			rev = latest
+		}
+	}
+}
+
+func applyEtcdConfig(key string, value []byte) {
+	name := strings.TrimPrefix(key, etcdPrefix)
+	apply, ok := etcdConfigKeys[name]
+	if !ok {
+		return
+	}
+	if err := apply(value); err != nil {
+		log.Printf("etcd动态配置 %s 无效，保留原规则: %v", name, err)
+		return
+	}
+	if value == nil {
+		log.Printf("etcd动态配置 %s 已删除，规则已清空", name)
+	} else {
+		log.Printf("etcd动态配置 %s 已更新", name)
+	}
+}
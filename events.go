@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+/* ---------- 新短信事件广播（Redis pub/sub） ---------- */
+
+// 短信事件类型
+const smsEventReceived = "sms.received"
+
+// SMSEvent 发布到 sms_events 频道的消息
+type SMSEvent struct {
+	Type     string    `json:"type"`
+	Instance string    `json:"instance"` // 发布实例标识，订阅方可据此忽略本实例发布的事件
+	Record   SMSRecord `json:"record"`
+}
+
+// 为空表示不发布
+var smsEventsChannel string
+
+// 实例标识（主机名-进程号），用于事件来源和 Stream 消费者名称
+var instanceID = func() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}()
+
+// 使用 Redis 时默认启用，频道名同样加上 KEY_PREFIX
+func initEvents() {
+	if rdb == nil || getEnvWithDefault("SMS_EVENTS_ENABLED", "true") != "true" {
+		return
+	}
+	smsEventsChannel = redisKey(getEnvWithDefault("SMS_EVENTS_CHANNEL", "sms_events"))
+	log.Printf("短信事件广播已启用 (频道: %s)", smsEventsChannel)
+	go subscribeSMSEvents()
+}
+
+// 订阅其他实例发布的事件，使本地最新短信缓存及时失效，并通知本实例的长轮询与 SSE 订阅者
+func subscribeSMSEvents() {
+	sub := rdb.Subscribe(context.Background(), smsEventsChannel)
+	for msg := range sub.Channel() {
+		var ev SMSEvent
+		if err := json.Unmarshal([]byte(msg.Payload), &ev); err != nil || ev.Instance == instanceID {
+			continue
+		}
+		if latestLRU != nil {
+			latestLRU.invalidate(ev.Record.From)
+		}
+		notifyNewSMS(ev.Record)
+	}
+}
+
+// 发布一条已保存的短信，失败只记录日志
+func publishSMSEvent(rec SMSRecord) {
+	if smsEventsChannel == "" {
+		return
+	}
+	data, _ := json.Marshal(SMSEvent{Type: smsEventReceived, Instance: instanceID, Record: rec})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := rdb.Publish(ctx, smsEventsChannel, data).Err(); err != nil {
+		log.Printf("发布短信事件失败: %v", err)
+	}
+}
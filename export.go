@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+/* ---------- 历史导出（CSV / JSON） ---------- */
+
+// 每批从数据库读取的条数，读完一批即写出，导出大量记录时不必全部读入内存
+const exportBatchSize = 1000
+
+// CSV 表头，与 SMSRecord 的 JSON 字段一致，另加可读的接收时间
+var exportCSVHeader = []string{"id", "from", "code", "raw_content", "received_at", "received_time", "cache_key", "created_at"}
+
+// GET /api/export?format=csv|json&phone=&since=&until=
+// 以附件形式导出 SQL 历史中的记录（按接收时间倒序），供审计或导入表格；中途出错时输出不完整
+func exportSMS(c *gin.Context) {
+	s := sqlHistoryStore()
+	if s == nil {
+		respondError(c, http.StatusNotImplemented, ErrSQLRequired, "导出需要启用 SQL 历史存储 (STORAGE_BACKEND=sqlite/postgres/mysql)", nil)
+		return
+	}
+	format := c.DefaultQuery("format", "csv")
+	if format != "csv" && format != "json" {
+		respondError(c, http.StatusBadRequest, ErrInvalidArgument, "format 参数错误，支持 csv / json", nil)
+		return
+	}
+	since, until, ok := bindTimeRange(c)
+	if !ok {
+		return
+	}
+	filter := SearchFilter{Since: since, Until: until}
+	phone := c.Query("phone")
+	if phone != "" {
+		phone = resolvePhoneAlias(c.Request.Context(), phone)
+		filter.From, filter.Exact = phoneKey(phone), true
+	}
+
+	filename := fmt.Sprintf("sms-export-%s.%s", time.Now().Format("20060102-150405"), format)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	ctx := c.Request.Context()
+	w := c.Writer
+	written := 0
+
+	var err error
+	if format == "csv" {
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		w.WriteString("\ufeff") // BOM，Excel 打开时按 UTF-8 识别中文
+		cw := csv.NewWriter(w)
+		cw.Write(exportCSVHeader)
+		err = s.eachMatch(ctx, filter, exportBatchSize, 0, func(rec SMSRecord) error {
+			if phone != "" {
+				rec.From = phone
+			}
+			written++
+			return cw.Write([]string{
+				strconv.FormatInt(rec.ID, 10),
+				rec.From,
+				rec.Code,
+				rec.RawContent,
+				strconv.FormatInt(rec.ReceivedAt, 10),
+				time.UnixMilli(rec.ReceivedAt).Format(time.RFC3339),
+				rec.CacheKey,
+				strconv.FormatInt(rec.CreatedAt, 10),
+			})
+		})
+		cw.Flush()
+	} else {
+		c.Header("Content-Type", "application/json; charset=utf-8")
+		w.WriteString("[")
+		err = s.eachMatch(ctx, filter, exportBatchSize, 0, func(rec SMSRecord) error {
+			if phone != "" {
+				rec.From = phone
+			}
+			if written > 0 {
+				w.WriteString(",")
+			}
+			written++
+			data, _ := json.Marshal(rec)
+			_, err := w.Write(data)
+			return err
+		})
+		w.WriteString("]")
+	}
+	if err != nil {
+		log.Printf("导出失败 (已写出 %d 条): %v", written, err)
+		return
+	}
+	log.Printf("导出短信历史 %d 条 (格式: %s)", written, format)
+}
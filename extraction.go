@@ -0,0 +1,257 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"gopkg.in/yaml.v3"
+)
+
+/* ---------- 验证码提取规则 ---------- */
+
+// ExtractionRule 单条提取规则，按顺序尝试，取第一条匹配的规则提取出的验证码。
+// 验证码取名为 code 的分组（(?P<code>…)），没有时取唯一的捕获分组，没有分组时取整个匹配。
+// 配置了 Keywords 时 Pattern 为关键词之后的验证码，默认为 4~8 位数字。
+// 配置了 Sender 时只用于来源号码匹配的短信；Sender 匹配但没有 Pattern 和 Keywords 时停止后续规则，该来源的短信不提取验证码
+type ExtractionRule struct {
+	Name     string   `yaml:"name" json:"name"`
+	Sender   string   `yaml:"sender" json:"sender,omitempty"`     // 来源号码正则，为空表示所有来源
+	Keywords []string `yaml:"keywords" json:"keywords,omitempty"` // 验证码前的关键词，不区分大小写
+	Pattern  string   `yaml:"pattern" json:"pattern"`
+	Last     bool     `yaml:"last" json:"last,omitempty"` // 有多处匹配时取最后一处，默认取第一处
+
+	senderRe *regexp.Regexp
+	re       *regexp.Regexp
+	group    int // 验证码所在分组的序号，0 为整个匹配
+}
+
+// ExtractionConfig 提取规则文件（YAML 或 JSON）
+type ExtractionConfig struct {
+	Rules []ExtractionRule `yaml:"rules"`
+}
+
+// ExtractionSettings 当前生效的提取规则。由 SMS_CODE_PATTERN / SMS_CODE_FALLBACK_PATTERN 或
+// /admin/settings 的 pattern、fallback 生成时为两条规则，并保留 Pattern、Fallback 供查看；使用规则文件时两者为空
+type ExtractionSettings struct {
+	Pattern  string
+	Fallback string
+	Rules    []ExtractionRule
+}
+
+// 内置规则：优先匹配关键词之后的 4~8 位数字（如“验证码…123456”“code is 123456”），否则取最后一串 4~8 位数字
+const (
+	defaultCodeDigits   = `[0-9]{4,8}`
+	defaultCodeFallback = defaultCodeDigits
+)
+
+// 内置的验证码关键词，可用 EXTRACTION_KEYWORDS 修改
+var defaultCodeKeywords = []string{"验证码", "verification code", "OTP", "code is", "인증번호", "код"}
+
+// SMS_CODE_PATTERN 的默认值，由关键词生成
+var defaultCodePattern = keywordCodePattern(defaultCodeKeywords, "("+defaultCodeDigits+")")
+
+var extractionSettings atomic.Pointer[ExtractionSettings]
+
+// 启动时加载的规则，etcd 中的提取规则删除后恢复为该规则
+var baseExtraction atomic.Pointer[ExtractionSettings]
+
+// 编译规则并确定验证码所在的分组
+func (r *ExtractionRule) compile() error {
+	var err error
+	if r.Sender != "" {
+		if r.senderRe, err = regexp.Compile(r.Sender); err != nil {
+			return fmt.Errorf("提取规则 %s 的 sender 正则无效: %w", r.Name, err)
+		}
+	}
+	expr := r.Pattern
+	if len(r.Keywords) > 0 {
+		if expr, err = r.keywordExpr(); err != nil {
+			return err
+		}
+	}
+	if expr == "" {
+		if r.senderRe == nil {
+			return fmt.Errorf("提取规则 %s 缺少 pattern", r.Name)
+		}
+		return nil
+	}
+	if r.re, err = regexp.Compile(expr); err != nil {
+		return fmt.Errorf("提取规则 %s 的正则无效: %w", r.Name, err)
+	}
+	if i := r.re.SubexpIndex("code"); i > 0 {
+		r.group = i
+		return nil
+	}
+	switch r.re.NumSubexp() {
+	case 0:
+		r.group = 0
+	case 1:
+		r.group = 1
+	default:
+		return fmt.Errorf("提取规则 %s 包含多个捕获分组，需用 (?P<code>…) 标明验证码: %s", r.Name, r.Pattern)
+	}
+	return nil
+}
+
+// 由 Keywords 和 Pattern 生成规则的正则；Pattern 没有捕获分组时整个作为验证码
+func (r *ExtractionRule) keywordExpr() (string, error) {
+	var keywords []string
+	for _, kw := range r.Keywords {
+		if kw = strings.TrimSpace(kw); kw != "" {
+			keywords = append(keywords, kw)
+		}
+	}
+	if len(keywords) == 0 {
+		return "", fmt.Errorf("提取规则 %s 的 keywords 不能为空", r.Name)
+	}
+	code := r.Pattern
+	if code == "" {
+		code = defaultCodeDigits
+	}
+	re, err := regexp.Compile(code)
+	if err != nil {
+		return "", fmt.Errorf("提取规则 %s 的正则无效: %w", r.Name, err)
+	}
+	if re.NumSubexp() == 0 {
+		code = "(" + code + ")"
+	} else {
+		code = "(?:" + code + ")"
+	}
+	return keywordCodePattern(keywords, code), nil
+}
+
+// 匹配任一关键词（不区分大小写）之后、中间不隔数字的验证码
+func keywordCodePattern(keywords []string, code string) string {
+	quoted := make([]string, len(keywords))
+	for i, kw := range keywords {
+		quoted[i] = regexp.QuoteMeta(kw)
+	}
+	return "(?i:" + strings.Join(quoted, "|") + ")[^0-9]*" + code
+}
+
+// 按规则提取验证码，未匹配或验证码分组为空时返回 false
+func (r *ExtractionRule) match(text string) (string, bool) {
+	var m []string
+	if r.Last {
+		all := r.re.FindAllStringSubmatch(text, -1)
+		if len(all) == 0 {
+			return "", false
+		}
+		m = all[len(all)-1]
+	} else if m = r.re.FindStringSubmatch(text); m == nil {
+		return "", false
+	}
+	return m[r.group], m[r.group] != ""
+}
+
+// 编译一组规则，未命名的规则命名为 rule-<序号>
+func newExtractionRules(rules []ExtractionRule) (*ExtractionSettings, error) {
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("至少需要一条提取规则")
+	}
+	compiled := make([]ExtractionRule, len(rules))
+	for i, rule := range rules {
+		if rule.Name == "" {
+			rule.Name = "rule-" + strconv.Itoa(i+1)
+		}
+		if err := rule.compile(); err != nil {
+			return nil, err
+		}
+		compiled[i] = rule
+	}
+	return &ExtractionSettings{Rules: compiled}, nil
+}
+
+// 由验证码正则和兜底正则生成规则；Fallback 为空表示不做兜底匹配
+func newExtractionSettings(pattern, fallback string) (*ExtractionSettings, error) {
+	rules := []ExtractionRule{{Name: "pattern", Pattern: pattern}}
+	if fallback != "" {
+		rules = append(rules, ExtractionRule{Name: "fallback", Pattern: fallback, Last: true})
+	}
+	s, err := newExtractionRules(rules)
+	if err != nil {
+		return nil, err
+	}
+	s.Pattern, s.Fallback = pattern, fallback
+	return s, nil
+}
+
+// 解析并校验提取规则文件
+func parseExtractionConfig(data []byte) (*ExtractionSettings, error) {
+	var cfg ExtractionConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+	return newExtractionRules(cfg.Rules)
+}
+
+// 从环境变量读取提取规则；配置了 EXTRACTION_RULES_FILE 时以规则文件为准，
+// 配置了 EXTRACTION_KEYWORDS 时按其生成 SMS_CODE_PATTERN 的默认值
+func initExtraction() {
+	keywords := splitAndTrim(getEnvWithDefault("EXTRACTION_KEYWORDS", ""))
+	if len(keywords) == 0 {
+		keywords = defaultCodeKeywords
+	}
+	defaultCodePattern = keywordCodePattern(keywords, "("+defaultCodeDigits+")")
+	s, err := loadExtraction()
+	if err != nil {
+		log.Fatalf("验证码提取规则配置无效: %v", err)
+	}
+	extractionSettings.Store(s)
+	baseExtraction.Store(s)
+}
+
+func loadExtraction() (*ExtractionSettings, error) {
+	path := getEnvWithDefault("EXTRACTION_RULES_FILE", "")
+	if path == "" {
+		return newExtractionSettings(
+			getEnvWithDefault("SMS_CODE_PATTERN", defaultCodePattern),
+			getEnvWithDefault("SMS_CODE_FALLBACK_PATTERN", defaultCodeFallback),
+		)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取提取规则文件 %s 失败: %w", path, err)
+	}
+	s, err := parseExtractionConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("解析提取规则文件 %s 失败: %w", path, err)
+	}
+	log.Printf("已加载 %d 条提取规则: %s", len(s.Rules), path)
+	return s, nil
+}
+
+// 当前生效的提取规则，未初始化时使用内置规则
+func currentExtraction() *ExtractionSettings {
+	if s := extractionSettings.Load(); s != nil {
+		return s
+	}
+	s, _ := newExtractionSettings(defaultCodePattern, defaultCodeFallback)
+	extractionSettings.CompareAndSwap(nil, s)
+	return extractionSettings.Load()
+}
+
+// extractCode 按当前提取规则提取短信中的验证码（默认为 4–8 位数字），from 为原始来源号码
+func extractCode(from, text string) string {
+	ex := currentExtraction()
+	for i := range ex.Rules {
+		rule := &ex.Rules[i]
+		if rule.senderRe != nil && !rule.senderRe.MatchString(from) {
+			continue
+		}
+		if rule.re == nil {
+			debugf("提取规则 %s 匹配来源 %s，不提取验证码", rule.Name, from)
+			return ""
+		}
+		if code, ok := rule.match(text); ok {
+			debugf("提取规则 %s 匹配到验证码", rule.Name)
+			return code
+		}
+	}
+	return ""
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+/* ---------- RabbitMQ / AMQP 发布 ---------- */
+
+func init() { registerForwarder("amqp", newAMQPForwarder) }
+
+// AMQPForwarder 发布到 AMQP 交换机，routing key 由来源号码派生
+type AMQPForwarder struct {
+	URL                string
+	Exchange           string
+	ExchangeType       string
+	RoutingKeyTemplate string // 支持 {from} 占位符，如 sms.{from}
+
+	mu   sync.Mutex
+	conn *amqp.Connection
+	ch   *amqp.Channel
+}
+
+// 从环境变量创建 AMQP 转发，缺少 URL / 交换机时返回 nil
+func newAMQPForwarder() Forwarder {
+	url := getEnvWithDefault("AMQP_URL", "")
+	exchange := getEnvWithDefault("AMQP_EXCHANGE", "")
+	if url == "" || exchange == "" {
+		return nil
+	}
+	return &AMQPForwarder{
+		URL:                url,
+		Exchange:           exchange,
+		ExchangeType:       getEnvWithDefault("AMQP_EXCHANGE_TYPE", "topic"),
+		RoutingKeyTemplate: getEnvWithDefault("AMQP_ROUTING_KEY", "sms.{from}"),
+	}
+}
+
+func (a *AMQPForwarder) Name() string { return "AMQP" }
+
+// 获取可用的 channel，连接断开时重新建立并声明交换机（需持有锁）
+func (a *AMQPForwarder) channel() (*amqp.Channel, error) {
+	if a.ch != nil && !a.ch.IsClosed() {
+		return a.ch, nil
+	}
+	if a.conn == nil || a.conn.IsClosed() {
+		conn, err := amqp.Dial(a.URL)
+		if err != nil {
+			return nil, fmt.Errorf("连接AMQP失败: %w", err)
+		}
+		a.conn = conn
+	}
+
+	ch, err := a.conn.Channel()
+	if err != nil {
+		return nil, fmt.Errorf("打开AMQP channel失败: %w", err)
+	}
+	if err := ch.ExchangeDeclare(a.Exchange, a.ExchangeType, true, false, false, false, nil); err != nil {
+		ch.Close()
+		return nil, fmt.Errorf("声明交换机 %s 失败: %w", a.Exchange, err)
+	}
+	a.ch = ch
+	return ch, nil
+}
+
+// Forward 以持久化消息发布 JSON
+func (a *AMQPForwarder) Forward(msg ForwardMessage) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("序列化消息失败: %w", err)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	ch, err := a.channel()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	routingKey := strings.ReplaceAll(a.RoutingKeyTemplate, "{from}", msg.From)
+	err = ch.PublishWithContext(ctx, a.Exchange, routingKey, false, false, amqp.Publishing{
+		ContentType:  "application/json",
+		DeliveryMode: amqp.Persistent,
+		Timestamp:    time.UnixMilli(msg.ReceivedAt),
+		Body:         body,
+	})
+	if err != nil {
+		return fmt.Errorf("发布到AMQP失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	snstypes "github.com/aws/aws-sdk-go-v2/service/sns/types"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+/* ---------- AWS SNS / SQS 输出 ---------- */
+
+func init() { registerForwarder("aws", newAWSForwarder) }
+
+// AWSForwarder 发布到 SNS 主题和/或 SQS 队列，凭证走 AWS 标准凭证链
+type AWSForwarder struct {
+	TopicARN string
+	QueueURL string
+	sns      *sns.Client
+	sqs      *sqs.Client
+}
+
+// 从环境变量创建 AWS 转发，SNS 主题和 SQS 队列都未配置时返回 nil
+func newAWSForwarder() Forwarder {
+	topicARN := getEnvWithDefault("AWS_SNS_TOPIC_ARN", "")
+	queueURL := getEnvWithDefault("AWS_SQS_QUEUE_URL", "")
+	if topicARN == "" && queueURL == "" {
+		return nil
+	}
+
+	// 读取 AWS_REGION、AWS_ACCESS_KEY_ID、~/.aws、IAM 角色等标准配置
+	cfg, err := awsconfig.LoadDefaultConfig(context.Background())
+	if err != nil {
+		log.Printf("加载AWS配置失败，已跳过: %v", err)
+		return nil
+	}
+
+	f := &AWSForwarder{TopicARN: topicARN, QueueURL: queueURL}
+	if topicARN != "" {
+		f.sns = sns.NewFromConfig(cfg)
+	}
+	if queueURL != "" {
+		f.sqs = sqs.NewFromConfig(cfg)
+	}
+	return f
+}
+
+func (a *AWSForwarder) Name() string { return "AWS" }
+
+// Forward 将 JSON 消息发布到已配置的 SNS / SQS；FIFO 目标按号码分组、按 cache key 去重
+func (a *AWSForwarder) Forward(msg ForwardMessage) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("序列化消息失败: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	if a.sns != nil {
+		input := &sns.PublishInput{
+			TopicArn: aws.String(a.TopicARN),
+			Message:  aws.String(string(body)),
+			MessageAttributes: map[string]snstypes.MessageAttributeValue{
+				"from": {DataType: aws.String("String"), StringValue: aws.String(msg.From)},
+			},
+		}
+		if strings.HasSuffix(a.TopicARN, ".fifo") {
+			input.MessageGroupId = aws.String(msg.From)
+			input.MessageDeduplicationId = aws.String(msg.CacheKey)
+		}
+		if _, err := a.sns.Publish(ctx, input); err != nil {
+			return fmt.Errorf("发布到SNS失败: %w", err)
+		}
+	}
+
+	if a.sqs != nil {
+		input := &sqs.SendMessageInput{
+			QueueUrl:    aws.String(a.QueueURL),
+			MessageBody: aws.String(string(body)),
+			MessageAttributes: map[string]sqstypes.MessageAttributeValue{
+				"from": {DataType: aws.String("String"), StringValue: aws.String(msg.From)},
+			},
+		}
+		if strings.HasSuffix(a.QueueURL, ".fifo") {
+			input.MessageGroupId = aws.String(msg.From)
+			input.MessageDeduplicationId = aws.String(msg.CacheKey)
+		}
+		if _, err := a.sqs.SendMessage(ctx, input); err != nil {
+			return fmt.Errorf("发送到SQS失败: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+/* ---------- Bark（iOS 推送）转发 ---------- */
+
+func init() { registerForwarder("bark", newBarkForwarder) }
+
+// BarkForwarder 推送到 Bark 服务端（默认 api.day.app）
+type BarkForwarder struct {
+	Server    string
+	DeviceKey string
+	Sound     string
+	Group     string
+	tmpl      *MessageTemplate
+}
+
+// 从环境变量创建 Bark 转发，未配置 BARK_DEVICE_KEY 时返回 nil
+func newBarkForwarder() Forwarder {
+	key := getEnvWithDefault("BARK_DEVICE_KEY", "")
+	if key == "" {
+		return nil
+	}
+	return (&BarkForwarder{
+		Server:    strings.TrimRight(getEnvWithDefault("BARK_SERVER", "https://api.day.app"), "/"),
+		DeviceKey: key,
+		Sound:     getEnvWithDefault("BARK_SOUND", ""),
+		Group:     getEnvWithDefault("BARK_GROUP", "sms-forward"),
+	}).setup()
+}
+
+func (b *BarkForwarder) setup() *BarkForwarder {
+	b.tmpl = loadMessageTemplate("bark", defaultTitleTemplate, "来源: {{.From}}\n时间: {{.Time}}")
+	return b
+}
+
+func (b *BarkForwarder) Name() string { return "Bark" }
+
+// Forward 推送通知，并让 iOS 自动复制验证码
+func (b *BarkForwarder) Forward(msg ForwardMessage) error {
+	title, body, err := b.tmpl.Render(msg)
+	if err != nil {
+		return err
+	}
+	payload := map[string]string{
+		"device_key": b.DeviceKey,
+		"title":      title,
+		"body":       body,
+		"group":      b.Group,
+		"copy":       msg.Code,
+		"autoCopy":   "1",
+	}
+	if b.Sound != "" {
+		payload["sound"] = b.Sound
+	}
+
+	if _, err := postJSON(b.Server+"/push", payload); err != nil {
+		return fmt.Errorf("请求Bark失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+/* ---------- 钉钉转发 ---------- */
+
+func init() { registerForwarder("dingtalk", newDingTalkForwarder) }
+
+// DingTalkForwarder 推送到钉钉群自定义机器人
+type DingTalkForwarder struct {
+	WebhookURL string
+	Secret     string // 加签密钥，为空表示未开启加签
+	tmpl       *MessageTemplate
+}
+
+// 从环境变量创建钉钉转发，未配置 Webhook 或 access_token 时返回 nil
+func newDingTalkForwarder() Forwarder {
+	webhook := getEnvWithDefault("DINGTALK_WEBHOOK_URL", "")
+	if webhook == "" {
+		token := getEnvWithDefault("DINGTALK_ACCESS_TOKEN", "")
+		if token == "" {
+			return nil
+		}
+		webhook = "https://oapi.dingtalk.com/robot/send?access_token=" + url.QueryEscape(token)
+	}
+	return (&DingTalkForwarder{
+		WebhookURL: webhook,
+		Secret:     getEnvWithDefault("DINGTALK_SECRET", ""),
+	}).setup()
+}
+
+func (d *DingTalkForwarder) setup() *DingTalkForwarder {
+	d.tmpl = loadMessageTemplate("dingtalk", defaultTitleTemplate, defaultBodyTemplate)
+	return d
+}
+
+func (d *DingTalkForwarder) Name() string { return "钉钉" }
+
+// 加签：HmacSHA256(timestamp + "\n" + secret) 后 Base64
+func (d *DingTalkForwarder) signedURL() string {
+	if d.Secret == "" {
+		return d.WebhookURL
+	}
+	ts := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	mac := hmac.New(sha256.New, []byte(d.Secret))
+	mac.Write([]byte(ts + "\n" + d.Secret))
+	sign := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	return fmt.Sprintf("%s&timestamp=%s&sign=%s", d.WebhookURL, ts, url.QueryEscape(sign))
+}
+
+// Forward 以 text 消息推送验证码
+func (d *DingTalkForwarder) Forward(msg ForwardMessage) error {
+	_, text, err := d.tmpl.Render(msg)
+	if err != nil {
+		return err
+	}
+	body, err := postJSON(d.signedURL(), map[string]any{
+		"msgtype": "text",
+		"text":    map[string]string{"content": text},
+	})
+	if err != nil {
+		return fmt.Errorf("请求钉钉失败: %w", err)
+	}
+
+	// 钉钉即使出错也返回 200，需要检查 errcode
+	var result struct {
+		ErrCode int    `json:"errcode"`
+		ErrMsg  string `json:"errmsg"`
+	}
+	if err := json.Unmarshal(body, &result); err == nil && result.ErrCode != 0 {
+		return fmt.Errorf("钉钉返回错误 %d: %s", result.ErrCode, result.ErrMsg)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+/* ---------- Discord 转发 ---------- */
+
+func init() { registerForwarder("discord", newDiscordForwarder) }
+
+// DiscordForwarder 通过频道 Webhook 推送 Embed 消息
+type DiscordForwarder struct {
+	WebhookURL string
+	Username   string
+	tmpl       *MessageTemplate
+}
+
+// 从环境变量创建 Discord 转发，未配置 DISCORD_WEBHOOK_URL 时返回 nil
+func newDiscordForwarder() Forwarder {
+	url := getEnvWithDefault("DISCORD_WEBHOOK_URL", "")
+	if url == "" {
+		return nil
+	}
+	return (&DiscordForwarder{
+		WebhookURL: url,
+		Username:   getEnvWithDefault("DISCORD_USERNAME", ""),
+	}).setup()
+}
+
+func (d *DiscordForwarder) setup() *DiscordForwarder {
+	// 正文模板默认为空，只展示字段；配置 DISCORD_TEMPLATE 后作为 Embed 描述
+	d.tmpl = loadMessageTemplate("discord", "📩 新验证码", "")
+	return d
+}
+
+func (d *DiscordForwarder) Name() string { return "Discord" }
+
+// Forward 以 Embed 形式推送，包含来源、验证码和接收时间
+func (d *DiscordForwarder) Forward(msg ForwardMessage) error {
+	title, description, err := d.tmpl.Render(msg)
+	if err != nil {
+		return err
+	}
+	embed := map[string]any{
+		"title": title,
+		"color": 0x5865F2,
+		"fields": []map[string]any{
+			{"name": "验证码", "value": msg.Code, "inline": true},
+			{"name": "来源", "value": msg.From, "inline": true},
+			{"name": "接收时间", "value": msg.Time(), "inline": false},
+		},
+		"timestamp": time.UnixMilli(msg.ReceivedAt).UTC().Format(time.RFC3339),
+	}
+	if description != "" {
+		embed["description"] = description
+	}
+	payload := map[string]any{"embeds": []any{embed}}
+	if d.Username != "" {
+		payload["username"] = d.Username
+	}
+
+	if _, err := postJSON(d.WebhookURL, payload); err != nil {
+		return fmt.Errorf("请求Discord失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+/* ---------- 邮件（SMTP）转发 ---------- */
+
+func init() { registerForwarder("email", newEmailForwarder) }
+
+// EmailForwarder 通过 SMTP 将验证码发送到一个或多个邮箱
+type EmailForwarder struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+	To       []string
+	TLSMode  string // starttls / tls / none
+	tmpl     *MessageTemplate
+}
+
+// 从环境变量创建邮件转发，未配置 SMTP_HOST 或收件人时返回 nil
+func newEmailForwarder() Forwarder {
+	host := getEnvWithDefault("SMTP_HOST", "")
+	to := splitAndTrim(getEnvWithDefault("SMTP_TO", ""))
+	if host == "" || len(to) == 0 {
+		return nil
+	}
+
+	port := getEnvWithDefault("SMTP_PORT", "587")
+	defaultMode := "starttls"
+	if port == "465" {
+		defaultMode = "tls"
+	}
+	username := getEnvWithDefault("SMTP_USERNAME", "")
+	return (&EmailForwarder{
+		Host:     host,
+		Port:     port,
+		Username: username,
+		Password: getEnvWithDefault("SMTP_PASSWORD", ""),
+		From:     getEnvWithDefault("SMTP_FROM", username),
+		To:       to,
+		TLSMode:  strings.ToLower(getEnvWithDefault("SMTP_TLS_MODE", defaultMode)),
+	}).setup()
+}
+
+func (e *EmailForwarder) setup() *EmailForwarder {
+	e.tmpl = loadMessageTemplate("email", "验证码 {{.Code}}（来源 {{.From}}）", defaultBodyTemplate)
+	return e
+}
+
+func (e *EmailForwarder) Name() string { return "邮件" }
+
+// 按 TLS 模式建立 SMTP 连接
+func (e *EmailForwarder) dial() (*smtp.Client, error) {
+	addr := net.JoinHostPort(e.Host, e.Port)
+	tlsCfg := &tls.Config{ServerName: e.Host}
+
+	if e.TLSMode == "tls" {
+		conn, err := tls.DialWithDialer(&net.Dialer{Timeout: 10 * time.Second}, "tcp", addr, tlsCfg)
+		if err != nil {
+			return nil, err
+		}
+		return smtp.NewClient(conn, e.Host)
+	}
+
+	conn, err := net.DialTimeout("tcp", addr, 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	client, err := smtp.NewClient(conn, e.Host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if e.TLSMode == "starttls" {
+		if err := client.StartTLS(tlsCfg); err != nil {
+			client.Close()
+			return nil, fmt.Errorf("STARTTLS失败: %w", err)
+		}
+	}
+	return client, nil
+}
+
+// 组装 RFC 5322 邮件内容
+func (e *EmailForwarder) buildMessage(msg ForwardMessage) ([]byte, error) {
+	title, text, err := e.tmpl.Render(msg)
+	if err != nil {
+		return nil, err
+	}
+	subject := mime.QEncoding.Encode("UTF-8", title)
+	body := strings.ReplaceAll(text, "\n", "\r\n") + "\r\n"
+
+	var sb strings.Builder
+	sb.WriteString("From: " + e.From + "\r\n")
+	sb.WriteString("To: " + strings.Join(e.To, ", ") + "\r\n")
+	sb.WriteString("Subject: " + subject + "\r\n")
+	sb.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	sb.WriteString("MIME-Version: 1.0\r\n")
+	sb.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	sb.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	sb.WriteString(body)
+	return []byte(sb.String()), nil
+}
+
+// Forward 发送邮件
+func (e *EmailForwarder) Forward(msg ForwardMessage) error {
+	content, err := e.buildMessage(msg)
+	if err != nil {
+		return err
+	}
+
+	client, err := e.dial()
+	if err != nil {
+		return fmt.Errorf("连接SMTP服务器失败: %w", err)
+	}
+	defer client.Close()
+
+	if e.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", e.Username, e.Password, e.Host)); err != nil {
+			return fmt.Errorf("SMTP认证失败: %w", err)
+		}
+	}
+	if err := client.Mail(e.From); err != nil {
+		return fmt.Errorf("设置发件人失败: %w", err)
+	}
+	for _, rcpt := range e.To {
+		if err := client.Rcpt(rcpt); err != nil {
+			return fmt.Errorf("设置收件人 %s 失败: %w", rcpt, err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("发送邮件失败: %w", err)
+	}
+	if _, err := w.Write(content); err != nil {
+		return fmt.Errorf("写入邮件内容失败: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("发送邮件失败: %w", err)
+	}
+	return client.Quit()
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/* ---------- 飞书 / Lark 转发 ---------- */
+
+func init() { registerForwarder("feishu", newFeishuForwarder) }
+
+// FeishuForwarder 推送到飞书（或 Lark）群自定义机器人
+type FeishuForwarder struct {
+	WebhookURL string
+	Secret     string // 签名校验密钥，为空表示未开启
+	tmpl       *MessageTemplate
+}
+
+// 从环境变量创建飞书转发，未配置 FEISHU_BOT_TOKEN 时返回 nil
+func newFeishuForwarder() Forwarder {
+	token := getEnvWithDefault("FEISHU_BOT_TOKEN", "")
+	if token == "" {
+		return nil
+	}
+	// 国际版 Lark 使用 https://open.larksuite.com
+	base := strings.TrimRight(getEnvWithDefault("FEISHU_API_BASE", "https://open.feishu.cn"), "/")
+	return (&FeishuForwarder{
+		WebhookURL: base + "/open-apis/bot/v2/hook/" + token,
+		Secret:     getEnvWithDefault("FEISHU_SECRET", ""),
+	}).setup()
+}
+
+func (f *FeishuForwarder) setup() *FeishuForwarder {
+	f.tmpl = loadMessageTemplate("feishu", defaultTitleTemplate, defaultBodyTemplate)
+	return f
+}
+
+func (f *FeishuForwarder) Name() string { return "飞书" }
+
+// 签名：以 timestamp + "\n" + secret 为密钥对空串做 HmacSHA256 后 Base64
+func (f *FeishuForwarder) sign(ts string) string {
+	mac := hmac.New(sha256.New, []byte(ts+"\n"+f.Secret))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Forward 以 text 消息推送验证码
+func (f *FeishuForwarder) Forward(msg ForwardMessage) error {
+	_, text, err := f.tmpl.Render(msg)
+	if err != nil {
+		return err
+	}
+	payload := map[string]any{
+		"msg_type": "text",
+		"content":  map[string]string{"text": text},
+	}
+	if f.Secret != "" {
+		ts := strconv.FormatInt(time.Now().Unix(), 10)
+		payload["timestamp"] = ts
+		payload["sign"] = f.sign(ts)
+	}
+
+	body, err := postJSON(f.WebhookURL, payload)
+	if err != nil {
+		return fmt.Errorf("请求飞书失败: %w", err)
+	}
+
+	var result struct {
+		Code int    `json:"code"`
+		Msg  string `json:"msg"`
+	}
+	if err := json.Unmarshal(body, &result); err == nil && result.Code != 0 {
+		return fmt.Errorf("飞书返回错误 %d: %s", result.Code, result.Msg)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+)
+
+/* ---------- Google Chat 转发 ---------- */
+
+func init() { registerForwarder("googlechat", newGoogleChatForwarder) }
+
+// GoogleChatForwarder 通过空间 Incoming Webhook 发送卡片消息
+type GoogleChatForwarder struct {
+	WebhookURL string
+	tmpl       *MessageTemplate
+}
+
+// 从环境变量创建 Google Chat 转发，未配置 GOOGLECHAT_WEBHOOK_URL 时返回 nil
+func newGoogleChatForwarder() Forwarder {
+	url := getEnvWithDefault("GOOGLECHAT_WEBHOOK_URL", "")
+	if url == "" {
+		return nil
+	}
+	return (&GoogleChatForwarder{
+		WebhookURL: url,
+	}).setup()
+}
+
+func (g *GoogleChatForwarder) setup() *GoogleChatForwarder {
+	// 正文模板默认为空，只展示卡片；配置 GOOGLECHAT_TEMPLATE 后作为消息文本
+	g.tmpl = loadMessageTemplate("googlechat", "📩 新验证码", "")
+	return g
+}
+
+func (g *GoogleChatForwarder) Name() string { return "Google Chat" }
+
+// 卡片中的一行字段
+func googleChatField(label, value string) map[string]any {
+	return map[string]any{"decoratedText": map[string]string{"topLabel": label, "text": value}}
+}
+
+// Forward 发送 cardsV2 卡片，展示来源、验证码和时间
+func (g *GoogleChatForwarder) Forward(msg ForwardMessage) error {
+	title, text, err := g.tmpl.Render(msg)
+	if err != nil {
+		return err
+	}
+
+	card := map[string]any{
+		"header": map[string]string{"title": title, "subtitle": msg.From},
+		"sections": []map[string]any{{
+			"widgets": []map[string]any{
+				googleChatField("验证码", msg.Code),
+				googleChatField("来源", msg.From),
+				googleChatField("时间", msg.Time()),
+			},
+		}},
+	}
+	payload := map[string]any{
+		"cardsV2": []map[string]any{{"cardId": "sms", "card": card}},
+	}
+	if text != "" {
+		payload["text"] = text
+	}
+
+	if _, err := postJSON(g.WebhookURL, payload); err != nil {
+		return fmt.Errorf("请求Google Chat失败: %w", err)
+	}
+	return nil
+}
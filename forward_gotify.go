@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+/* ---------- Gotify 转发 ---------- */
+
+func init() { registerForwarder("gotify", newGotifyForwarder) }
+
+// GotifyForwarder 推送到自建 Gotify 服务
+type GotifyForwarder struct {
+	ServerURL string
+	AppToken  string
+	Priority  int
+	tmpl      *MessageTemplate
+}
+
+// 从环境变量创建 Gotify 转发，缺少 URL / 应用 Token 时返回 nil
+func newGotifyForwarder() Forwarder {
+	server := getEnvWithDefault("GOTIFY_URL", "")
+	token := getEnvWithDefault("GOTIFY_APP_TOKEN", "")
+	if server == "" || token == "" {
+		return nil
+	}
+	priority, _ := strconv.Atoi(getEnvWithDefault("GOTIFY_PRIORITY", "5"))
+	return (&GotifyForwarder{
+		ServerURL: strings.TrimRight(server, "/"),
+		AppToken:  token,
+		Priority:  priority,
+	}).setup()
+}
+
+func (g *GotifyForwarder) setup() *GotifyForwarder {
+	g.tmpl = loadMessageTemplate("gotify", defaultTitleTemplate, "来源: {{.From}}\n时间: {{.Time}}")
+	return g
+}
+
+func (g *GotifyForwarder) Name() string { return "Gotify" }
+
+// Forward 调用 /message 接口推送
+func (g *GotifyForwarder) Forward(msg ForwardMessage) error {
+	title, body, err := g.tmpl.Render(msg)
+	if err != nil {
+		return err
+	}
+	payload := map[string]any{
+		"title":    title,
+		"message":  body,
+		"priority": g.Priority,
+	}
+	if _, err := postJSON(g.ServerURL+"/message?token="+url.QueryEscape(g.AppToken), payload); err != nil {
+		return fmt.Errorf("请求Gotify失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+/* ---------- Kafka 生产者 ---------- */
+
+func init() { registerForwarder("kafka", newKafkaForwarder) }
+
+// KafkaForwarder 将每条短信写入 Kafka 主题，供下游流水线消费
+type KafkaForwarder struct {
+	KeyByPhone bool
+	writer     *kafka.Writer
+}
+
+// 从环境变量创建 Kafka 转发，缺少 brokers / topic 时返回 nil
+func newKafkaForwarder() Forwarder {
+	brokers := splitAndTrim(getEnvWithDefault("KAFKA_BROKERS", ""))
+	topic := getEnvWithDefault("KAFKA_TOPIC", "")
+	if len(brokers) == 0 || topic == "" {
+		return nil
+	}
+
+	keyByPhone := getEnvWithDefault("KAFKA_KEY_BY_PHONE", "true") == "true"
+	var balancer kafka.Balancer = &kafka.LeastBytes{}
+	if keyByPhone {
+		balancer = &kafka.Hash{} // 同一号码落在同一分区，保证顺序
+	}
+
+	return &KafkaForwarder{
+		KeyByPhone: keyByPhone,
+		writer: &kafka.Writer{
+			Addr:         kafka.TCP(brokers...),
+			Topic:        topic,
+			Balancer:     balancer,
+			RequiredAcks: kafka.RequireOne,
+			WriteTimeout: 10 * time.Second,
+		},
+	}
+}
+
+func (k *KafkaForwarder) Name() string { return "Kafka" }
+
+// Forward 写入一条 JSON 消息，按需以号码作为消息 key 分区
+func (k *KafkaForwarder) Forward(msg ForwardMessage) error {
+	value, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("序列化消息失败: %w", err)
+	}
+
+	record := kafka.Message{Value: value, Time: time.UnixMilli(msg.ReceivedAt)}
+	if k.KeyByPhone {
+		record.Key = []byte(msg.From)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	if err := k.writer.WriteMessages(ctx, record); err != nil {
+		return fmt.Errorf("写入Kafka失败: %w", err)
+	}
+	return nil
+}
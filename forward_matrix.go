@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+/* ---------- Matrix 转发 ---------- */
+
+func init() { registerForwarder("matrix", newMatrixForwarder) }
+
+// MatrixForwarder 使用 access token 向 Matrix 房间发送消息。
+// 本服务不支持端到端加密，若主房间已开启加密且配置了备用房间，则改发到备用（未加密）房间
+type MatrixForwarder struct {
+	Homeserver     string
+	AccessToken    string
+	RoomID         string
+	FallbackRoomID string
+	tmpl           *MessageTemplate
+
+	encryptedOnce sync.Once
+	encrypted     bool
+}
+
+// 从环境变量创建 Matrix 转发，缺少 homeserver / token / 房间时返回 nil
+func newMatrixForwarder() Forwarder {
+	hs := getEnvWithDefault("MATRIX_HOMESERVER", "")
+	token := getEnvWithDefault("MATRIX_ACCESS_TOKEN", "")
+	room := getEnvWithDefault("MATRIX_ROOM_ID", "")
+	if hs == "" || token == "" || room == "" {
+		return nil
+	}
+	return &MatrixForwarder{
+		Homeserver:     strings.TrimRight(hs, "/"),
+		AccessToken:    token,
+		RoomID:         room,
+		FallbackRoomID: getEnvWithDefault("MATRIX_FALLBACK_ROOM_ID", ""),
+		tmpl:           loadMessageTemplate("matrix", defaultTitleTemplate, defaultBodyTemplate),
+	}
+}
+
+func (m *MatrixForwarder) Name() string { return "Matrix" }
+
+// 调用 Matrix Client-Server API
+func (m *MatrixForwarder) do(method, path string, payload any) (int, error) {
+	var body io.Reader
+	if payload != nil {
+		data, _ := json.Marshal(payload)
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, m.Homeserver+path, body)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Authorization", "Bearer "+m.AccessToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := forwardHTTPClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("异常状态码 %d: %s", resp.StatusCode, string(respBody))
+	}
+	return resp.StatusCode, nil
+}
+
+// 检查主房间是否开启了加密（只检查一次）
+func (m *MatrixForwarder) roomEncrypted() bool {
+	m.encryptedOnce.Do(func() {
+		path := fmt.Sprintf("/_matrix/client/v3/rooms/%s/state/m.room.encryption", url.PathEscape(m.RoomID))
+		status, _ := m.do(http.MethodGet, path, nil)
+		m.encrypted = status == http.StatusOK
+		if m.encrypted {
+			log.Printf("Matrix房间 %s 已开启端到端加密", m.RoomID)
+		}
+	})
+	return m.encrypted
+}
+
+// 向指定房间发送 m.text 消息
+func (m *MatrixForwarder) send(roomID string, msg ForwardMessage) error {
+	_, text, err := m.tmpl.Render(msg)
+	if err != nil {
+		return err
+	}
+	content := map[string]string{
+		"msgtype": "m.text",
+		"body":    text,
+	}
+	txnID := fmt.Sprintf("sms-%d-%d", msg.ReceivedAt, time.Now().UnixNano())
+	path := fmt.Sprintf("/_matrix/client/v3/rooms/%s/send/m.room.message/%s",
+		url.PathEscape(roomID), url.PathEscape(txnID))
+	_, err = m.do(http.MethodPut, path, content)
+	return err
+}
+
+// Forward 发送到主房间；主房间加密或发送失败时使用备用房间
+func (m *MatrixForwarder) Forward(msg ForwardMessage) error {
+	if m.FallbackRoomID != "" && m.roomEncrypted() {
+		return m.send(m.FallbackRoomID, msg)
+	}
+
+	err := m.send(m.RoomID, msg)
+	if err != nil && m.FallbackRoomID != "" {
+		log.Printf("Matrix主房间发送失败，改用备用房间: %v", err)
+		err = m.send(m.FallbackRoomID, msg)
+	}
+	if err != nil {
+		return fmt.Errorf("请求Matrix失败: %w", err)
+	}
+	return nil
+}
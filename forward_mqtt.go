@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+/* ---------- MQTT 发布 ---------- */
+
+func init() { registerForwarder("mqtt", newMQTTForwarder) }
+
+// MQTTForwarder 将每条短信（原文 + 验证码）发布到 MQTT Broker
+type MQTTForwarder struct {
+	TopicTemplate string // 支持 {from} 占位符，如 sms/{from}
+	QoS           byte
+	Retain        bool
+	client        mqtt.Client
+}
+
+// 从环境变量创建 MQTT 转发，未配置 MQTT_BROKER 时返回 nil
+func newMQTTForwarder() Forwarder {
+	broker := getEnvWithDefault("MQTT_BROKER", "")
+	if broker == "" {
+		return nil
+	}
+
+	hostname, _ := os.Hostname()
+	opts := mqtt.NewClientOptions().
+		AddBroker(broker).
+		SetClientID(getEnvWithDefault("MQTT_CLIENT_ID", "sms-forwarder-"+hostname)).
+		SetUsername(getEnvWithDefault("MQTT_USERNAME", "")).
+		SetPassword(getEnvWithDefault("MQTT_PASSWORD", "")).
+		SetAutoReconnect(true).
+		SetConnectRetry(true). // Broker 暂不可用时后台重连，不阻塞启动
+		SetConnectTimeout(10 * time.Second).
+		SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+			log.Printf("MQTT连接断开: %v", err)
+		})
+
+	client := mqtt.NewClient(opts)
+	client.Connect()
+
+	qos, _ := strconv.Atoi(getEnvWithDefault("MQTT_QOS", "1"))
+	return &MQTTForwarder{
+		TopicTemplate: getEnvWithDefault("MQTT_TOPIC", "sms/{from}"),
+		QoS:           byte(qos),
+		Retain:        getEnvWithDefault("MQTT_RETAIN", "false") == "true",
+		client:        client,
+	}
+}
+
+func (m *MQTTForwarder) Name() string { return "MQTT" }
+
+// Forward 发布 JSON 消息到按来源号码展开的主题
+func (m *MQTTForwarder) Forward(msg ForwardMessage) error {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("序列化消息失败: %w", err)
+	}
+
+	topic := strings.ReplaceAll(m.TopicTemplate, "{from}", msg.From)
+	token := m.client.Publish(topic, m.QoS, m.Retain, payload)
+	if !token.WaitTimeout(10 * time.Second) {
+		return fmt.Errorf("发布到 %s 超时", topic)
+	}
+	if err := token.Error(); err != nil {
+		return fmt.Errorf("发布到 %s 失败: %w", topic, err)
+	}
+	return nil
+}
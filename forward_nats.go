@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+/* ---------- NATS 发布 ---------- */
+
+func init() { registerForwarder("nats", newNATSForwarder) }
+
+// NATSForwarder 发布到 NATS 主题，可选使用 JetStream 持久化
+type NATSForwarder struct {
+	SubjectTemplate string // 支持 {from} 占位符，如 sms.received.{from}
+	conn            *nats.Conn
+	js              nats.JetStreamContext // 为 nil 表示使用 Core NATS
+}
+
+// 从环境变量创建 NATS 转发，未配置 NATS_URL 时返回 nil
+func newNATSForwarder() Forwarder {
+	url := getEnvWithDefault("NATS_URL", "")
+	if url == "" {
+		return nil
+	}
+
+	conn, err := nats.Connect(url,
+		nats.Name("sms-forwarder"),
+		nats.MaxReconnects(-1),
+		nats.RetryOnFailedConnect(true), // 启动时连不上也在后台重试
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			if err != nil {
+				log.Printf("NATS连接断开: %v", err)
+			}
+		}),
+	)
+	if err != nil {
+		log.Printf("NATS初始化失败，已跳过: %v", err)
+		return nil
+	}
+
+	f := &NATSForwarder{
+		SubjectTemplate: getEnvWithDefault("NATS_SUBJECT", "sms.received.{from}"),
+		conn:            conn,
+	}
+
+	if getEnvWithDefault("NATS_JETSTREAM", "false") == "true" {
+		js, err := conn.JetStream()
+		if err != nil {
+			log.Printf("NATS JetStream不可用，已跳过: %v", err)
+			return nil
+		}
+		f.js = js
+		f.ensureStream(getEnvWithDefault("NATS_STREAM", "SMS"))
+	}
+	return f
+}
+
+// 确保 JetStream stream 存在，覆盖主题模板对应的所有主题
+func (n *NATSForwarder) ensureStream(name string) {
+	subject := strings.ReplaceAll(n.SubjectTemplate, "{from}", "*")
+	if _, err := n.js.StreamInfo(name); err == nil {
+		return
+	}
+	if _, err := n.js.AddStream(&nats.StreamConfig{
+		Name:     name,
+		Subjects: []string{subject},
+		Storage:  nats.FileStorage,
+	}); err != nil {
+		log.Printf("创建JetStream stream %s 失败: %v", name, err)
+	}
+}
+
+func (n *NATSForwarder) Name() string { return "NATS" }
+
+// Forward 发布 JSON 消息；JetStream 模式下等待服务端确认
+func (n *NATSForwarder) Forward(msg ForwardMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("序列化消息失败: %w", err)
+	}
+
+	subject := strings.ReplaceAll(n.SubjectTemplate, "{from}", msg.From)
+	if n.js != nil {
+		if _, err := n.js.Publish(subject, data, nats.AckWait(10*time.Second)); err != nil {
+			return fmt.Errorf("发布到JetStream %s 失败: %w", subject, err)
+		}
+		return nil
+	}
+	if err := n.conn.Publish(subject, data); err != nil {
+		return fmt.Errorf("发布到 %s 失败: %w", subject, err)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+/* ---------- ntfy 转发 ---------- */
+
+func init() { registerForwarder("ntfy", newNtfyForwarder) }
+
+// NtfyForwarder 发布到 ntfy 主题（ntfy.sh 或自建）
+type NtfyForwarder struct {
+	Server   string
+	Topic    string
+	Token    string
+	Tags     []string
+	Priority int
+	tmpl     *MessageTemplate
+}
+
+// 从环境变量创建 ntfy 转发，未配置 NTFY_TOPIC 时返回 nil
+func newNtfyForwarder() Forwarder {
+	topic := getEnvWithDefault("NTFY_TOPIC", "")
+	if topic == "" {
+		return nil
+	}
+	priority, _ := strconv.Atoi(getEnvWithDefault("NTFY_PRIORITY", "3"))
+	return (&NtfyForwarder{
+		Server:   strings.TrimRight(getEnvWithDefault("NTFY_SERVER", "https://ntfy.sh"), "/"),
+		Topic:    topic,
+		Token:    getEnvWithDefault("NTFY_TOKEN", ""),
+		Tags:     splitAndTrim(getEnvWithDefault("NTFY_TAGS", "envelope")),
+		Priority: priority,
+	}).setup()
+}
+
+// setup 的默认标题取自 NTFY_TITLE，兼容模板功能之前的配置
+func (n *NtfyForwarder) setup() *NtfyForwarder {
+	n.tmpl = loadMessageTemplate("ntfy", getEnvWithDefault("NTFY_TITLE", "新验证码"), "验证码: {{.Code}}\n来源: {{.From}}\n时间: {{.Time}}")
+	return n
+}
+
+func (n *NtfyForwarder) Name() string { return "ntfy" }
+
+// Forward 以 JSON 方式发布，避免非 ASCII 标题放在请求头里的编码问题
+func (n *NtfyForwarder) Forward(msg ForwardMessage) error {
+	title, body, err := n.tmpl.Render(msg)
+	if err != nil {
+		return err
+	}
+	payload := map[string]any{
+		"topic":    n.Topic,
+		"title":    title,
+		"message":  body,
+		"tags":     n.Tags,
+		"priority": n.Priority,
+	}
+	var headers map[string]string
+	if n.Token != "" {
+		headers = map[string]string{"Authorization": "Bearer " + n.Token}
+	}
+
+	if _, err := postJSONWithHeaders(n.Server, payload, headers); err != nil {
+		return fmt.Errorf("请求ntfy失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+/* ---------- PagerDuty 安全告警 ---------- */
+
+func init() { registerForwarder("pagerduty", newPagerDutyForwarder) }
+
+// pagerDutyKeyword 告警关键词及对应的事件级别
+type pagerDutyKeyword struct {
+	Keyword  string
+	Severity string
+}
+
+// PagerDutyForwarder 短信命中告警关键词时触发 PagerDuty 事件（Events API v2），
+// 未命中的短信直接忽略
+type PagerDutyForwarder struct {
+	RoutingKey      string
+	DefaultSeverity string
+	Keywords        []pagerDutyKeyword
+}
+
+// PagerDuty 支持的事件级别
+var pagerDutySeverities = map[string]bool{"critical": true, "error": true, "warning": true, "info": true}
+
+// 从环境变量创建 PagerDuty 转发，缺少 routing key 或关键词时返回 nil
+func newPagerDutyForwarder() Forwarder {
+	key := getEnvWithDefault("PAGERDUTY_ROUTING_KEY", "")
+	if key == "" {
+		return nil
+	}
+	severity := strings.ToLower(getEnvWithDefault("PAGERDUTY_SEVERITY", "warning"))
+	if !pagerDutySeverities[severity] {
+		log.Printf("PAGERDUTY_SEVERITY=%s 无效，使用 warning", severity)
+		severity = "warning"
+	}
+
+	keywords := parsePagerDutyKeywords(getEnvWithDefault("PAGERDUTY_KEYWORDS", "登录异常,异地登录,诈骗,盗刷"), severity)
+	if len(keywords) == 0 {
+		return nil
+	}
+	if getEnvWithDefault("PAGERDUTY_INCLUDE_RAW", getEnvWithDefault("FORWARD_INCLUDE_RAW", "true")) != "true" {
+		log.Printf("PagerDuty 未接收原始短信内容，关键词告警将无法命中")
+	}
+	return &PagerDutyForwarder{RoutingKey: key, DefaultSeverity: severity, Keywords: keywords}
+}
+
+// 解析 "登录异常:critical,诈骗" 形式的关键词配置，未写级别的使用默认级别
+func parsePagerDutyKeywords(s, defaultSeverity string) []pagerDutyKeyword {
+	var keywords []pagerDutyKeyword
+	for _, item := range splitAndTrim(s) {
+		kw, sev, ok := strings.Cut(item, ":")
+		sev = strings.ToLower(strings.TrimSpace(sev))
+		if !ok || !pagerDutySeverities[sev] {
+			sev = defaultSeverity
+		}
+		keywords = append(keywords, pagerDutyKeyword{Keyword: strings.TrimSpace(kw), Severity: sev})
+	}
+	return keywords
+}
+
+func (p *PagerDutyForwarder) Name() string { return "PagerDuty" }
+
+// AcceptsCodeless 安全告警短信通常不含验证码，也需要检查
+func (p *PagerDutyForwarder) AcceptsCodeless() bool { return true }
+
+// 返回第一个命中的关键词
+func (p *PagerDutyForwarder) match(msg ForwardMessage) (pagerDutyKeyword, bool) {
+	for _, k := range p.Keywords {
+		if strings.Contains(msg.RawContent, k.Keyword) {
+			return k, true
+		}
+	}
+	return pagerDutyKeyword{}, false
+}
+
+// Forward 命中关键词时触发事件，同一条短信使用相同 dedup_key 避免重复告警
+func (p *PagerDutyForwarder) Forward(msg ForwardMessage) error {
+	kw, ok := p.match(msg)
+	if !ok {
+		return nil
+	}
+
+	event := map[string]any{
+		"routing_key":  p.RoutingKey,
+		"event_action": "trigger",
+		"dedup_key":    fmt.Sprintf("sms:%s:%d", msg.From, msg.ReceivedAt),
+		"payload": map[string]any{
+			"summary":   fmt.Sprintf("[%s] 短信命中告警关键词「%s」", msg.From, kw.Keyword),
+			"source":    msg.From,
+			"severity":  kw.Severity,
+			"timestamp": time.UnixMilli(msg.ReceivedAt).UTC().Format(time.RFC3339),
+			"custom_details": map[string]string{
+				"content": msg.RawContent,
+				"code":    msg.Code,
+				"keyword": kw.Keyword,
+			},
+		},
+	}
+	if _, err := postJSON("https://events.pagerduty.com/v2/enqueue", event); err != nil {
+		return fmt.Errorf("请求PagerDuty失败: %w", err)
+	}
+	return nil
+}
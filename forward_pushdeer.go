@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+/* ---------- PushDeer 转发 ---------- */
+
+func init() { registerForwarder("pushdeer", newPushDeerForwarder) }
+
+// PushDeerForwarder 推送到 PushDeer（官方或自建服务）
+type PushDeerForwarder struct {
+	Server  string
+	PushKey string
+	tmpl    *MessageTemplate
+}
+
+// 从环境变量创建 PushDeer 转发，未配置 PUSHDEER_PUSHKEY 时返回 nil
+func newPushDeerForwarder() Forwarder {
+	key := getEnvWithDefault("PUSHDEER_PUSHKEY", "")
+	if key == "" {
+		return nil
+	}
+	return (&PushDeerForwarder{
+		Server:  strings.TrimRight(getEnvWithDefault("PUSHDEER_SERVER", "https://api2.pushdeer.com"), "/"),
+		PushKey: key,
+	}).setup()
+}
+
+func (p *PushDeerForwarder) setup() *PushDeerForwarder {
+	p.tmpl = loadMessageTemplate("pushdeer", defaultTitleTemplate, "来源: {{.From}}\n\n时间: {{.Time}}")
+	return p
+}
+
+func (p *PushDeerForwarder) Name() string { return "PushDeer" }
+
+// Forward 以 Markdown 消息推送
+func (p *PushDeerForwarder) Forward(msg ForwardMessage) error {
+	title, desp, err := p.tmpl.Render(msg)
+	if err != nil {
+		return err
+	}
+	payload := map[string]string{
+		"pushkey": p.PushKey,
+		"type":    "markdown",
+		"text":    title,
+		"desp":    desp,
+	}
+	body, err := postJSON(p.Server+"/message/push", payload)
+	if err != nil {
+		return fmt.Errorf("请求PushDeer失败: %w", err)
+	}
+
+	var result struct {
+		Code  int    `json:"code"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(body, &result); err == nil && result.Code != 0 {
+		return fmt.Errorf("PushDeer返回错误 %d: %s", result.Code, result.Error)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+)
+
+/* ---------- Pushover 转发 ---------- */
+
+func init() { registerForwarder("pushover", newPushoverForwarder) }
+
+// pushoverPriorityRule 关键词 → 优先级映射
+type pushoverPriorityRule struct {
+	Keyword  string
+	Priority int
+}
+
+// PushoverForwarder 推送到 Pushover，支持按关键词提升优先级
+type PushoverForwarder struct {
+	AppToken        string
+	UserKey         string
+	DefaultPriority int
+	Rules           []pushoverPriorityRule
+	tmpl            *MessageTemplate
+}
+
+// 从环境变量创建 Pushover 转发，缺少 token / user key 时返回 nil
+func newPushoverForwarder() Forwarder {
+	token := getEnvWithDefault("PUSHOVER_APP_TOKEN", "")
+	user := getEnvWithDefault("PUSHOVER_USER_KEY", "")
+	if token == "" || user == "" {
+		return nil
+	}
+	def, _ := strconv.Atoi(getEnvWithDefault("PUSHOVER_PRIORITY", "0"))
+	return (&PushoverForwarder{
+		AppToken:        token,
+		UserKey:         user,
+		DefaultPriority: def,
+		Rules:           parsePushoverRules(getEnvWithDefault("PUSHOVER_PRIORITY_MAP", "")),
+	}).setup()
+}
+
+// 解析 "银行:1,安全:2" 形式的优先级映射，按配置顺序匹配
+func parsePushoverRules(s string) []pushoverPriorityRule {
+	var rules []pushoverPriorityRule
+	for _, item := range splitAndTrim(s) {
+		kw, p, ok := strings.Cut(item, ":")
+		priority, err := strconv.Atoi(strings.TrimSpace(p))
+		if !ok || err != nil || priority < -2 || priority > 2 {
+			log.Printf("忽略无效的 PUSHOVER_PRIORITY_MAP 项: %s", item)
+			continue
+		}
+		rules = append(rules, pushoverPriorityRule{Keyword: strings.TrimSpace(kw), Priority: priority})
+	}
+	return rules
+}
+
+func (p *PushoverForwarder) setup() *PushoverForwarder {
+	p.tmpl = loadMessageTemplate("pushover", defaultTitleTemplate, "来源: {{.From}}\n时间: {{.Time}}")
+	return p
+}
+
+func (p *PushoverForwarder) Name() string { return "Pushover" }
+
+// 根据来源号码或原始内容中的关键词确定优先级；PUSHOVER_INCLUDE_RAW=false 时消息中没有原始内容，同样按原始内容匹配
+func (p *PushoverForwarder) priority(msg ForwardMessage) int {
+	content := msg.content
+	if content == "" {
+		content = msg.RawContent
+	}
+	for _, r := range p.Rules {
+		if strings.Contains(content, r.Keyword) || strings.Contains(msg.From, r.Keyword) {
+			return r.Priority
+		}
+	}
+	return p.DefaultPriority
+}
+
+// Forward 调用 messages 接口推送
+func (p *PushoverForwarder) Forward(msg ForwardMessage) error {
+	title, body, err := p.tmpl.Render(msg)
+	if err != nil {
+		return err
+	}
+	priority := p.priority(msg)
+	payload := map[string]any{
+		"token":     p.AppToken,
+		"user":      p.UserKey,
+		"title":     title,
+		"message":   body,
+		"priority":  priority,
+		"timestamp": msg.ReceivedAt / 1000,
+	}
+	if priority == 2 { // 紧急优先级必须带重试参数
+		payload["retry"] = 60
+		payload["expire"] = 3600
+	}
+
+	if _, err := postJSON("https://api.pushover.net/1/messages.json", payload); err != nil {
+		return fmt.Errorf("请求Pushover失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+)
+
+/* ---------- Server酱 转发 ---------- */
+
+func init() { registerForwarder("serverchan", newServerChanForwarder) }
+
+// Server酱³ 的 SendKey 形如 sctp{uid}t…，需要使用专属域名
+var reServerChan3Key = regexp.MustCompile(`^sctp(\d+)t`)
+
+// ServerChanForwarder 通过 Server酱（SCT）推送到微信
+type ServerChanForwarder struct {
+	SendKey string
+	tmpl    *MessageTemplate
+}
+
+// 从环境变量创建 Server酱 转发，未配置 SERVERCHAN_SENDKEY 时返回 nil
+func newServerChanForwarder() Forwarder {
+	key := getEnvWithDefault("SERVERCHAN_SENDKEY", "")
+	if key == "" {
+		return nil
+	}
+	return (&ServerChanForwarder{
+		SendKey: key,
+	}).setup()
+}
+
+func (s *ServerChanForwarder) setup() *ServerChanForwarder {
+	s.tmpl = loadMessageTemplate("serverchan", defaultTitleTemplate, "**验证码**: {{.Code}}\n\n**来源**: {{.From}}\n\n**时间**: {{.Time}}")
+	return s
+}
+
+func (s *ServerChanForwarder) Name() string { return "Server酱" }
+
+// 根据 SendKey 类型选择推送地址
+func (s *ServerChanForwarder) endpoint() string {
+	if m := reServerChan3Key.FindStringSubmatch(s.SendKey); len(m) == 2 {
+		return fmt.Sprintf("https://%s.push.ft07.com/send/%s.send", m[1], s.SendKey)
+	}
+	return fmt.Sprintf("https://sctapi.ftqq.com/%s.send", s.SendKey)
+}
+
+// Forward 推送标题 + Markdown 正文
+func (s *ServerChanForwarder) Forward(msg ForwardMessage) error {
+	title, desp, err := s.tmpl.Render(msg)
+	if err != nil {
+		return err
+	}
+	payload := map[string]string{"title": title, "desp": desp}
+	body, err := postJSON(s.endpoint(), payload)
+	if err != nil {
+		return fmt.Errorf("请求Server酱失败: %w", err)
+	}
+
+	var result struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(body, &result); err == nil && result.Code != 0 {
+		return fmt.Errorf("Server酱返回错误 %d: %s", result.Code, result.Message)
+	}
+	return nil
+}
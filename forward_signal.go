@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+/* ---------- Signal 转发（signal-cli-rest-api） ---------- */
+
+func init() { registerForwarder("signal", newSignalForwarder) }
+
+// SignalForwarder 通过 signal-cli-rest-api 容器发送 Signal 消息
+type SignalForwarder struct {
+	APIURL     string
+	Number     string   // 已在 signal-cli 注册的发送号码
+	Recipients []string // 接收号码或群组 ID（group.xxx）
+	tmpl       *MessageTemplate
+}
+
+// 从环境变量创建 Signal 转发，缺少 API 地址 / 发送号码 / 接收方时返回 nil
+func newSignalForwarder() Forwarder {
+	apiURL := getEnvWithDefault("SIGNAL_API_URL", "")
+	number := getEnvWithDefault("SIGNAL_NUMBER", "")
+	recipients := splitAndTrim(getEnvWithDefault("SIGNAL_RECIPIENTS", ""))
+	if apiURL == "" || number == "" || len(recipients) == 0 {
+		return nil
+	}
+	return (&SignalForwarder{
+		APIURL:     strings.TrimRight(apiURL, "/"),
+		Number:     number,
+		Recipients: recipients,
+	}).setup()
+}
+
+func (s *SignalForwarder) setup() *SignalForwarder {
+	s.tmpl = loadMessageTemplate("signal", defaultTitleTemplate, defaultBodyTemplate)
+	return s
+}
+
+func (s *SignalForwarder) Name() string { return "Signal" }
+
+// Forward 调用 /v2/send 接口发送
+func (s *SignalForwarder) Forward(msg ForwardMessage) error {
+	_, text, err := s.tmpl.Render(msg)
+	if err != nil {
+		return err
+	}
+	payload := map[string]any{
+		"message":    text,
+		"number":     s.Number,
+		"recipients": s.Recipients,
+	}
+	if _, err := postJSON(s.APIURL+"/v2/send", payload); err != nil {
+		return fmt.Errorf("请求Signal失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+)
+
+/* ---------- Slack 转发 ---------- */
+
+func init() { registerForwarder("slack", newSlackForwarder) }
+
+// SlackForwarder 通过 Incoming Webhook 推送到 Slack 频道
+type SlackForwarder struct {
+	WebhookURL string
+	tmpl       *MessageTemplate
+}
+
+// 从环境变量创建 Slack 转发，未配置 SLACK_WEBHOOK_URL 时返回 nil
+func newSlackForwarder() Forwarder {
+	url := getEnvWithDefault("SLACK_WEBHOOK_URL", "")
+	if url == "" {
+		return nil
+	}
+	return (&SlackForwarder{
+		WebhookURL: url,
+	}).setup()
+}
+
+func (s *SlackForwarder) setup() *SlackForwarder {
+	s.tmpl = loadMessageTemplate("slack", defaultTitleTemplate, ":envelope: 新验证码 *{{.Code}}*\n来源: {{.From}}\n时间: {{.Time}}")
+	return s
+}
+
+func (s *SlackForwarder) Name() string { return "Slack" }
+
+// Forward 渲染模板并发送到 Webhook
+func (s *SlackForwarder) Forward(msg ForwardMessage) error {
+	_, text, err := s.tmpl.Render(msg)
+	if err != nil {
+		return err
+	}
+	if _, err := postJSON(s.WebhookURL, map[string]string{"text": text}); err != nil {
+		return fmt.Errorf("请求Slack失败: %w", err)
+	}
+	return nil
+}
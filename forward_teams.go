@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+)
+
+/* ---------- Microsoft Teams 转发 ---------- */
+
+func init() { registerForwarder("teams", newTeamsForwarder) }
+
+// TeamsForwarder 通过 Incoming Webhook（或 Workflows webhook）发送 Adaptive Card
+type TeamsForwarder struct {
+	WebhookURL string
+	tmpl       *MessageTemplate
+}
+
+// 从环境变量创建 Teams 转发，未配置 TEAMS_WEBHOOK_URL 时返回 nil
+func newTeamsForwarder() Forwarder {
+	url := getEnvWithDefault("TEAMS_WEBHOOK_URL", "")
+	if url == "" {
+		return nil
+	}
+	return &TeamsForwarder{
+		WebhookURL: url,
+		// 正文模板默认为空，只展示字段；配置 TEAMS_TEMPLATE 后追加为文本块
+		tmpl: loadMessageTemplate("teams", "📩 新验证码", ""),
+	}
+}
+
+func (t *TeamsForwarder) Name() string { return "Teams" }
+
+// Forward 发送包含来源、验证码和时间的 Adaptive Card
+func (t *TeamsForwarder) Forward(msg ForwardMessage) error {
+	title, text, err := t.tmpl.Render(msg)
+	if err != nil {
+		return err
+	}
+
+	body := []map[string]any{
+		{"type": "TextBlock", "size": "Medium", "weight": "Bolder", "text": title},
+		{"type": "FactSet", "facts": []map[string]string{
+			{"title": "验证码", "value": msg.Code},
+			{"title": "来源", "value": msg.From},
+			{"title": "时间", "value": msg.Time()},
+		}},
+	}
+	if text != "" {
+		body = append(body, map[string]any{"type": "TextBlock", "text": text, "wrap": true})
+	}
+
+	payload := map[string]any{
+		"type": "message",
+		"attachments": []map[string]any{{
+			"contentType": "application/vnd.microsoft.card.adaptive",
+			"content": map[string]any{
+				"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+				"type":    "AdaptiveCard",
+				"version": "1.4",
+				"body":    body,
+			},
+		}},
+	}
+	if _, err := postJSON(t.WebhookURL, payload); err != nil {
+		return fmt.Errorf("请求Teams失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+)
+
+/* ---------- Telegram 转发 ---------- */
+
+func init() { registerForwarder("telegram", newTelegramForwarder) }
+
+// TelegramForwarder 通过机器人推送到 Telegram 会话
+type TelegramForwarder struct {
+	BotToken string
+	ChatID   string
+	tmpl     *MessageTemplate
+}
+
+// 从环境变量创建 Telegram 转发，未配置时返回 nil
+func newTelegramForwarder() Forwarder {
+	token := getEnvWithDefault("TELEGRAM_BOT_TOKEN", "")
+	chatID := getEnvWithDefault("TELEGRAM_CHAT_ID", "")
+	if token == "" || chatID == "" {
+		return nil
+	}
+	return (&TelegramForwarder{
+		BotToken: token,
+		ChatID:   chatID,
+	}).setup()
+}
+
+func (t *TelegramForwarder) setup() *TelegramForwarder {
+	t.tmpl = loadMessageTemplate("telegram", defaultTitleTemplate, "📩 新验证码\n来源: {{.From}}\n验证码: {{.Code}}\n时间: {{.Time}}")
+	return t
+}
+
+func (t *TelegramForwarder) Name() string { return "Telegram" }
+
+// Forward 调用 sendMessage 推送验证码
+func (t *TelegramForwarder) Forward(msg ForwardMessage) error {
+	_, text, err := t.tmpl.Render(msg)
+	if err != nil {
+		return err
+	}
+	url := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", t.BotToken)
+	if _, err := postJSON(url, map[string]string{"chat_id": t.ChatID, "text": text}); err != nil {
+		return fmt.Errorf("请求Telegram失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+/* ---------- Twilio 短信转发 ---------- */
+
+func init() { registerForwarder("twilio", newTwilioForwarder) }
+
+// TwilioForwarder 通过 Twilio（或兼容 API 的服务商）将短信重新发送到其他号码
+type TwilioForwarder struct {
+	APIBase             string
+	AccountSID          string
+	AuthToken           string
+	From                string // 发送号码，与 MessagingServiceSID 二选一
+	MessagingServiceSID string
+	To                  []string
+	tmpl                *MessageTemplate
+}
+
+// 从环境变量创建 Twilio 转发，缺少账号、发送方或接收号码时返回 nil
+func newTwilioForwarder() Forwarder {
+	sid := getEnvWithDefault("TWILIO_ACCOUNT_SID", "")
+	token := getEnvWithDefault("TWILIO_AUTH_TOKEN", "")
+	from := getEnvWithDefault("TWILIO_FROM", "")
+	service := getEnvWithDefault("TWILIO_MESSAGING_SERVICE_SID", "")
+	to := splitAndTrim(getEnvWithDefault("TWILIO_TO", ""))
+	if sid == "" || token == "" || (from == "" && service == "") || len(to) == 0 {
+		return nil
+	}
+	return &TwilioForwarder{
+		APIBase:             strings.TrimRight(getEnvWithDefault("TWILIO_API_BASE", "https://api.twilio.com"), "/"),
+		AccountSID:          sid,
+		AuthToken:           token,
+		From:                from,
+		MessagingServiceSID: service,
+		To:                  to,
+		tmpl:                loadMessageTemplate("twilio", defaultTitleTemplate, "[{{.From}}] 验证码 {{.Code}}"),
+	}
+}
+
+func (t *TwilioForwarder) Name() string { return "Twilio" }
+
+// 发送一条短信
+func (t *TwilioForwarder) send(to, body string) error {
+	form := url.Values{"To": {to}, "Body": {body}}
+	if t.MessagingServiceSID != "" {
+		form.Set("MessagingServiceSid", t.MessagingServiceSID)
+	} else {
+		form.Set("From", t.From)
+	}
+
+	endpoint := fmt.Sprintf("%s/2010-04-01/Accounts/%s/Messages.json", t.APIBase, url.PathEscape(t.AccountSID))
+	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(t.AccountSID, t.AuthToken)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := forwardHTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("异常状态码 %d: %s", resp.StatusCode, string(respBody))
+	}
+	return nil
+}
+
+// Forward 发送到所有接收号码；来源就是接收号码之一时跳过，避免转发回环
+func (t *TwilioForwarder) Forward(msg ForwardMessage) error {
+	_, body, err := t.tmpl.Render(msg)
+	if err != nil {
+		return err
+	}
+
+	var lastErr error
+	for _, to := range t.To {
+		if to == msg.From {
+			continue
+		}
+		if err := t.send(to, body); err != nil {
+			lastErr = fmt.Errorf("发送到 %s 失败: %w", to, err)
+		}
+	}
+	return lastErr
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/* ---------- 通用 Webhook 转发 ---------- */
+
+// 每个地址一个实例，只有一个地址时标识为 webhook，多个时为 webhook-1、webhook-2…，
+// 各地址的投递状态和重试互不影响，重试不会向已成功的地址重复投递
+func init() { registerForwarderSet("webhook", newWebhookForwarders) }
+
+// WebhookForwarder 将短信 JSON POST 到自定义地址，失败时按退避重试；
+// 启用转发重试队列时只投递一次，失败后由重试队列负责退避
+type WebhookForwarder struct {
+	URLs       []string
+	Headers    map[string]string
+	MaxRetries int
+	Backoff    time.Duration
+	client     *http.Client
+}
+
+// 可重试的错误（5xx 或连接失败）
+var errWebhookRetryable = errors.New("可重试错误")
+
+// 从环境变量创建 Webhook 转发，未配置 WEBHOOK_URLS 时不创建
+func newWebhookForwarders() map[string]Forwarder {
+	urls := splitAndTrim(getEnvWithDefault("WEBHOOK_URLS", ""))
+	headers := parseHeaders(getEnvWithDefault("WEBHOOK_HEADERS", ""))
+	retries, _ := strconv.Atoi(getEnvWithDefault("WEBHOOK_MAX_RETRIES", "3"))
+	backoff := getEnvDuration("WEBHOOK_RETRY_BACKOFF", time.Second)
+	client := &http.Client{Timeout: getEnvDuration("WEBHOOK_TIMEOUT", 5*time.Second)}
+
+	instances := make(map[string]Forwarder)
+	for i, url := range urls {
+		name := "webhook"
+		if len(urls) > 1 {
+			name = fmt.Sprintf("webhook-%d", i+1)
+		}
+		instances[name] = &WebhookForwarder{URLs: []string{url}, Headers: headers, MaxRetries: retries, Backoff: backoff, client: client}
+	}
+	return instances
+}
+
+// 解析 "Key: Value;Key2: Value2" 形式的请求头配置
+func parseHeaders(s string) map[string]string {
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(s, ";") {
+		k, v, ok := strings.Cut(pair, ":")
+		if !ok || strings.TrimSpace(k) == "" {
+			continue
+		}
+		headers[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return headers
+}
+
+func (w *WebhookForwarder) Name() string { return "Webhook" }
+
+// Forward 依次投递到所有地址（环境变量配置的实例只有一个地址），返回最后一个失败地址的错误
+func (w *WebhookForwarder) Forward(msg ForwardMessage) error {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("序列化消息失败: %w", err)
+	}
+
+	// 重试队列会在 FORWARD_RETRY_BACKOFF 后重新投递，这里再重试会成倍增加请求次数，并在退避期间占用 worker
+	retries := w.MaxRetries
+	if retryCfg.Enabled {
+		retries = 0
+	}
+	var lastErr error
+	for _, url := range w.URLs {
+		if err := w.sendWithRetry(url, payload, retries); err != nil {
+			log.Printf("Webhook投递失败 (%s): %v", url, err)
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+// 对单个地址投递，5xx / 连接失败时指数退避重试，最多重试 retries 次
+func (w *WebhookForwarder) sendWithRetry(url string, payload []byte, retries int) error {
+	backoff := w.Backoff
+	var err error
+	for attempt := 0; attempt <= retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+		if err = w.send(url, payload); err == nil || !errors.Is(err, errWebhookRetryable) {
+			return err
+		}
+	}
+	if retries == 0 {
+		return err
+	}
+	return fmt.Errorf("重试 %d 次后仍失败: %w", retries, err)
+}
+
+func (w *WebhookForwarder) send(url string, payload []byte) error {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range w.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errWebhookRetryable, err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+
+	switch {
+	case resp.StatusCode >= 500:
+		return fmt.Errorf("%w: 状态码 %d", errWebhookRetryable, resp.StatusCode)
+	case resp.StatusCode >= 300:
+		return fmt.Errorf("异常状态码 %d: %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
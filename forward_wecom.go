@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+/* ---------- 企业微信应用消息转发 ---------- */
+
+func init() { registerForwarder("wecom", newWeComForwarder) }
+
+// WeComForwarder 通过企业微信自建应用发送消息
+type WeComForwarder struct {
+	APIBase string
+	CorpID  string
+	AgentID int
+	Secret  string
+	ToUser  string
+	tmpl    *MessageTemplate
+
+	mu          sync.Mutex
+	accessToken string
+	expiresAt   time.Time
+}
+
+// 企业微信接口的通用返回
+type wecomResult struct {
+	ErrCode     int    `json:"errcode"`
+	ErrMsg      string `json:"errmsg"`
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// 从环境变量创建企业微信转发，缺少 corp ID / agent ID / secret 时返回 nil
+func newWeComForwarder() Forwarder {
+	corpID := getEnvWithDefault("WECOM_CORP_ID", "")
+	secret := getEnvWithDefault("WECOM_SECRET", "")
+	agentID, _ := strconv.Atoi(getEnvWithDefault("WECOM_AGENT_ID", "0"))
+	if corpID == "" || secret == "" || agentID == 0 {
+		return nil
+	}
+	return &WeComForwarder{
+		APIBase: getEnvWithDefault("WECOM_API_BASE", "https://qyapi.weixin.qq.com"),
+		CorpID:  corpID,
+		AgentID: agentID,
+		Secret:  secret,
+		ToUser:  getEnvWithDefault("WECOM_TO_USER", "@all"),
+		tmpl:    loadMessageTemplate("wecom", defaultTitleTemplate, defaultBodyTemplate),
+	}
+}
+
+func (w *WeComForwarder) Name() string { return "企业微信" }
+
+// 获取 access_token，未过期时直接使用缓存（提前 5 分钟刷新）
+func (w *WeComForwarder) token() (string, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.accessToken != "" && time.Now().Before(w.expiresAt) {
+		return w.accessToken, nil
+	}
+
+	u := fmt.Sprintf("%s/cgi-bin/gettoken?corpid=%s&corpsecret=%s",
+		w.APIBase, url.QueryEscape(w.CorpID), url.QueryEscape(w.Secret))
+	resp, err := forwardHTTPClient.Get(u)
+	if err != nil {
+		return "", fmt.Errorf("获取access_token失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result wecomResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("解析access_token失败: %w", err)
+	}
+	if result.ErrCode != 0 {
+		return "", fmt.Errorf("获取access_token失败 %d: %s", result.ErrCode, result.ErrMsg)
+	}
+
+	w.accessToken = result.AccessToken
+	w.expiresAt = time.Now().Add(time.Duration(result.ExpiresIn)*time.Second - 5*time.Minute)
+	return w.accessToken, nil
+}
+
+// 清除缓存的 access_token，下次发送时重新获取
+func (w *WeComForwarder) invalidateToken() {
+	w.mu.Lock()
+	w.accessToken = ""
+	w.mu.Unlock()
+}
+
+// Forward 发送文本消息，token 失效时刷新后重试一次
+func (w *WeComForwarder) Forward(msg ForwardMessage) error {
+	_, text, err := w.tmpl.Render(msg)
+	if err != nil {
+		return err
+	}
+	payload := map[string]any{
+		"touser":  w.ToUser,
+		"msgtype": "text",
+		"agentid": w.AgentID,
+		"text":    map[string]string{"content": text},
+	}
+
+	for attempt := 0; attempt < 2; attempt++ {
+		token, err := w.token()
+		if err != nil {
+			return err
+		}
+
+		body, err := postJSON(w.APIBase+"/cgi-bin/message/send?access_token="+url.QueryEscape(token), payload)
+		if err != nil {
+			return fmt.Errorf("请求企业微信失败: %w", err)
+		}
+
+		var result wecomResult
+		if err := json.Unmarshal(body, &result); err != nil {
+			return fmt.Errorf("解析企业微信响应失败: %w", err)
+		}
+		switch result.ErrCode {
+		case 0:
+			return nil
+		case 40014, 42001: // access_token 无效或已过期
+			w.invalidateToken()
+			continue
+		default:
+			return fmt.Errorf("企业微信返回错误 %d: %s", result.ErrCode, result.ErrMsg)
+		}
+	}
+	return fmt.Errorf("企业微信access_token刷新后仍然无效")
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+/* ---------- WhatsApp 转发（Business Cloud API） ---------- */
+
+func init() { registerForwarder("whatsapp", newWhatsAppForwarder) }
+
+// WhatsAppForwarder 通过 WhatsApp Business Cloud API 发送消息。
+// 配置了模板名时发送已审核的模板消息（主动发送必须使用模板），否则发送普通文本（仅 24 小时会话窗口内有效）
+type WhatsAppForwarder struct {
+	APIBase       string
+	PhoneNumberID string
+	AccessToken   string
+	To            []string
+	TemplateName  string
+	TemplateLang  string
+	CopyCodeBtn   bool                 // 身份验证类模板的「复制验证码」按钮需要同样传入验证码
+	params        []*template.Template // 模板正文变量 {{1}}、{{2}}… 的取值
+	tmpl          *MessageTemplate
+}
+
+// 从环境变量创建 WhatsApp 转发，缺少号码 ID / 令牌 / 接收号码时返回 nil
+func newWhatsAppForwarder() Forwarder {
+	phoneID := getEnvWithDefault("WHATSAPP_PHONE_NUMBER_ID", "")
+	token := getEnvWithDefault("WHATSAPP_ACCESS_TOKEN", "")
+	to := splitAndTrim(getEnvWithDefault("WHATSAPP_TO", ""))
+	if phoneID == "" || token == "" || len(to) == 0 {
+		return nil
+	}
+
+	w := &WhatsAppForwarder{
+		APIBase:       strings.TrimRight(getEnvWithDefault("WHATSAPP_API_BASE", "https://graph.facebook.com/v19.0"), "/"),
+		PhoneNumberID: phoneID,
+		AccessToken:   token,
+		To:            to,
+		TemplateName:  getEnvWithDefault("WHATSAPP_TEMPLATE_NAME", ""),
+		TemplateLang:  getEnvWithDefault("WHATSAPP_TEMPLATE_LANG", "zh_CN"),
+		CopyCodeBtn:   getEnvWithDefault("WHATSAPP_TEMPLATE_BUTTON", "false") == "true",
+		tmpl:          loadMessageTemplate("whatsapp", defaultTitleTemplate, defaultBodyTemplate),
+	}
+	for i, p := range splitAndTrim(getEnvWithDefault("WHATSAPP_TEMPLATE_PARAMS", "{{.Code}}")) {
+		w.params = append(w.params, mustParseTemplate(fmt.Sprintf("whatsapp.param%d", i+1), p))
+	}
+	return w
+}
+
+func (w *WhatsAppForwarder) Name() string { return "WhatsApp" }
+
+// 构造模板消息的 components
+func (w *WhatsAppForwarder) templateComponents(msg ForwardMessage) ([]map[string]any, error) {
+	var bodyParams []map[string]any
+	for _, p := range w.params {
+		var buf bytes.Buffer
+		if err := p.Execute(&buf, msg); err != nil {
+			return nil, fmt.Errorf("渲染模板参数失败: %w", err)
+		}
+		bodyParams = append(bodyParams, map[string]any{"type": "text", "text": buf.String()})
+	}
+
+	var components []map[string]any
+	if len(bodyParams) > 0 {
+		components = append(components, map[string]any{"type": "body", "parameters": bodyParams})
+	}
+	if w.CopyCodeBtn {
+		components = append(components, map[string]any{
+			"type":       "button",
+			"sub_type":   "url",
+			"index":      "0",
+			"parameters": []map[string]any{{"type": "text", "text": msg.Code}},
+		})
+	}
+	return components, nil
+}
+
+// Forward 逐个接收号码调用 /{phone_number_id}/messages 接口
+func (w *WhatsAppForwarder) Forward(msg ForwardMessage) error {
+	payload := map[string]any{"messaging_product": "whatsapp"}
+	if w.TemplateName != "" {
+		components, err := w.templateComponents(msg)
+		if err != nil {
+			return err
+		}
+		payload["type"] = "template"
+		payload["template"] = map[string]any{
+			"name":       w.TemplateName,
+			"language":   map[string]string{"code": w.TemplateLang},
+			"components": components,
+		}
+	} else {
+		_, text, err := w.tmpl.Render(msg)
+		if err != nil {
+			return err
+		}
+		payload["type"] = "text"
+		payload["text"] = map[string]string{"body": text}
+	}
+
+	url := fmt.Sprintf("%s/%s/messages", w.APIBase, w.PhoneNumberID)
+	headers := map[string]string{"Authorization": "Bearer " + w.AccessToken}
+	var lastErr error
+	for _, to := range w.To {
+		payload["to"] = strings.TrimPrefix(to, "+")
+		if _, err := postJSONWithHeaders(url, payload, headers); err != nil {
+			lastErr = fmt.Errorf("发送到 %s 失败: %w", to, err)
+		}
+	}
+	return lastErr
+}
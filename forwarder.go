@@ -0,0 +1,302 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/* ---------- 转发通道抽象 ---------- */
+
+// ForwardMessage 投递给各转发通道的消息
+type ForwardMessage struct {
+	From       string `json:"from"`                  // 来源号码
+	Code       string `json:"code"`                  // 提取出的验证码
+	RawContent string `json:"raw_content,omitempty"` // 原始短信内容，通道配置为只转发验证码时为空
+	ReceivedAt int64  `json:"received_at"`           // 接收时间（毫秒时间戳）
+	CacheKey   string `json:"cache_key"`             // Redis 中的历史 key
+
+	content string // 原始短信内容，不随通道配置裁剪，只供通道内部判断（如按关键词确定优先级），不输出到模板
+}
+
+// Time 返回格式化后的接收时间
+func (m ForwardMessage) Time() string {
+	return time.UnixMilli(m.ReceivedAt).Format("2006-01-02 15:04:05")
+}
+
+// Forwarder 转发通道接口，每个通道（Telegram、Slack…）实现一次
+type Forwarder interface {
+	Name() string
+	Forward(msg ForwardMessage) error
+}
+
+// CodelessForwarder 可选接口：实现并返回 true 的通道在短信中没有验证码时也会收到消息（如安全告警）
+type CodelessForwarder interface {
+	AcceptsCodeless() bool
+}
+
+// 判断通道是否接收不含验证码的短信
+func acceptsCodeless(f Forwarder) bool {
+	c, ok := f.(CodelessForwarder)
+	return ok && c.AcceptsCodeless()
+}
+
+var (
+	forwarders []Forwarder
+
+	// 已启用通道，按通道标识索引，供路由规则查找
+	forwardersByName = make(map[string]Forwarder)
+
+	// 通道集合（registerForwarderSet 注册）的标识 → 已启用实例的标识，路由规则可用集合标识指代全部实例
+	forwarderSets = make(map[string][]string)
+
+	// 各通道是否接收原始短信内容，false 表示只转发验证码
+	forwardIncludeRaw = make(map[string]bool)
+
+	// 转发通道共用的 HTTP 客户端
+	forwardHTTPClient = &http.Client{Timeout: 10 * time.Second}
+)
+
+// ForwarderFactory 从环境变量创建转发通道，未配置时返回 nil
+type ForwarderFactory func() Forwarder
+
+// ForwarderSetFactory 从一份配置创建多个通道实例（如 Apprise URL 列表），返回 实例标识 → 通道
+type ForwarderSetFactory func() map[string]Forwarder
+
+// 已注册的转发通道，按注册顺序保存；factory 和 set 二选一
+type registeredForwarder struct {
+	name    string
+	factory ForwarderFactory
+	set     ForwarderSetFactory
+}
+
+var forwarderRegistry []registeredForwarder
+
+// registerForwarder 注册转发通道，由各通道文件的 init 调用；
+// name 为通道标识，与 FORWARDERS 配置及模板通道名一致
+func registerForwarder(name string, factory ForwarderFactory) {
+	addRegisteredForwarder(registeredForwarder{name: name, factory: factory})
+}
+
+// registerForwarderSet 注册可生成多个实例的通道；FORWARDERS 中的 name 会启用其全部实例
+func registerForwarderSet(name string, factory ForwarderSetFactory) {
+	addRegisteredForwarder(registeredForwarder{name: name, set: factory})
+}
+
+func addRegisteredForwarder(r registeredForwarder) {
+	if forwarderRegistered(r.name) {
+		log.Fatalf("转发通道 %s 重复注册", r.name)
+	}
+	forwarderRegistry = append(forwarderRegistry, r)
+}
+
+// 通道标识对应的环境变量前缀，如 apprise-1 → APPRISE_1
+func forwarderEnvPrefix(name string) string {
+	return strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// 根据配置初始化转发通道：
+// FORWARDERS 为空时启用所有已配置的通道，否则只启用列出的通道
+func initForwarders() {
+	enabled := make(map[string]bool)
+	for _, name := range splitAndTrim(getEnvWithDefault("FORWARDERS", "")) {
+		enabled[strings.ToLower(name)] = true
+	}
+	for name := range enabled {
+		if !forwarderRegistered(name) {
+			log.Fatalf("FORWARDERS 中包含未知的转发通道: %s", name)
+		}
+	}
+	defaultRaw := getEnvWithDefault("FORWARD_INCLUDE_RAW", "true")
+
+	for _, r := range forwarderRegistry {
+		if len(enabled) > 0 && !enabled[r.name] {
+			continue
+		}
+		includeRaw := getEnvWithDefault(forwarderEnvPrefix(r.name)+"_INCLUDE_RAW", defaultRaw) == "true"
+
+		instances := make(map[string]Forwarder)
+		if r.set != nil {
+			instances = r.set()
+		} else if f := r.factory(); f != nil {
+			instances[r.name] = f
+		}
+		if len(instances) == 0 {
+			if enabled[r.name] {
+				log.Printf("转发通道 %s 已在 FORWARDERS 中启用，但缺少必要配置，已跳过", r.name)
+			}
+			continue
+		}
+
+		names := make([]string, 0, len(instances))
+		for name := range instances {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if r.set != nil {
+			forwarderSets[r.name] = names
+		}
+		for _, name := range names {
+			f := instances[name]
+			forwarders = append(forwarders, f)
+			forwardersByName[name] = f
+			forwardIncludeRaw[name] = includeRaw
+			log.Printf("转发通道已启用: %s [%s] (原始内容: %t)", f.Name(), name, includeRaw)
+		}
+	}
+	if len(forwarders) == 0 {
+		log.Printf("未配置任何转发通道")
+	}
+}
+
+// 判断通道标识是否已注册
+func forwarderRegistered(name string) bool {
+	for _, r := range forwarderRegistry {
+		if r.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// 通道标识对应的已启用实例标识：通道集合的标识（如 apprise、webhook）展开为其全部实例
+func expandForwarderName(name string) []string {
+	if forwardersByName[name] != nil {
+		return []string{name}
+	}
+	return forwarderSets[name]
+}
+
+// 按通道配置裁剪消息：不接收原始内容的通道只拿到验证码
+func messageForChannel(f Forwarder, msg ForwardMessage) ForwardMessage {
+	if msg.content == "" {
+		msg.content = msg.RawContent
+	}
+	if !forwardIncludeRaw[forwarderID(f)] {
+		msg.RawContent = ""
+	}
+	return msg
+}
+
+// forwardTask 投递给单个通道的一次任务
+type forwardTask struct {
+	forwarder Forwarder
+	msg       ForwardMessage
+}
+
+// 转发任务队列，由固定数量的 worker 并发消费
+var forwardQueue chan forwardTask
+
+// 启动转发 worker 池，需在 initForwarders 之后调用
+func startForwardWorkers() {
+	if len(forwarders) == 0 {
+		return
+	}
+	workers, _ := strconv.Atoi(getEnvWithDefault("FORWARD_WORKERS", "4"))
+	queueSize, _ := strconv.Atoi(getEnvWithDefault("FORWARD_QUEUE_SIZE", "1000"))
+	if workers <= 0 {
+		workers = 1
+	}
+
+	forwardQueue = make(chan forwardTask, queueSize)
+	for i := 0; i < workers; i++ {
+		go func() {
+			for task := range forwardQueue {
+				runForwardTask(task)
+			}
+		}()
+	}
+	log.Printf("转发 worker 池已启动 (worker: %d, 队列长度: %d)", workers, queueSize)
+}
+
+// 执行一次投递，失败时写入重试队列
+func runForwardTask(task forwardTask) {
+	f := task.forwarder
+	err := f.Forward(task.msg)
+	markDeliveryResult(task.msg.CacheKey, forwarderID(f), err)
+	if err != nil {
+		log.Printf("%s转发失败: %v", f.Name(), err)
+		enqueueRetry(context.Background(), newRetryJob(forwarderID(f), task.msg), err)
+	}
+}
+
+// 限流通道的任务进入该通道的排队队列，其余进入公共 worker 池；队列已满时返回 false
+func enqueueForwardTask(task forwardTask) bool {
+	if cl := channelLimiters[forwarderID(task.forwarder)]; cl != nil {
+		return cl.enqueue(task)
+	}
+	select {
+	case forwardQueue <- task:
+		return true
+	default:
+		return false
+	}
+}
+
+// dispatchForward 将消息按通道拆分为任务放入 worker 池，各通道并发投递，
+// 慢通道不会阻塞其他通道，也不会阻塞 HTTP 响应。
+// 目标通道由路由规则选出（未配置规则时为全部通道）；
+// 没有验证码的消息只投递给 CodelessForwarder，除非由路由规则显式指定；运行时停用的通道跳过
+func dispatchForward(msg ForwardMessage) {
+	if len(forwarders) == 0 {
+		return
+	}
+	targets, explicit := routeMessage(msg)
+	for _, f := range targets {
+		if msg.Code == "" && !explicit && !acceptsCodeless(f) {
+			continue
+		}
+		if forwarderDisabled(forwarderID(f)) { // 已通过管理接口停用
+			continue
+		}
+		task := forwardTask{forwarder: f, msg: messageForChannel(f, msg)}
+		markDeliveryPending(msg.CacheKey, forwarderID(f))
+		if !enqueueForwardTask(task) { // 队列已满，交给重试队列稍后投递
+			log.Printf("转发队列已满，%s 任务转入重试队列", f.Name())
+			enqueueRetry(context.Background(), newRetryJob(forwarderID(f), task.msg),
+				fmt.Errorf("转发队列已满"))
+		}
+	}
+}
+
+// postJSON 以 JSON 发送 POST 请求，非 2xx 状态码视为失败，返回响应体
+func postJSON(url string, payload any) ([]byte, error) {
+	return postJSONWithHeaders(url, payload, nil)
+}
+
+// postJSONWithHeaders 同 postJSON，附带额外请求头（如认证信息）
+func postJSONWithHeaders(url string, payload any, headers map[string]string) ([]byte, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("序列化请求失败: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := forwardHTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return body, fmt.Errorf("异常状态码 %d: %s", resp.StatusCode, string(body))
+	}
+	return body, nil
+}
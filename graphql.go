@@ -0,0 +1,550 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+/* ---------- GraphQL 查询接口 ---------- */
+
+// 前端按需选择字段，一次请求取回多个号码的最新验证码、历史和搜索结果；只读，数据与 REST 接口相同
+const graphqlSchema = `# 毫秒时间戳；作为参数时也可传 RFC3339 或 2006-01-02 格式的字符串
+scalar Timestamp
+
+type Query {
+  # 最新验证码，等同于 GET /api/v1/latest_sms/:phone
+  latestSMS(phone: String!, since: Timestamp): SMS
+  # 号码的历史记录，按接收时间倒序，等同于 GET /api/v1/sms/:phone/history
+  history(phone: String!, limit: Int = 20, before: Timestamp): [SMS!]!
+  # 搜索历史记录，需要 SQL 历史存储，等同于 GET /api/v1/search
+  search(from: String, q: String, since: Timestamp, until: Timestamp, limit: Int = 50): [SMS!]!
+}
+
+type SMS {
+  sender: String!
+  code: String!
+  rawContent: String!
+  receivedAt: Timestamp!
+  cacheKey: String!
+  # 上报短信的设备 ID，未使用设备令牌时为 null
+  device: String
+  # 验证码的过期时间与剩余有效期（秒），已过期时为 null
+  expiresAt: Timestamp
+  ttlRemaining: Int
+}
+`
+
+// 单次请求最多的顶层字段数，避免一次查询展开过多存储读取
+const graphqlMaxRootFields = 20
+
+// gqlRequest GraphQL over HTTP 的请求
+type gqlRequest struct {
+	Query         string         `json:"query"`
+	Variables     map[string]any `json:"variables"`
+	OperationName string         `json:"operationName"`
+}
+
+type gqlError struct {
+	Message string `json:"message"`
+	Path    []any  `json:"path,omitempty"`
+}
+
+// gqlObject 按查询中的字段顺序输出的 JSON 对象
+type gqlObject []gqlEntry
+
+type gqlEntry struct {
+	Key   string
+	Value any
+}
+
+func (o gqlObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, e := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, _ := json.Marshal(e.Key)
+		buf.Write(k)
+		buf.WriteByte(':')
+		v, err := json.Marshal(e.Value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// 字段参数，已代入变量
+type gqlArgs map[string]any
+
+// 顶层字段的解析函数，返回 *SMSView 或 []SMSView
+type gqlResolver func(ctx context.Context, args gqlArgs) (any, error)
+
+type gqlFieldDef struct {
+	args    map[string]bool // 参数名 → 是否必填
+	resolve gqlResolver
+}
+
+var gqlQueryFields = map[string]gqlFieldDef{
+	"latestSMS": {args: map[string]bool{"phone": true, "since": false}, resolve: resolveLatestSMS},
+	"history":   {args: map[string]bool{"phone": true, "limit": false, "before": false}, resolve: resolveHistory},
+	"search":    {args: map[string]bool{"from": false, "q": false, "since": false, "until": false, "limit": false}, resolve: resolveSearch},
+}
+
+var gqlSMSFields = map[string]func(v SMSView) any{
+	"sender":     func(v SMSView) any { return v.Sender },
+	"code":       func(v SMSView) any { return v.Code },
+	"rawContent": func(v SMSView) any { return v.RawContent },
+	"receivedAt": func(v SMSView) any { return v.ReceivedAt },
+	"cacheKey":   func(v SMSView) any { return v.CacheKey },
+	"device": func(v SMSView) any {
+		if v.Device == "" {
+			return nil
+		}
+		return v.Device
+	},
+	"expiresAt": func(v SMSView) any {
+		if v.ExpiresAt == 0 {
+			return nil
+		}
+		return v.ExpiresAt
+	},
+	"ttlRemaining": func(v SMSView) any {
+		if v.TTLRemaining == 0 {
+			return nil
+		}
+		return v.TTLRemaining
+	},
+}
+
+func resolveLatestSMS(ctx context.Context, args gqlArgs) (any, error) {
+	phone, _ := args["phone"].(string)
+	phone = resolvePhoneAlias(ctx, phone)
+	since, err := args.timestamp("since")
+	if err != nil {
+		return nil, err
+	}
+	rec, err := store.GetLatest(ctx, phoneKey(phone))
+	if err != nil || rec == nil || rec.ReceivedAt < since {
+		return nil, err
+	}
+	v := recordView(phone, *rec)
+	return &v, nil
+}
+
+func resolveHistory(ctx context.Context, args gqlArgs) (any, error) {
+	phone, _ := args["phone"].(string)
+	phone = resolvePhoneAlias(ctx, phone)
+	limit, err := args.limit(20, 100)
+	if err != nil {
+		return nil, err
+	}
+	before, err := args.timestamp("before")
+	if err != nil {
+		return nil, err
+	}
+	records, err := store.GetHistory(ctx, phoneKey(phone), limit, before)
+	if err != nil {
+		return nil, err
+	}
+	views := make([]SMSView, 0, len(records))
+	for _, rec := range records {
+		views = append(views, recordView(phone, rec))
+	}
+	return views, nil
+}
+
+func resolveSearch(ctx context.Context, args gqlArgs) (any, error) {
+	s := sqlHistoryStore()
+	if s == nil {
+		return nil, errors.New("搜索需要启用 SQL 历史存储 (STORAGE_BACKEND=sqlite/postgres/mysql)")
+	}
+	limit, err := args.limit(50, 500)
+	if err != nil {
+		return nil, err
+	}
+	filter := SearchFilter{}
+	filter.Query, _ = args["q"].(string)
+	if filter.Since, err = args.timestamp("since"); err != nil {
+		return nil, err
+	}
+	if filter.Until, err = args.timestamp("until"); err != nil {
+		return nil, err
+	}
+	if from, _ := args["from"].(string); from != "" {
+		filter.From, filter.Exact = phoneKey(from), phoneHashKey != nil
+	}
+	records, err := s.search(ctx, filter, limit)
+	if err != nil {
+		return nil, err
+	}
+	views := make([]SMSView, 0, len(records))
+	for _, rec := range records {
+		views = append(views, recordView(rec.From, rec))
+	}
+	return views, nil
+}
+
+// 读取时间参数：整数为毫秒时间戳，字符串按 parseTimeParam 解析，未传为 0
+func (a gqlArgs) timestamp(name string) (int64, error) {
+	switch v := a[name].(type) {
+	case nil:
+		return 0, nil
+	case string:
+		return parseTimeParam(v)
+	default:
+		if n, ok := gqlInteger(v); ok {
+			return n, nil
+		}
+	}
+	return 0, fmt.Errorf("参数 %s 应为时间戳", name)
+}
+
+// 读取 limit 参数，未传时使用默认值，超过上限时按上限处理
+func (a gqlArgs) limit(def, max int) (int, error) {
+	v, ok := a["limit"]
+	if !ok || v == nil {
+		return def, nil
+	}
+	n, ok := gqlInteger(v)
+	if !ok || n <= 0 {
+		return 0, errors.New("参数 limit 应为正整数")
+	}
+	return int(min(n, int64(max))), nil
+}
+
+// 查询中的整数为 int64，变量中的数字由 JSON 解码为 float64
+func gqlInteger(v any) (int64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return n, true
+	case float64:
+		if n == math.Trunc(n) && math.Abs(n) < 1<<53 {
+			return int64(n), true
+		}
+	case json.Number:
+		i, err := n.Int64()
+		return i, err == nil
+	}
+	return 0, false
+}
+
+// gqlExecutor 执行一次查询操作
+type gqlExecutor struct {
+	ctx       context.Context
+	doc       *gqlDocument
+	variables map[string]any
+	errors    []gqlError
+}
+
+// 选择要执行的操作并代入变量，请求错误（无法执行）时返回 error
+func prepareGraphQL(ctx context.Context, req gqlRequest) (*gqlExecutor, *gqlOperation, error) {
+	doc, err := parseGraphQL(req.Query)
+	if err != nil {
+		return nil, nil, err
+	}
+	var op *gqlOperation
+	for i := range doc.Operations {
+		if req.OperationName == "" || doc.Operations[i].Name == req.OperationName {
+			if op != nil {
+				return nil, nil, errors.New("查询包含多个操作，需要指定 operationName")
+			}
+			op = &doc.Operations[i]
+		}
+	}
+	if op == nil {
+		return nil, nil, fmt.Errorf("未找到操作 %s", req.OperationName)
+	}
+	if op.Type != "query" {
+		return nil, nil, fmt.Errorf("只支持 query 操作，不支持 %s", op.Type)
+	}
+
+	e := &gqlExecutor{ctx: ctx, doc: doc, variables: make(map[string]any)}
+	for _, def := range op.Variables {
+		v, ok := req.Variables[def.Name]
+		if !ok {
+			v = def.Default
+		}
+		if v == nil && strings.HasSuffix(def.Type, "!") {
+			return nil, nil, fmt.Errorf("变量 $%s 不能为空", def.Name)
+		}
+		e.variables[def.Name] = v
+	}
+	return e, op, nil
+}
+
+// 代入变量后的参数值
+func (e *gqlExecutor) value(v any) (any, error) {
+	switch x := v.(type) {
+	case gqlVariable:
+		val, ok := e.variables[string(x)]
+		if !ok {
+			return nil, fmt.Errorf("未声明的变量 $%s", string(x))
+		}
+		return val, nil
+	case []any:
+		list := make([]any, len(x))
+		for i, item := range x {
+			val, err := e.value(item)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = val
+		}
+		return list, nil
+	case map[string]any:
+		obj := make(map[string]any, len(x))
+		for k, item := range x {
+			val, err := e.value(item)
+			if err != nil {
+				return nil, err
+			}
+			obj[k] = val
+		}
+		return obj, nil
+	}
+	return v, nil
+}
+
+// 按 @skip / @include 判断是否保留
+func (e *gqlExecutor) included(dirs []gqlDirective) (bool, error) {
+	for _, d := range dirs {
+		if d.Name != "skip" && d.Name != "include" {
+			return false, fmt.Errorf("不支持的指令 @%s", d.Name)
+		}
+		var cond any
+		for _, a := range d.Args {
+			if a.Name == "if" {
+				cond, _ = e.value(a.Value)
+			}
+		}
+		b, ok := cond.(bool)
+		if !ok {
+			return false, fmt.Errorf("@%s 需要 Boolean 类型的 if 参数", d.Name)
+		}
+		if (d.Name == "skip") == b {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// 展开片段并合并同名字段，返回按出现顺序排列的字段
+func (e *gqlExecutor) collectFields(sels []gqlSelection, visiting map[string]bool) ([]gqlSelection, error) {
+	var fields []gqlSelection
+	index := make(map[string]int)
+	add := func(sel gqlSelection) {
+		if i, ok := index[sel.key()]; ok {
+			fields[i].Selections = append(fields[i].Selections, sel.Selections...)
+			return
+		}
+		index[sel.key()] = len(fields)
+		fields = append(fields, sel)
+	}
+
+	for _, sel := range sels {
+		ok, err := e.included(sel.Directives)
+		if err != nil {
+			return nil, err
+		} else if !ok {
+			continue
+		}
+		var nested []gqlSelection
+		switch {
+		case sel.Spread != "":
+			frag, found := e.doc.Fragments[sel.Spread]
+			if !found {
+				return nil, fmt.Errorf("未定义的片段 %s", sel.Spread)
+			}
+			if visiting[sel.Spread] {
+				return nil, fmt.Errorf("片段 %s 存在循环引用", sel.Spread)
+			}
+			visiting[sel.Spread] = true
+			nested, err = e.collectFields(frag, visiting)
+			delete(visiting, sel.Spread)
+		case sel.Inline != nil:
+			nested, err = e.collectFields(sel.Inline, visiting)
+		default:
+			add(sel)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range nested {
+			add(f)
+		}
+	}
+	return fields, nil
+}
+
+// 执行查询，返回 data；字段级错误记录在 e.errors 中，对应字段为 null
+func (e *gqlExecutor) execute(op *gqlOperation) (gqlObject, error) {
+	fields, err := e.collectFields(op.Selections, map[string]bool{})
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) > graphqlMaxRootFields {
+		return nil, fmt.Errorf("单次查询最多 %d 个顶层字段", graphqlMaxRootFields)
+	}
+
+	// 先校验全部字段与参数，再读取存储
+	type rootField struct {
+		sel  gqlSelection
+		def  gqlFieldDef
+		args gqlArgs
+		sub  []gqlSelection
+	}
+	roots := make([]rootField, 0, len(fields))
+	for _, sel := range fields {
+		if sel.Name == "__typename" {
+			roots = append(roots, rootField{sel: sel})
+			continue
+		}
+		def, ok := gqlQueryFields[sel.Name]
+		if !ok {
+			return nil, fmt.Errorf("Query 类型没有字段 %s", sel.Name)
+		}
+		args := gqlArgs{}
+		for _, a := range sel.Args {
+			if _, known := def.args[a.Name]; !known {
+				return nil, fmt.Errorf("字段 %s 没有参数 %s", sel.Name, a.Name)
+			}
+			if args[a.Name], err = e.value(a.Value); err != nil {
+				return nil, err
+			}
+		}
+		for name, required := range def.args {
+			if s, _ := args[name].(string); required && s == "" {
+				return nil, fmt.Errorf("字段 %s 缺少必填参数 %s", sel.Name, name)
+			}
+		}
+		if len(sel.Selections) == 0 {
+			return nil, fmt.Errorf("字段 %s 需要选择子字段", sel.Name)
+		}
+		sub, err := e.collectFields(sel.Selections, map[string]bool{})
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range sub {
+			if _, ok := gqlSMSFields[f.Name]; !ok && f.Name != "__typename" {
+				return nil, fmt.Errorf("SMS 类型没有字段 %s", f.Name)
+			}
+			if len(f.Selections) > 0 || len(f.Args) > 0 {
+				return nil, fmt.Errorf("SMS.%s 为标量字段，不能带参数或子字段", f.Name)
+			}
+		}
+		roots = append(roots, rootField{sel: sel, def: def, args: args, sub: sub})
+	}
+
+	data := make(gqlObject, 0, len(roots))
+	for _, root := range roots {
+		key := root.sel.key()
+		if root.sel.Name == "__typename" {
+			data = append(data, gqlEntry{key, "Query"})
+			continue
+		}
+		result, err := root.def.resolve(e.ctx, root.args)
+		if err != nil {
+			e.errors = append(e.errors, gqlError{Message: err.Error(), Path: []any{key}})
+			data = append(data, gqlEntry{key, nil})
+			continue
+		}
+		switch v := result.(type) {
+		case *SMSView:
+			if v == nil {
+				data = append(data, gqlEntry{key, nil})
+			} else {
+				data = append(data, gqlEntry{key, smsObject(*v, root.sub)})
+			}
+		case []SMSView:
+			list := make([]gqlObject, len(v))
+			for i, item := range v {
+				list[i] = smsObject(item, root.sub)
+			}
+			data = append(data, gqlEntry{key, list})
+		default:
+			data = append(data, gqlEntry{key, nil})
+		}
+	}
+	return data, nil
+}
+
+func smsObject(v SMSView, fields []gqlSelection) gqlObject {
+	obj := make(gqlObject, 0, len(fields))
+	for _, f := range fields {
+		if f.Name == "__typename" {
+			obj = append(obj, gqlEntry{f.key(), "SMS"})
+		} else {
+			obj = append(obj, gqlEntry{f.key(), gqlSMSFields[f.Name](v)})
+		}
+	}
+	return obj
+}
+
+// 注册 /graphql，GRAPHQL_ENABLED=false 时不开放
+func registerGraphQLRoutes(r *gin.Engine) {
+	if getEnvWithDefault("GRAPHQL_ENABLED", "true") != "true" {
+		return
+	}
+	r.GET("/graphql", graphqlHandler)
+	r.POST("/graphql", graphqlHandler)
+	r.GET("/graphql/schema", func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(graphqlSchema))
+	})
+}
+
+// GET /graphql?query=...&variables=...，POST /graphql（application/json 或 application/graphql）
+// 无法执行的请求返回 400 与 errors；字段读取失败时仍返回 200，data 中对应字段为 null
+func graphqlHandler(c *gin.Context) {
+	var req gqlRequest
+	var err error
+	switch {
+	case c.Request.Method == http.MethodGet:
+		req.Query, req.OperationName = c.Query("query"), c.Query("operationName")
+		if vars := c.Query("variables"); vars != "" {
+			err = json.Unmarshal([]byte(vars), &req.Variables)
+		}
+	case c.ContentType() == "application/graphql":
+		var body []byte
+		body, err = io.ReadAll(c.Request.Body)
+		req.Query = string(body)
+	default:
+		err = c.ShouldBindJSON(&req)
+	}
+	if err == nil && strings.TrimSpace(req.Query) == "" {
+		err = errors.New("query 不能为空")
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"errors": []gqlError{{Message: "请求解析失败: " + err.Error()}}})
+		return
+	}
+
+	e, op, err := prepareGraphQL(c.Request.Context(), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"errors": []gqlError{{Message: err.Error()}}})
+		return
+	}
+	data, err := e.execute(op)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"errors": []gqlError{{Message: err.Error()}}})
+		return
+	}
+	resp := gin.H{"data": data}
+	if len(e.errors) > 0 {
+		resp["errors"] = e.errors
+	}
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,436 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+/* ---------- GraphQL 查询解析 ---------- */
+
+// 只实现 /graphql 需要的子集：query 操作、变量、参数、别名、片段（具名与内联）和 @include / @skip 指令；
+// 不支持 mutation、subscription 与内省查询
+
+// 词法单元类型
+const (
+	gqlEOF = iota
+	gqlPunct
+	gqlName
+	gqlInt
+	gqlFloat
+	gqlString
+)
+
+type gqlToken struct {
+	kind int
+	val  string
+	pos  int
+}
+
+// 变量引用与枚举值，解析后在执行时按变量表求值
+type (
+	gqlVariable string
+	gqlEnum     string
+)
+
+type gqlArgument struct {
+	Name  string
+	Value any
+}
+
+type gqlDirective struct {
+	Name string
+	Args []gqlArgument
+}
+
+// gqlSelection 字段、片段展开（Spread 非空）或内联片段（Inline 非 nil）
+type gqlSelection struct {
+	Alias      string
+	Name       string
+	Args       []gqlArgument
+	Directives []gqlDirective
+	Selections []gqlSelection
+	Spread     string
+	Inline     []gqlSelection
+}
+
+// 响应中的字段名
+func (s gqlSelection) key() string {
+	if s.Alias != "" {
+		return s.Alias
+	}
+	return s.Name
+}
+
+type gqlVariableDef struct {
+	Name    string
+	Type    string
+	Default any
+}
+
+type gqlOperation struct {
+	Type       string // query / mutation / subscription
+	Name       string
+	Variables  []gqlVariableDef
+	Selections []gqlSelection
+}
+
+type gqlDocument struct {
+	Operations []gqlOperation
+	Fragments  map[string][]gqlSelection
+}
+
+type gqlParser struct {
+	src string
+	pos int
+	tok gqlToken
+}
+
+// 解析查询文档，错误信息带出错位置（行:列）
+func parseGraphQL(src string) (doc *gqlDocument, err error) {
+	p := &gqlParser{src: src}
+	defer func() {
+		if r := recover(); r != nil {
+			if e, ok := r.(gqlSyntaxError); ok {
+				doc, err = nil, e
+				return
+			}
+			panic(r)
+		}
+	}()
+	p.next()
+	doc = &gqlDocument{Fragments: make(map[string][]gqlSelection)}
+	for p.tok.kind != gqlEOF {
+		switch {
+		case p.peek(gqlPunct, "{"):
+			doc.Operations = append(doc.Operations, gqlOperation{Type: "query", Selections: p.selectionSet()})
+		case p.peek(gqlName, "fragment"):
+			p.next()
+			name := p.expect(gqlName, "").val
+			p.expect(gqlName, "on")
+			p.expect(gqlName, "")
+			p.directives()
+			doc.Fragments[name] = p.selectionSet()
+		case p.tok.kind == gqlName:
+			op := gqlOperation{Type: p.tok.val}
+			if op.Type != "query" && op.Type != "mutation" && op.Type != "subscription" {
+				p.fail("未知的操作类型 %s", op.Type)
+			}
+			p.next()
+			if p.tok.kind == gqlName {
+				op.Name = p.tok.val
+				p.next()
+			}
+			if p.skip("(") {
+				for !p.skip(")") {
+					p.expect(gqlPunct, "$")
+					def := gqlVariableDef{Name: p.expect(gqlName, "").val}
+					p.expect(gqlPunct, ":")
+					def.Type = p.typeRef()
+					if p.skip("=") {
+						def.Default = p.value(true)
+					}
+					op.Variables = append(op.Variables, def)
+				}
+			}
+			p.directives()
+			op.Selections = p.selectionSet()
+			doc.Operations = append(doc.Operations, op)
+		default:
+			p.fail("意外的 %q", p.tok.val)
+		}
+	}
+	if len(doc.Operations) == 0 {
+		return nil, gqlSyntaxError("查询中没有操作")
+	}
+	return doc, nil
+}
+
+type gqlSyntaxError string
+
+func (e gqlSyntaxError) Error() string { return string(e) }
+
+func (p *gqlParser) fail(format string, args ...any) {
+	line := strings.Count(p.src[:p.tok.pos], "\n") + 1
+	col := p.tok.pos - strings.LastIndex(p.src[:p.tok.pos], "\n")
+	panic(gqlSyntaxError(fmt.Sprintf("语法错误 (%d:%d): %s", line, col, fmt.Sprintf(format, args...))))
+}
+
+func (p *gqlParser) peek(kind int, val string) bool {
+	return p.tok.kind == kind && p.tok.val == val
+}
+
+// 当前为指定标点时跳过并返回 true
+func (p *gqlParser) skip(punct string) bool {
+	if p.peek(gqlPunct, punct) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+// 要求当前词法单元为指定类型（val 非空时还需等于 val），返回并前进
+func (p *gqlParser) expect(kind int, val string) gqlToken {
+	t := p.tok
+	if t.kind != kind || (val != "" && t.val != val) {
+		if t.kind == gqlEOF {
+			p.fail("查询意外结束")
+		}
+		p.fail("意外的 %q", t.val)
+	}
+	p.next()
+	return t
+}
+
+func (p *gqlParser) selectionSet() []gqlSelection {
+	p.expect(gqlPunct, "{")
+	var sels []gqlSelection
+	for !p.skip("}") {
+		if p.skip("...") {
+			if p.tok.kind == gqlName && p.tok.val != "on" {
+				sel := gqlSelection{Spread: p.tok.val}
+				p.next()
+				sel.Directives = p.directives()
+				sels = append(sels, sel)
+				continue
+			}
+			if p.peek(gqlName, "on") { // 只有一种对象类型，类型条件不做检查
+				p.next()
+				p.expect(gqlName, "")
+			}
+			sel := gqlSelection{Directives: p.directives()}
+			sel.Inline = p.selectionSet()
+			sels = append(sels, sel)
+			continue
+		}
+		sel := gqlSelection{Name: p.expect(gqlName, "").val}
+		if p.skip(":") {
+			sel.Alias, sel.Name = sel.Name, p.expect(gqlName, "").val
+		}
+		sel.Args = p.arguments(false)
+		sel.Directives = p.directives()
+		if p.peek(gqlPunct, "{") {
+			sel.Selections = p.selectionSet()
+		}
+		sels = append(sels, sel)
+	}
+	if len(sels) == 0 {
+		p.fail("选择集不能为空")
+	}
+	return sels
+}
+
+func (p *gqlParser) arguments(constant bool) []gqlArgument {
+	if !p.skip("(") {
+		return nil
+	}
+	var args []gqlArgument
+	for !p.skip(")") {
+		arg := gqlArgument{Name: p.expect(gqlName, "").val}
+		p.expect(gqlPunct, ":")
+		arg.Value = p.value(constant)
+		args = append(args, arg)
+	}
+	return args
+}
+
+func (p *gqlParser) directives() []gqlDirective {
+	var dirs []gqlDirective
+	for p.skip("@") {
+		d := gqlDirective{Name: p.expect(gqlName, "").val}
+		d.Args = p.arguments(false)
+		dirs = append(dirs, d)
+	}
+	return dirs
+}
+
+// 变量类型如 String!、[Int]，只记录文本
+func (p *gqlParser) typeRef() string {
+	var t string
+	if p.skip("[") {
+		t = "[" + p.typeRef() + "]"
+		p.expect(gqlPunct, "]")
+	} else {
+		t = p.expect(gqlName, "").val
+	}
+	if p.skip("!") {
+		t += "!"
+	}
+	return t
+}
+
+// 解析值；constant 为 true 时不允许变量（用于变量默认值）
+func (p *gqlParser) value(constant bool) any {
+	t := p.tok
+	switch t.kind {
+	case gqlInt:
+		p.next()
+		n, err := strconv.ParseInt(t.val, 10, 64)
+		if err != nil {
+			p.fail("整数超出范围: %s", t.val)
+		}
+		return n
+	case gqlFloat:
+		p.next()
+		f, _ := strconv.ParseFloat(t.val, 64)
+		return f
+	case gqlString:
+		p.next()
+		return t.val
+	case gqlName:
+		p.next()
+		switch t.val {
+		case "true":
+			return true
+		case "false":
+			return false
+		case "null":
+			return nil
+		}
+		return gqlEnum(t.val)
+	}
+	switch {
+	case p.skip("$"):
+		if constant {
+			p.fail("此处不能使用变量")
+		}
+		return gqlVariable(p.expect(gqlName, "").val)
+	case p.skip("["):
+		list := []any{}
+		for !p.skip("]") {
+			list = append(list, p.value(constant))
+		}
+		return list
+	case p.skip("{"):
+		obj := map[string]any{}
+		for !p.skip("}") {
+			name := p.expect(gqlName, "").val
+			p.expect(gqlPunct, ":")
+			obj[name] = p.value(constant)
+		}
+		return obj
+	}
+	p.fail("意外的 %q", t.val)
+	return nil
+}
+
+// 读取下一个词法单元，跳过空白、逗号和注释
+func (p *gqlParser) next() {
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		if c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',' {
+			p.pos++
+		} else if c == '#' {
+			for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+				p.pos++
+			}
+		} else if strings.HasPrefix(p.src[p.pos:], "\ufeff") { // BOM
+			p.pos += len("\ufeff")
+		} else {
+			break
+		}
+	}
+	start := p.pos
+	p.tok = gqlToken{pos: start}
+	if p.pos >= len(p.src) {
+		return
+	}
+
+	c := p.src[p.pos]
+	switch {
+	case strings.HasPrefix(p.src[p.pos:], "..."):
+		p.pos += 3
+		p.tok.kind, p.tok.val = gqlPunct, "..."
+	case strings.IndexByte("!$()[]{}:=@|&", c) >= 0:
+		p.pos++
+		p.tok.kind, p.tok.val = gqlPunct, string(c)
+	case c == '_' || isASCIILetter(c):
+		for p.pos < len(p.src) && (p.src[p.pos] == '_' || isASCIILetter(p.src[p.pos]) || isASCIIDigit(p.src[p.pos])) {
+			p.pos++
+		}
+		p.tok.kind, p.tok.val = gqlName, p.src[start:p.pos]
+	case c == '-' || isASCIIDigit(c):
+		p.pos++
+		kind := gqlInt
+		for p.pos < len(p.src) {
+			d := p.src[p.pos]
+			if d == '.' || d == 'e' || d == 'E' || ((d == '+' || d == '-') && (p.src[p.pos-1] == 'e' || p.src[p.pos-1] == 'E')) {
+				kind = gqlFloat
+			} else if !isASCIIDigit(d) {
+				break
+			}
+			p.pos++
+		}
+		p.tok.kind, p.tok.val = kind, p.src[start:p.pos]
+	case c == '"':
+		p.tok.kind, p.tok.val = gqlString, p.stringLiteral()
+	default:
+		r, _ := utf8.DecodeRuneInString(p.src[p.pos:])
+		p.fail("无法识别的字符 %q", r)
+	}
+}
+
+// 解析双引号字符串（含 \uXXXX 等转义）与 """ 块字符串
+func (p *gqlParser) stringLiteral() string {
+	if strings.HasPrefix(p.src[p.pos:], `"""`) {
+		end := strings.Index(p.src[p.pos+3:], `"""`)
+		if end < 0 {
+			p.fail("块字符串未结束")
+		}
+		s := p.src[p.pos+3 : p.pos+3+end]
+		p.pos += end + 6
+		return strings.TrimSpace(s)
+	}
+	var b strings.Builder
+	p.pos++
+	for {
+		if p.pos >= len(p.src) || p.src[p.pos] == '\n' {
+			p.fail("字符串未结束")
+		}
+		c := p.src[p.pos]
+		if c == '"' {
+			p.pos++
+			return b.String()
+		}
+		if c != '\\' {
+			b.WriteByte(c)
+			p.pos++
+			continue
+		}
+		if p.pos+1 >= len(p.src) {
+			p.fail("字符串未结束")
+		}
+		esc := p.src[p.pos+1]
+		p.pos += 2
+		switch esc {
+		case '"', '\\', '/':
+			b.WriteByte(esc)
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case 'u':
+			if p.pos+4 > len(p.src) {
+				p.fail("无效的 Unicode 转义")
+			}
+			n, err := strconv.ParseUint(p.src[p.pos:p.pos+4], 16, 32)
+			if err != nil {
+				p.fail("无效的 Unicode 转义")
+			}
+			b.WriteRune(rune(n))
+			p.pos += 4
+		default:
+			p.fail("无效的转义字符 \\%c", esc)
+		}
+	}
+}
+
+func isASCIILetter(c byte) bool { return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') }
+func isASCIIDigit(c byte) bool  { return c >= '0' && c <= '9' }
@@ -0,0 +1,174 @@
+package main
+
+//go:generate protoc -I proto --go_out=. --go_opt=module=sms-forwarder --go-grpc_out=. --go-grpc_opt=module=sms-forwarder proto/sms_forwarder.proto
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+
+	"sms-forwarder/smspb"
+)
+
+/* ---------- gRPC 接口 ---------- */
+
+// grpcServer 以 gRPC 提供接收、查询、等待与推送，逻辑与 REST 接口共用
+type grpcServer struct {
+	smspb.UnimplementedSMSForwarderServer
+}
+
+// 配置 GRPC_PORT 时在该端口启动 gRPC 服务（与 HTTP 端口分开），并开启反射便于 grpcurl 调试
+func initGRPC() {
+	port := getEnvWithDefault("GRPC_PORT", "")
+	if port == "" {
+		return
+	}
+	lis, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		log.Fatalf("gRPC 监听端口 %s 失败: %v", port, err)
+	}
+	srv := grpc.NewServer()
+	smspb.RegisterSMSForwarderServer(srv, &grpcServer{})
+	reflection.Register(srv)
+	go func() {
+		if err := srv.Serve(lis); err != nil {
+			log.Fatalf("gRPC 服务异常退出: %v", err)
+		}
+	}()
+	log.Printf("gRPC 服务启动在端口 %s", port)
+}
+
+// 转换为响应消息，phone 为请求的号码（启用号码哈希时存储中只有哈希）
+func toProtoRecord(phone string, rec SMSRecord) *smspb.SMSRecord {
+	return &smspb.SMSRecord{
+		From:       phone,
+		Code:       rec.Code,
+		RawContent: rec.RawContent,
+		ReceivedAt: rec.ReceivedAt,
+		CacheKey:   rec.CacheKey,
+	}
+}
+
+func (s *grpcServer) ReceiveSMS(ctx context.Context, req *smspb.ReceiveSMSRequest) (*smspb.ReceiveSMSResponse, error) {
+	if req.From == "" || req.Content == "" || req.ReceivedAt == 0 {
+		return nil, status.Error(codes.InvalidArgument, "参数错误: from、content、received_at 不能为空")
+	}
+	device, err := grpcDevice(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sms := SMS{From: req.From, Content: req.Content, ReceivedAt: req.ReceivedAt, TTL: int(req.Ttl), Device: device}
+
+	if ingestStreamEnabled() {
+		id, err := enqueueIngest(ctx, sms, "")
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "写入接收队列失败: %v", err)
+		}
+		return &smspb.ReceiveSMSResponse{
+			CacheKey: smsCacheKey(phoneKey(sms.From), sms.ReceivedAt),
+			StreamId: id,
+			Accepted: true,
+		}, nil
+	}
+
+	code, cacheKey, err := processSMS(context.Background(), sms)
+	if errors.Is(err, errNoCode) {
+		return nil, status.Error(codes.InvalidArgument, "未找到验证码数字")
+	} else if err != nil {
+		return nil, status.Errorf(codes.Internal, "缓存存储失败: %v", err)
+	}
+	return &smspb.ReceiveSMSResponse{Code: code, CacheKey: cacheKey}, nil
+}
+
+// 设备令牌放在 metadata 的 x-device-token 中，校验规则与 HTTP 接收接口的 X-Device-Token 相同
+const grpcDeviceTokenKey = "x-device-token"
+
+func grpcDevice(ctx context.Context) (string, error) {
+	var token string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(grpcDeviceTokenKey); len(v) > 0 {
+			token = v[0]
+		}
+	}
+	id, authErr := verifyDeviceToken(ctx, token)
+	if authErr == nil {
+		return id, nil
+	}
+	code := codes.Unauthenticated // 缺少令牌、令牌无效和设备已停用
+	if authErr.status == http.StatusInternalServerError {
+		code = codes.Internal
+	}
+	if authErr.details != nil {
+		return "", status.Errorf(code, "%s: %v", authErr.message, authErr.details)
+	}
+	return "", status.Error(code, authErr.message)
+}
+
+func (s *grpcServer) GetLatestSMS(ctx context.Context, req *smspb.GetLatestSMSRequest) (*smspb.SMSRecord, error) {
+	if req.Phone == "" {
+		return nil, status.Error(codes.InvalidArgument, "手机号不能为空")
+	}
+	phone := resolvePhoneAlias(ctx, req.Phone)
+	rec, err := store.GetLatest(ctx, phoneKey(phone))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "查询失败: %v", err)
+	} else if rec == nil {
+		return nil, status.Error(codes.NotFound, "未找到该手机号的短信记录")
+	}
+	return toProtoRecord(phone, *rec), nil
+}
+
+func (s *grpcServer) WaitSMS(ctx context.Context, req *smspb.WaitSMSRequest) (*smspb.SMSRecord, error) {
+	if req.Phone == "" {
+		return nil, status.Error(codes.InvalidArgument, "手机号不能为空")
+	}
+	phone := resolvePhoneAlias(ctx, req.Phone)
+	timeout := 30 * time.Second
+	if req.TimeoutSeconds > 0 {
+		timeout = time.Duration(req.TimeoutSeconds) * time.Second
+	}
+	after := req.After
+	if after == 0 {
+		after = time.Now().UnixMilli()
+	}
+
+	rec, err := waitForSMS(ctx, phone, after, timeout)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, status.FromContextError(ctx.Err()).Err()
+		}
+		return nil, status.Errorf(codes.Internal, "查询失败: %v", err)
+	} else if rec == nil {
+		return nil, status.Error(codes.NotFound, "等待超时，未收到新的验证码")
+	}
+	return toProtoRecord(phone, *rec), nil
+}
+
+func (s *grpcServer) StreamSMS(req *smspb.StreamSMSRequest, stream smspb.SMSForwarder_StreamSMSServer) error {
+	if req.Phone == "" {
+		return status.Error(codes.InvalidArgument, "手机号不能为空")
+	}
+	phone := resolvePhoneAlias(stream.Context(), req.Phone)
+	key := phoneKey(phone)
+	ch := smsStreams.subscribe(key)
+	defer smsStreams.unsubscribe(key, ch)
+	for {
+		select {
+		case rec := <-ch:
+			if err := stream.Send(toProtoRecord(phone, rec)); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return nil
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"compress/gzip"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+/* ---------- gzip 请求体 ---------- */
+
+// 解压后请求体的大小上限（字节），防止压缩炸弹
+var maxDecompressedBody int64 = 10 << 20
+
+func initDecompression() {
+	if n, err := strconv.ParseInt(getEnvWithDefault("GZIP_MAX_DECOMPRESSED_BYTES", "10485760"), 10, 64); err == nil && n > 0 {
+		maxDecompressedBody = n
+	} else {
+		log.Fatalf("GZIP_MAX_DECOMPRESSED_BYTES 配置无效: %s", getEnvWithDefault("GZIP_MAX_DECOMPRESSED_BYTES", ""))
+	}
+}
+
+// decompressRequest 透明解压 Content-Encoding: gzip 的请求体，处理函数读到的是解压后的内容；
+// 用于接收类接口，方便批量补发离线短信的转发应用压缩请求
+func decompressRequest() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		encoding := strings.ToLower(strings.TrimSpace(c.GetHeader("Content-Encoding")))
+		switch encoding {
+		case "", "identity":
+			c.Next()
+			return
+		case "gzip", "x-gzip":
+		default:
+			respondError(c, http.StatusUnsupportedMediaType, ErrUnsupportedEncode, "不支持的 Content-Encoding: "+encoding+"（仅支持 gzip）", nil)
+			return
+		}
+
+		zr, err := gzip.NewReader(c.Request.Body)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, ErrInvalidArgument, "gzip 请求体解压失败", err)
+			return
+		}
+		defer zr.Close()
+		c.Request.Body = http.MaxBytesReader(c.Writer, zr, maxDecompressedBody)
+		c.Request.Header.Del("Content-Encoding")
+		c.Request.ContentLength = -1
+		c.Next()
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+/* ---------- 健康检查 ---------- */
+
+// 进程启动时间，用于 /healthz 返回运行时长
+var processStartedAt = time.Now()
+
+// 检查项状态
+const (
+	checkOK       = "ok"
+	checkDegraded = "degraded" // 依赖不可用但服务仍可工作（如 Redis 故障时已降级到内存存储）
+	checkDown     = "down"
+)
+
+// HealthCheck 单个依赖的检查结果
+type HealthCheck struct {
+	Status    string `json:"status"`
+	Message   string `json:"message,omitempty"`
+	LatencyMS int64  `json:"latency_ms"`
+}
+
+// 注册 /healthz 与 /readyz，供 Kubernetes 探针和负载均衡使用，不需要认证
+func registerHealthRoutes(r *gin.Engine) {
+	r.GET("/healthz", healthz)
+	r.GET("/readyz", readyz)
+}
+
+// GET /healthz
+// 存活探针：进程能处理请求即返回 200，不检查外部依赖，避免依赖故障导致容器被反复重启
+func healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status":         checkOK,
+		"instance":       instanceID,
+		"uptime_seconds": int64(time.Since(processStartedAt).Seconds()),
+	})
+}
+
+// GET /readyz
+// 就绪探针：检查已启用的存储依赖和转发通道，任一项为 down 时返回 503；仅有降级项时返回 200，status 为 degraded
+func readyz(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), getEnvDuration("READYZ_TIMEOUT", 2*time.Second))
+	defer cancel()
+
+	checks := make(map[string]HealthCheck)
+	if rdb != nil {
+		check := probe(ctx, func(ctx context.Context) error { return rdb.Ping(ctx).Err() })
+		if check.Status == checkDown && memoryFallbackEnabled() {
+			check.Status = checkDegraded
+		}
+		checks["redis"] = check
+	}
+	if s := sqlHistoryStore(); s != nil {
+		checks["sql"] = probe(ctx, s.db.PingContext)
+	}
+	if etcdClient != nil {
+		checks["etcd"] = probe(ctx, func(ctx context.Context) error {
+			_, err := etcdClient.Status(ctx, etcdClient.Endpoints()[0])
+			return err
+		})
+	}
+	checks["forwarders"] = forwardersCheck()
+
+	status, code := "ready", http.StatusOK
+	for _, check := range checks {
+		if check.Status == checkDown {
+			status, code = "not_ready", http.StatusServiceUnavailable
+			break
+		} else if check.Status == checkDegraded {
+			status = checkDegraded
+		}
+	}
+	c.JSON(code, gin.H{"status": status, "checks": checks})
+}
+
+// 执行一次探测并记录耗时
+func probe(ctx context.Context, ping func(ctx context.Context) error) HealthCheck {
+	start := time.Now()
+	err := ping(ctx)
+	check := HealthCheck{Status: checkOK, LatencyMS: time.Since(start).Milliseconds()}
+	if err != nil {
+		check.Status, check.Message = checkDown, err.Error()
+	}
+	return check
+}
+
+// 未启用转发通道是只缓存验证码的正常部署方式，默认视为就绪；设置 READYZ_REQUIRE_FORWARDERS=true 时视为未就绪
+func forwardersCheck() HealthCheck {
+	if len(forwarders) > 0 {
+		return HealthCheck{Status: checkOK, Message: fmt.Sprintf("已启用 %d 个转发通道", len(forwarders))}
+	}
+	check := HealthCheck{Status: checkOK, Message: "未配置任何转发通道"}
+	if getEnvWithDefault("READYZ_REQUIRE_FORWARDERS", "false") == "true" {
+		check.Status = checkDown
+	}
+	return check
+}
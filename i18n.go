@@ -0,0 +1,142 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+/* ---------- 错误信息本地化 ---------- */
+
+// 错误响应的 message 按 Accept-Language 返回中文或英文，未指定或不支持时为中文；
+// 错误码 code 不随语言变化，details 中的底层错误信息保持原文。旧路径 /api 始终返回中文，兼容按中文判断的客户端
+const (
+	langZH = "zh-CN"
+	langEN = "en"
+)
+
+// 英文错误信息，key 为代码中的中文原文；新增错误信息时同步补充
+var messagesEN = map[string]string{
+	"参数错误":                          "invalid request",
+	"查询失败":                          "query failed",
+	"删除失败":                          "delete failed",
+	"清空失败":                          "clear failed",
+	"重新投递失败":                        "redelivery failed",
+	"管理接口认证失败":                      "admin authentication failed",
+	"接口不存在":                         "endpoint not found",
+	"请求体过大":                         "request body too large",
+	"读取请求体失败":                       "failed to read request body",
+	"gzip 请求体解压失败":                  "failed to decompress gzip request body",
+	"写入接收队列失败":                      "failed to enqueue message",
+	"异步处理队列已满，请稍后重试":                "async processing queue is full, retry later",
+	"任务不存在或已过期":                     "job not found or expired",
+	"未找到验证码数字":                      "no verification code found in message",
+	"缓存存储失败":                        "failed to store message",
+	"手机号不能为空":                       "phone is required",
+	"未找到该手机号的短信记录":                  "no message found for this phone",
+	"未找到该短信记录":                      "message not found",
+	"未找到收到该验证码的记录":                  "no message found with this code",
+	"未找到该短信的投递记录":                   "no delivery record found for this message",
+	"未使用 Redis，不记录投递状态":             "delivery status requires Redis",
+	"号码统计需要使用 Redis 存储":             "phone stats require Redis storage",
+	"该手机号没有统计记录":                    "no stats for this phone",
+	"等待超时，未收到新的验证码":                 "timed out waiting for a new code",
+	"验证失败次数过多，请重新获取验证码":             "too many failed attempts, request a new code",
+	"cursor 不能与 offset、before 同时使用": "cursor cannot be combined with offset or before",
+	"cursor 无效或不属于该接口":              "cursor is invalid or belongs to another endpoint",
+	"cache_key 格式错误":                "malformed cache_key",
+	"format 参数错误，支持 csv / json":     "invalid format parameter, use csv or json",
+	"设置无效":                          "invalid settings",
+	"扫描 Redis 失败":                   "failed to scan Redis",
+	"备份文件解析失败":                      "failed to parse backup file",
+	"不支持的备份版本":                      "unsupported backup version",
+	"导入历史失败":                        "failed to import history",
+	"读取订阅失败":                        "failed to read subscriptions",
+	"保存订阅失败":                        "failed to save subscription",
+	"删除订阅失败":                        "failed to delete subscription",
+	"订阅不存在":                         "subscription not found",
+	"缺少设备令牌":                        "device token required",
+	"设备令牌无效":                        "invalid device token",
+	"设备已停用":                         "device is disabled",
+	"设备不存在":                         "device not found",
+	"读取设备失败":                        "failed to read devices",
+	"保存设备失败":                        "failed to save device",
+	"读取号码别名失败":                      "failed to read phone aliases",
+	"保存号码别名失败":                      "failed to save phone alias",
+	"删除号码别名失败":                      "failed to delete phone alias",
+	"清除数据失败":                        "failed to purge data",
+	"Idempotency-Key 格式错误":          "malformed Idempotency-Key",
+	"不超过 255 个可见字符":                 "at most 255 printable ASCII characters",
+	"Idempotency-Key 已用于内容不同的短信":    "Idempotency-Key was already used for a different message",
+	"相同的请求正在处理，请稍后重试":               "an identical request is still being processed, retry later",
+	"号码别名不存在":                       "phone alias not found",
+	"搜索需要启用 SQL 历史存储 (STORAGE_BACKEND=sqlite/postgres/mysql)":        "search requires SQL history storage (STORAGE_BACKEND=sqlite/postgres/mysql)",
+	"导出需要启用 SQL 历史存储 (STORAGE_BACKEND=sqlite/postgres/mysql)":        "export requires SQL history storage (STORAGE_BACKEND=sqlite/postgres/mysql)",
+	"按来源号码前缀查询需要启用 SQL 历史存储 (STORAGE_BACKEND=sqlite/postgres/mysql)": "querying by sender prefix requires SQL history storage (STORAGE_BACKEND=sqlite/postgres/mysql)",
+}
+
+// 带参数的错误信息
+var messagePatternsEN = []struct {
+	re *regexp.Regexp
+	en string
+}{
+	{regexp.MustCompile(`^(\w+) 参数(?:错误|无效)$`), "invalid $1 parameter"},
+	{regexp.MustCompile(`^(\w+) 参数不能为空$`), "$1 is required"},
+	{regexp.MustCompile(`^phones 需包含 1~(\d+) 个号码$`), "phones must contain 1 to $1 numbers"},
+	{regexp.MustCompile(`^每个号码最多 (\d+) 个订阅$`), "at most $1 subscriptions per phone"},
+	{regexp.MustCompile(`^offset 不能超过 (\d+)，请使用 before 翻页$`), "offset cannot exceed $1, page with before instead"},
+	{regexp.MustCompile(`^不支持的 Content-Encoding: (.*)（仅支持 gzip）$`), "unsupported Content-Encoding: ${1} (only gzip is supported)"},
+}
+
+// 按 Accept-Language 选择语言，取 q 值最高的受支持语言，q 相同时取先出现的
+func preferredLanguage(header string) string {
+	lang, best := langZH, 0.0
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		var l string
+		switch tag = strings.ToLower(tag); {
+		case tag == "en" || strings.HasPrefix(tag, "en-"):
+			l = langEN
+		case tag == "zh" || strings.HasPrefix(tag, "zh-"):
+			l = langZH
+		default:
+			continue
+		}
+		if q > best {
+			lang, best = l, q
+		}
+	}
+	return lang
+}
+
+// 请求的错误信息语言；旧路径 /api 固定为中文
+func requestLanguage(c *gin.Context) string {
+	if isLegacyAPI(c) {
+		return langZH
+	}
+	return preferredLanguage(c.GetHeader("Accept-Language"))
+}
+
+// 翻译错误信息，没有对应译文时返回原文
+func localizeMessage(lang, msg string) string {
+	if lang != langEN {
+		return msg
+	}
+	if en, ok := messagesEN[msg]; ok {
+		return en
+	}
+	for _, p := range messagePatternsEN {
+		if p.re.MatchString(msg) {
+			return p.re.ReplaceAllString(msg, p.en)
+		}
+	}
+	return msg
+}
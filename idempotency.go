@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+)
+
+/* ---------- 接收接口幂等 ---------- */
+
+// 转发应用在网络不稳定时会重试上报，同一条短信只处理一次：带相同 Idempotency-Key（未带时按号码、接收时间和内容生成）
+// 的请求直接返回第一次的响应，不重复写入历史和转发
+const (
+	headerIdempotencyKey      = "Idempotency-Key"
+	headerIdempotencyReplayed = "Idempotent-Replayed"
+)
+
+var (
+	idempotencyTTL    = 24 * time.Hour // 记录保留时长，0 为关闭
+	idempotencyDerive = true           // 未带 Idempotency-Key 时按短信内容生成
+)
+
+// 处理中的占位记录的有效期，进程在处理中崩溃时重试不会一直返回 409
+const idempotencyLockTTL = time.Minute
+
+// 一次请求的幂等记录；Done 为 false 时表示第一次请求仍在处理
+type idempotencyEntry struct {
+	Fingerprint string `json:"fingerprint"` // 短信内容摘要，同一个 key 用于不同短信时拒绝
+	Phone       string `json:"phone"`       // 存储中的号码，按号码清除数据时使用
+	Done        bool   `json:"done"`
+	Status      int    `json:"status,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Location    string `json:"location,omitempty"`
+	Body        []byte `json:"body,omitempty"`
+}
+
+// 未使用 Redis 时幂等记录只保存在本进程内
+var memoryIdempotency = struct {
+	sync.Mutex
+	m map[string]memoryIdempotencyEntry
+}{m: make(map[string]memoryIdempotencyEntry)}
+
+type memoryIdempotencyEntry struct {
+	entry     idempotencyEntry
+	expiresAt time.Time
+}
+
+func initIdempotency() {
+	idempotencyTTL = getEnvDuration("IDEMPOTENCY_TTL", 24*time.Hour)
+	idempotencyDerive = getEnvWithDefault("IDEMPOTENCY_DERIVE", "true") == "true"
+}
+
+func idempotencyKey(key string) string {
+	return redisKey("idempotency:" + key)
+}
+
+// 短信内容摘要：号码、接收时间和原始内容都相同视为同一条短信
+func smsFingerprint(sms SMS) string {
+	sum := sha256.Sum256([]byte(phoneKey(sms.From) + "\n" + strconv.FormatInt(sms.ReceivedAt, 10) + "\n" + sms.Content))
+	return hex.EncodeToString(sum[:16])
+}
+
+// 记录响应体，处理结束后写入幂等记录
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// idempotentRequest 一次占用了幂等 key 的接收请求
+type idempotentRequest struct {
+	key         string
+	fingerprint string
+	phone       string
+	writer      *recordingWriter
+}
+
+// beginIdempotent 占用请求的幂等 key。key 已处理过时直接返回第一次的响应，仍在处理或 key 对应其他短信时返回错误，
+// 这两种情况均返回 false；未启用或请求没有 key 时返回 nil, true
+func beginIdempotent(c *gin.Context, sms SMS) (*idempotentRequest, bool) {
+	if idempotencyTTL <= 0 {
+		return nil, true
+	}
+	fingerprint := smsFingerprint(sms)
+	key := c.GetHeader(headerIdempotencyKey)
+	if key == "" {
+		if !idempotencyDerive {
+			return nil, true
+		}
+		key = "sms:" + fingerprint
+	} else if !printableToken(key, 255) {
+		respondError(c, http.StatusBadRequest, ErrInvalidArgument, "Idempotency-Key 格式错误", "不超过 255 个可见字符")
+		return nil, false
+	}
+
+	key = strconv.Itoa(apiVersion(c)) + ":" + key // 旧路径与 v1 的错误响应格式不同，分别记录
+
+	ctx := c.Request.Context()
+	phone := phoneKey(sms.From)
+	existing, err := reserveIdempotency(ctx, key, idempotencyEntry{Fingerprint: fingerprint, Phone: phone})
+	if err != nil { // 读写失败时按普通请求处理，不影响接收
+		log.Printf("读取幂等记录失败: %v", err)
+		return nil, true
+	}
+	switch {
+	case existing == nil:
+		w := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		return &idempotentRequest{key: key, fingerprint: fingerprint, phone: phone, writer: w}, true
+	case existing.Fingerprint != fingerprint:
+		respondError(c, http.StatusUnprocessableEntity, ErrIdempotencyMismatch, "Idempotency-Key 已用于内容不同的短信", nil)
+	case !existing.Done:
+		respondError(c, http.StatusConflict, ErrIdempotencyConflict, "相同的请求正在处理，请稍后重试", nil)
+	default:
+		debugf("重复上报的短信，返回第一次的响应 - 来源:%s", sms.From)
+		c.Header(headerIdempotencyReplayed, "true")
+		if existing.Location != "" {
+			c.Header("Location", existing.Location)
+		}
+		c.Data(existing.Status, existing.ContentType, existing.Body)
+		c.Abort()
+	}
+	return nil, false
+}
+
+// finish 保存第一次的响应；5xx 或未写入响应时释放 key，重试时重新处理
+func (r *idempotentRequest) finish(c *gin.Context) {
+	ctx := context.Background()
+	status := c.Writer.Status()
+	if !c.Writer.Written() || status >= http.StatusInternalServerError {
+		deleteIdempotency(ctx, r.key)
+		return
+	}
+	entry := idempotencyEntry{
+		Fingerprint: r.fingerprint,
+		Phone:       r.phone,
+		Done:        true,
+		Status:      status,
+		ContentType: c.Writer.Header().Get("Content-Type"),
+		Location:    c.Writer.Header().Get("Location"),
+		Body:        r.writer.body.Bytes(),
+	}
+	if err := saveIdempotency(ctx, r.key, entry, idempotencyTTL); err != nil {
+		log.Printf("保存幂等记录失败: %v", err)
+	}
+}
+
+/* ---------- 幂等记录存储 ---------- */
+
+// 写入占位记录，key 已存在时返回已有记录
+func reserveIdempotency(ctx context.Context, key string, entry idempotencyEntry) (*idempotencyEntry, error) {
+	if rdb == nil {
+		memoryIdempotency.Lock()
+		defer memoryIdempotency.Unlock()
+		now := time.Now()
+		if e, ok := memoryIdempotency.m[key]; ok && now.Before(e.expiresAt) {
+			return &e.entry, nil
+		}
+		if len(memoryIdempotency.m) >= 10000 { // 清理已过期的记录，避免无限增长
+			for k, e := range memoryIdempotency.m {
+				if now.After(e.expiresAt) {
+					delete(memoryIdempotency.m, k)
+				}
+			}
+		}
+		memoryIdempotency.m[key] = memoryIdempotencyEntry{entry: entry, expiresAt: now.Add(idempotencyLockTTL)}
+		return nil, nil
+	}
+	ok, err := rdb.SetNX(ctx, idempotencyKey(key), sealJSON(entry), idempotencyLockTTL).Result()
+	if err != nil || ok {
+		return nil, err
+	}
+	v, err := rdb.Get(ctx, idempotencyKey(key)).Result()
+	if err == redis.Nil { // 占位记录恰好过期，按未处理过处理
+		return reserveIdempotency(ctx, key, entry)
+	} else if err != nil {
+		return nil, err
+	}
+	var existing idempotencyEntry
+	if err := openJSON(v, &existing); err != nil {
+		return nil, err
+	}
+	return &existing, nil
+}
+
+func saveIdempotency(ctx context.Context, key string, entry idempotencyEntry, ttl time.Duration) error {
+	if rdb == nil {
+		memoryIdempotency.Lock()
+		defer memoryIdempotency.Unlock()
+		memoryIdempotency.m[key] = memoryIdempotencyEntry{entry: entry, expiresAt: time.Now().Add(ttl)}
+		return nil
+	}
+	return rdb.Set(ctx, idempotencyKey(key), sealJSON(entry), ttl).Err()
+}
+
+func deleteIdempotency(ctx context.Context, key string) {
+	if rdb == nil {
+		memoryIdempotency.Lock()
+		delete(memoryIdempotency.m, key)
+		memoryIdempotency.Unlock()
+		return
+	}
+	if err := rdb.Del(ctx, idempotencyKey(key)).Err(); err != nil {
+		log.Printf("删除幂等记录失败: %v", err)
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+/* ---------- Redis Streams 接收队列 ---------- */
+
+// IngestStreamConfig 接收队列配置
+type IngestStreamConfig struct {
+	Enabled   bool
+	Stream    string // Stream key，已加 KEY_PREFIX
+	Group     string
+	Consumers int
+	MaxLen    int64         // 近似上限，超出后裁剪最早的消息
+	ClaimIdle time.Duration // 消息未确认超过该时长后由其他消费者接管（消费者崩溃时）
+}
+
+var ingestCfg = IngestStreamConfig{}
+
+func ingestStreamEnabled() bool { return ingestCfg.Enabled }
+
+// 启用后接收接口只把短信写入 Stream，由消费者组处理提取、存储与转发，保证至少处理一次
+func initIngestStream() {
+	if getEnvWithDefault("INGEST_STREAM_ENABLED", "false") != "true" {
+		return
+	}
+	if rdb == nil {
+		log.Printf("接收队列需要 Redis，bbolt 模式下已忽略 INGEST_STREAM_ENABLED")
+		return
+	}
+	consumers, _ := strconv.Atoi(getEnvWithDefault("INGEST_STREAM_CONSUMERS", "2"))
+	if consumers <= 0 {
+		consumers = 1
+	}
+	maxLen, _ := strconv.ParseInt(getEnvWithDefault("INGEST_STREAM_MAXLEN", "100000"), 10, 64)
+	claimIdle := getEnvDuration("INGEST_STREAM_CLAIM_IDLE", time.Minute)
+	if claimIdle <= 0 {
+		claimIdle = time.Minute
+	}
+	ingestCfg = IngestStreamConfig{
+		Enabled:   true,
+		Stream:    redisKey(getEnvWithDefault("INGEST_STREAM_KEY", "sms_ingest")),
+		Group:     getEnvWithDefault("INGEST_STREAM_GROUP", "sms-forward"),
+		Consumers: consumers,
+		MaxLen:    maxLen,
+		ClaimIdle: claimIdle,
+	}
+
+	// 从头创建消费组，已存在时忽略
+	err := rdb.XGroupCreateMkStream(context.Background(), ingestCfg.Stream, ingestCfg.Group, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		log.Fatalf("创建接收队列消费组失败: %v", err)
+	}
+	for i := 0; i < consumers; i++ {
+		go runIngestConsumer(instanceID + "-" + strconv.Itoa(i+1))
+	}
+	log.Printf("接收队列已启用 (stream: %s, 消费组: %s, 消费者: %d)", ingestCfg.Stream, ingestCfg.Group, consumers)
+}
+
+// 将短信写入 Stream，返回消息 ID；jobID 为对应的异步接收任务，没有时为空
+func enqueueIngest(ctx context.Context, sms SMS, jobID string) (string, error) {
+	data := sealJSON(sms)
+	values := map[string]interface{}{"sms": data}
+	if jobID != "" {
+		values["job"] = jobID
+	}
+	return rdb.XAdd(ctx, &redis.XAddArgs{
+		Stream: ingestCfg.Stream,
+		MaxLen: ingestCfg.MaxLen,
+		Approx: true,
+		Values: values,
+	}).Result()
+}
+
+// 消费者：先接管超时未确认的消息，再读取新消息
+func runIngestConsumer(consumer string) {
+	ctx := context.Background()
+	lastClaim := time.Time{}
+	block := 5 * time.Second
+	if ingestCfg.ClaimIdle < block { // 阻塞读取不超过接管间隔，保证按时检查未确认消息
+		block = ingestCfg.ClaimIdle
+	}
+	if block < 100*time.Millisecond { // BLOCK 按毫秒取整，为 0 时会一直阻塞
+		block = 100 * time.Millisecond
+	}
+	for {
+		if time.Since(lastClaim) >= ingestCfg.ClaimIdle {
+			lastClaim = time.Now()
+			claimStaleIngest(ctx, consumer)
+		}
+
+		streams, err := rdb.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    ingestCfg.Group,
+			Consumer: consumer,
+			Streams:  []string{ingestCfg.Stream, ">"},
+			Count:    10,
+			Block:    block,
+		}).Result()
+		if err == redis.Nil {
+			continue
+		} else if err != nil {
+			log.Printf("读取接收队列失败: %v", err)
+			time.Sleep(time.Second)
+			continue
+		}
+		for _, st := range streams {
+			for _, msg := range st.Messages {
+				handleIngestMessage(ctx, msg)
+			}
+		}
+	}
+}
+
+// 接管其他消费者超时未确认的消息（如处理中进程崩溃）；
+// 使用 XPENDING + XCLAIM 而非 XAUTOCLAIM，兼容 Redis 7 之前的版本及其返回格式
+func claimStaleIngest(ctx context.Context, consumer string) {
+	pending, err := rdb.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream: ingestCfg.Stream,
+		Group:  ingestCfg.Group,
+		Start:  "-",
+		End:    "+",
+		Count:  100,
+	}).Result()
+	if err == redis.Nil {
+		return
+	} else if err != nil {
+		log.Printf("查询接收队列未确认消息失败: %v", err)
+		return
+	}
+	var ids []string
+	for _, p := range pending {
+		if p.Idle >= ingestCfg.ClaimIdle {
+			ids = append(ids, p.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	msgs, err := rdb.XClaim(ctx, &redis.XClaimArgs{
+		Stream:   ingestCfg.Stream,
+		Group:    ingestCfg.Group,
+		Consumer: consumer,
+		MinIdle:  ingestCfg.ClaimIdle, // 其他消费者已先接管的消息不会重复返回
+		Messages: ids,
+	}).Result()
+	if err != nil {
+		log.Printf("接管接收队列消息失败: %v", err)
+		return
+	}
+	for _, msg := range msgs {
+		handleIngestMessage(ctx, msg)
+	}
+}
+
+// 处理一条消息：成功或无需重试（数据无效、不含验证码）时确认，存储失败时不确认，等待超时后重新处理
+func handleIngestMessage(ctx context.Context, msg redis.XMessage) {
+	var sms SMS
+	data, _ := msg.Values["sms"].(string)
+	jobID, _ := msg.Values["job"].(string)
+	if err := openJSON(data, &sms); err != nil {
+		log.Printf("接收队列消息 %s 解析失败，已丢弃: %v", msg.ID, err)
+		updateJob(ctx, jobID, func(j *IngestJob) {
+			j.Status, j.ErrorCode, j.Error = jobFailed, ErrInternal, "接收队列消息解析失败"
+		})
+	} else if err := runIngestJob(ctx, jobID, sms, true); err != nil && !errors.Is(err, errNoCode) {
+		log.Printf("接收队列消息 %s 处理失败，稍后重试: %v", msg.ID, err)
+		return
+	}
+	if err := rdb.XAck(ctx, ingestCfg.Stream, ingestCfg.Group, msg.ID).Err(); err != nil {
+		log.Printf("确认接收队列消息 %s 失败: %v", msg.ID, err)
+	}
+}
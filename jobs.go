@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+)
+
+/* ---------- 异步接收任务 ---------- */
+
+// IngestJob 异步接收的处理任务：接收接口返回 202 和 job_id，转发器或调用方可通过 GET /api/jobs/:id 查询处理结果
+type IngestJob struct {
+	ID         string    `json:"id"`
+	Status     string    `json:"status"`
+	From       string    `json:"from"`
+	ReceivedAt int64     `json:"timestamp"`
+	CacheKey   string    `json:"cache_key"`
+	Code       string    `json:"code,omitempty"`       // 处理成功后提取出的验证码
+	Attempts   int       `json:"attempts"`             // 处理次数，接收队列重新投递时递增
+	ErrorCode  ErrorCode `json:"error_code,omitempty"` // 处理失败的原因，错误码与接口错误响应相同
+	Error      string    `json:"error,omitempty"`
+	CreatedAt  int64     `json:"created_at"`
+	UpdatedAt  int64     `json:"updated_at"`
+}
+
+// 任务状态
+const (
+	jobQueued     = "queued"
+	jobProcessing = "processing"
+	jobRetrying   = "retrying" // 存储失败，接收队列稍后重新处理
+	jobSucceeded  = "succeeded"
+	jobFailed     = "failed"
+)
+
+// IngestAsyncConfig 进程内异步接收配置；启用 Redis Streams 接收队列时由队列处理，不使用进程内 worker
+type IngestAsyncConfig struct {
+	Enabled bool
+	Workers int
+	Queue   int           // 等待处理的任务上限，队列满时返回 503
+	JobTTL  time.Duration // 任务状态保留时长
+}
+
+var ingestAsync = IngestAsyncConfig{JobTTL: 24 * time.Hour}
+
+var ingestJobs chan ingestTask
+
+type ingestTask struct {
+	jobID string
+	sms   SMS
+}
+
+var errIngestQueueFull = errors.New("异步处理队列已满")
+
+// 未使用 Redis 时任务状态只保存在本进程内
+var memoryJobs = struct {
+	sync.Mutex
+	m map[string]IngestJob
+}{m: make(map[string]IngestJob)}
+
+func jobKey(id string) string {
+	return redisKey("ingest_job:" + id)
+}
+
+func initIngestAsync() {
+	ingestAsync.JobTTL = getEnvDuration("INGEST_JOB_TTL", 24*time.Hour)
+	if getEnvWithDefault("INGEST_ASYNC", "false") != "true" || ingestStreamEnabled() {
+		return
+	}
+	workers, _ := strconv.Atoi(getEnvWithDefault("INGEST_ASYNC_WORKERS", "4"))
+	if workers <= 0 {
+		workers = 1
+	}
+	queue, _ := strconv.Atoi(getEnvWithDefault("INGEST_ASYNC_QUEUE", "1000"))
+	if queue <= 0 {
+		queue = 1000
+	}
+	ingestAsync.Enabled, ingestAsync.Workers, ingestAsync.Queue = true, workers, queue
+	ingestJobs = make(chan ingestTask, queue)
+	for i := 0; i < workers; i++ {
+		go runIngestWorker()
+	}
+	log.Printf("异步接收已启用 (worker: %d, 队列上限: %d)", workers, queue)
+}
+
+// 接收接口是否立即返回 202
+func ingestAsyncEnabled() bool {
+	return ingestAsync.Enabled || ingestStreamEnabled()
+}
+
+// submitIngest 登记任务并交给接收队列或进程内 worker，返回任务及接收队列消息 ID（未启用接收队列时为空）
+func submitIngest(ctx context.Context, sms SMS) (*IngestJob, string, error) {
+	now := time.Now().UnixMilli()
+	job := &IngestJob{
+		ID:         randomHex(8),
+		Status:     jobQueued,
+		From:       sms.From,
+		ReceivedAt: sms.ReceivedAt,
+		CacheKey:   smsCacheKey(phoneKey(sms.From), sms.ReceivedAt),
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	// 先保存任务再入队，保证任务开始处理时已能查到
+	if err := saveJob(ctx, *job); err != nil {
+		return nil, "", err
+	}
+	if ingestStreamEnabled() {
+		id, err := enqueueIngest(ctx, sms, job.ID)
+		if err != nil {
+			deleteJob(ctx, job.ID)
+			return nil, "", err
+		}
+		return job, id, nil
+	}
+	select {
+	case ingestJobs <- ingestTask{jobID: job.ID, sms: sms}:
+		return job, "", nil
+	default:
+		deleteJob(ctx, job.ID)
+		return nil, "", errIngestQueueFull
+	}
+}
+
+func runIngestWorker() {
+	for t := range ingestJobs {
+		runIngestJob(context.Background(), t.jobID, t.sms, false)
+	}
+}
+
+// runIngestJob 处理一条短信并更新任务状态；retryable 为 true 时存储失败记为 retrying，由接收队列重新投递
+func runIngestJob(ctx context.Context, jobID string, sms SMS, retryable bool) error {
+	updateJob(ctx, jobID, func(j *IngestJob) {
+		j.Status = jobProcessing
+		j.Attempts++
+	})
+	code, _, err := processSMS(ctx, sms)
+	updateJob(ctx, jobID, func(j *IngestJob) {
+		switch {
+		case err == nil:
+			j.Status, j.Code, j.ErrorCode, j.Error = jobSucceeded, code, "", ""
+		case errors.Is(err, errNoCode): // 短信仍会保存历史并转发，与同步接收返回 400 一致
+			j.Status, j.ErrorCode, j.Error = jobFailed, ErrNoCodeFound, "未找到验证码数字"
+		case retryable:
+			j.Status, j.ErrorCode, j.Error = jobRetrying, ErrInternal, err.Error()
+		default:
+			j.Status, j.ErrorCode, j.Error = jobFailed, ErrInternal, err.Error()
+		}
+	})
+	if err != nil && !errors.Is(err, errNoCode) {
+		log.Printf("异步接收任务 %s 处理失败: %v", jobID, err)
+	}
+	return err
+}
+
+/* ---------- 任务存储 ---------- */
+
+// 任务保存在 Redis 中，过期时间为 INGEST_JOB_TTL，多个实例共享；值为任务 JSON（启用存储加密时为密文）
+func saveJob(ctx context.Context, job IngestJob) error {
+	if rdb == nil {
+		memoryJobs.Lock()
+		defer memoryJobs.Unlock()
+		if len(memoryJobs.m) >= 10000 { // 清理已过期的任务，避免无限增长
+			cutoff := time.Now().Add(-ingestAsync.JobTTL).UnixMilli()
+			for id, j := range memoryJobs.m {
+				if j.UpdatedAt < cutoff {
+					delete(memoryJobs.m, id)
+				}
+			}
+		}
+		memoryJobs.m[job.ID] = job
+		return nil
+	}
+	return rdb.Set(ctx, jobKey(job.ID), sealJSON(job), ingestAsync.JobTTL).Err()
+}
+
+// 读取任务，不存在或已过期时返回 nil
+func getJob(ctx context.Context, id string) (*IngestJob, error) {
+	if rdb == nil {
+		memoryJobs.Lock()
+		defer memoryJobs.Unlock()
+		j, ok := memoryJobs.m[id]
+		if !ok || time.Since(time.UnixMilli(j.UpdatedAt)) > ingestAsync.JobTTL {
+			return nil, nil
+		}
+		return &j, nil
+	}
+	v, err := rdb.Get(ctx, jobKey(id)).Result()
+	if err == redis.Nil {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var j IngestJob
+	if err := openJSON(v, &j); err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
+
+// 更新任务状态；失败只记录日志，不影响短信处理
+func updateJob(ctx context.Context, id string, fn func(*IngestJob)) {
+	if id == "" {
+		return
+	}
+	j, err := getJob(ctx, id)
+	if err != nil {
+		log.Printf("读取异步接收任务 %s 失败: %v", id, err)
+		return
+	} else if j == nil {
+		return
+	}
+	fn(j)
+	j.UpdatedAt = time.Now().UnixMilli()
+	if err := saveJob(ctx, *j); err != nil {
+		log.Printf("更新异步接收任务 %s 失败: %v", id, err)
+	}
+}
+
+func deleteJob(ctx context.Context, id string) {
+	if rdb == nil {
+		memoryJobs.Lock()
+		delete(memoryJobs.m, id)
+		memoryJobs.Unlock()
+		return
+	}
+	rdb.Del(ctx, jobKey(id))
+}
+
+// GET /api/jobs/:id
+func getJobHandler(c *gin.Context) {
+	job, err := getJob(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrInternal, "查询失败", err)
+		return
+	} else if job == nil {
+		respondError(c, http.StatusNotFound, ErrNotFound, "任务不存在或已过期", nil)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success", "data": job})
+}
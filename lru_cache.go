@@ -0,0 +1,134 @@
+package main
+
+import (
+	"container/list"
+	"context"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+)
+
+/* ---------- 最新短信进程内 LRU 缓存 ---------- */
+
+// 缓存中的一项，rec 为 nil 表示号码暂无记录（同样缓存，避免轮询时反复查询）
+type lruEntry struct {
+	phone     string
+	rec       *SMSRecord
+	expiresAt time.Time
+}
+
+// latestCache 在存储前缓存 GetLatest 的结果，有效期很短；
+// 本实例写入或删除时立即失效，其他实例的写入通过 sms_events 事件失效（未启用事件时最多延迟 ttl）
+type latestCache struct {
+	Storage
+
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	order   *list.List // 最近使用的在前
+	entries map[string]*list.Element
+}
+
+var latestLRU *latestCache
+
+// 配置 LATEST_CACHE_SIZE > 0 时包装存储，需在 initStorage 之后调用
+func initLatestCache() {
+	size, _ := strconv.Atoi(getEnvWithDefault("LATEST_CACHE_SIZE", "1000"))
+	ttl := getEnvDuration("LATEST_CACHE_TTL", time.Second)
+	if size <= 0 || ttl <= 0 {
+		return
+	}
+	latestLRU = &latestCache{
+		Storage: store,
+		size:    size,
+		ttl:     ttl,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+	store = latestLRU
+	log.Printf("最新短信本地缓存已启用 (容量 %d, 有效期 %s)", size, ttl)
+}
+
+func (c *latestCache) GetLatest(ctx context.Context, phone string) (*SMSRecord, error) {
+	if rec, ok := c.get(phone); ok {
+		return rec, nil
+	}
+	rec, err := c.Storage.GetLatest(ctx, phone)
+	if err != nil {
+		return nil, err
+	}
+	c.put(phone, rec)
+	return rec, nil
+}
+
+func (c *latestCache) SaveSMS(ctx context.Context, rec SMSRecord) error {
+	err := c.Storage.SaveSMS(ctx, rec)
+	c.invalidate(rec.From)
+	return err
+}
+
+func (c *latestCache) Delete(ctx context.Context, phone string) error {
+	err := c.Storage.Delete(ctx, phone)
+	c.invalidate(phone)
+	return err
+}
+
+func (c *latestCache) DeleteRecord(ctx context.Context, phone string, receivedAt int64) (bool, error) {
+	found, err := c.Storage.DeleteRecord(ctx, phone, receivedAt)
+	c.invalidate(phone)
+	return found, err
+}
+
+func (c *latestCache) ConsumeLatest(ctx context.Context, phone string) (*SMSRecord, error) {
+	rec, err := c.Storage.ConsumeLatest(ctx, phone)
+	c.invalidate(phone)
+	return rec, err
+}
+
+func (c *latestCache) get(phone string) (*SMSRecord, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[phone]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*lruEntry)
+	if time.Now().After(e.expiresAt) {
+		c.order.Remove(el)
+		delete(c.entries, phone)
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	if e.rec == nil {
+		return nil, true
+	}
+	rec := *e.rec // 返回副本，调用方修改不影响缓存
+	return &rec, true
+}
+
+func (c *latestCache) put(phone string, rec *SMSRecord) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e := &lruEntry{phone: phone, rec: rec, expiresAt: time.Now().Add(c.ttl)}
+	if el, ok := c.entries[phone]; ok {
+		el.Value = e
+		c.order.MoveToFront(el)
+		return
+	}
+	c.entries[phone] = c.order.PushFront(e)
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry).phone)
+	}
+}
+
+func (c *latestCache) invalidate(phone string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[phone]; ok {
+		c.order.Remove(el)
+		delete(c.entries, phone)
+	}
+}
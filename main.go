@@ -3,28 +3,55 @@ package main
 import (
 	"bytes"
 	"context"
-	"encoding/json"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"os"
-	"regexp"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/go-redis/redis/v8"
 	"github.com/joho/godotenv"
+
+	"sms-forwarder/smspb"
 )
 
 /* ---------- 数据结构 ---------- */
 
 // SMS 短信数据结构
+// XML 请求体的根元素名不限，字段为同名子元素；表单字段与 JSON 同名
 type SMS struct {
-	From       string `json:"from" binding:"required"`
-	Content    string `json:"content" binding:"required"`
-	ReceivedAt int64  `json:"received_at,string" binding:"required"` // 兼容带引号时间戳
+	From       string `json:"from" xml:"from" form:"from" binding:"required"`
+	Content    string `json:"content" xml:"content" form:"content" binding:"required"`
+	ReceivedAt int64  `json:"received_at,string" xml:"received_at" form:"received_at" binding:"required"` // 兼容带引号时间戳
+	TTL        int    `json:"ttl,omitempty" xml:"ttl,omitempty" form:"ttl"`                               // 可选：缓存有效期（秒），不超过 SMS_TTL_MAX
+
+	DeviceToken string `json:"device_token,omitempty" xml:"device_token,omitempty" form:"device_token"` // 可选：设备令牌，也可通过 X-Device-Token 请求头传递
+	Device      string `json:"device,omitempty" xml:"-" form:"-"`                                       // 令牌校验通过后的设备 ID，由服务端填写
+}
+
+// SMSView /api/v1 返回的验证码记录：同时包含原始内容与提取出的验证码，
+// 旧路径 /api 仍返回 SMS 格式（content 为验证码），见 smsResponse
+type SMSView struct {
+	Sender     string `json:"sender"`
+	Code       string `json:"code"`
+	RawContent string `json:"raw_content"` // 早期版本缓存在 Redis 中的记录为空
+	ReceivedAt int64  `json:"received_at"`
+	CacheKey   string `json:"cache_key"`
+	Device     string `json:"device,omitempty"`
+
+	// 验证码的过期时间（毫秒）与剩余有效期（秒），调用方据此判断是否需要重新获取；已过期或无法确定时不返回
+	ExpiresAt    int64 `json:"expires_at,omitempty"`
+	TTLRemaining int64 `json:"ttl_remaining,omitempty"`
 }
 
 // QueryRequest 查询请求数据结构
@@ -32,23 +59,41 @@ type QueryRequest struct {
 	Phone string `json:"phone" binding:"required"`
 }
 
+// BatchQueryRequest 批量查询的请求体
+type BatchQueryRequest struct {
+	Phones []string `json:"phones" binding:"required"`
+}
+
+// 单次批量查询的号码数上限
+const batchQueryMax = 100
+
 // Redis配置结构
 type RedisConfig struct {
 	Host     string
 	Port     string
+	Username string // Redis 6+ ACL 用户名，为空时只用密码认证
 	Password string
 	DB       int
 	PoolSize int
+
+	// TLS：云厂商托管 Redis（ElastiCache、Upstash、Azure 等）通常要求
+	TLS           bool
+	CACert        string // 自定义 CA 证书文件，为空时使用系统根证书
+	TLSSkipVerify bool
+
+	// Sentinel 模式：配置 master 名称后忽略 Host/Port，由哨兵发现主节点并自动故障转移
+	SentinelMaster   string
+	SentinelAddrs    []string
+	SentinelPassword string
+
+	// 集群模式：配置节点地址后使用 ClusterClient，DB 固定为 0
+	ClusterAddrs []string
 }
 
 /* ---------- 全局变量 ---------- */
 
 var (
-	rdb *redis.Client
-
-	// 提取验证码：优先匹配“验证码…123456”，否则取最后一串 4~8 位数字
-	reCodeSpecific = regexp.MustCompile(`验证码[^0-9]*([0-9]{4,8})`)
-	reCodeFallback = regexp.MustCompile(`[0-9]{4,8}`)
+	rdb redis.UniversalClient // 单机 / Sentinel 为 *redis.Client，集群为 *redis.ClusterClient
 )
 
 /* ---------- 工具函数 ---------- */
@@ -61,58 +106,142 @@ func getEnvWithDefault(key, defaultValue string) string {
 	return defaultValue
 }
 
-// 从.env / 环境变量加载 Redis 配置
-func loadRedisConfig() *RedisConfig {
-	_ = godotenv.Load()
+// 获取时长类环境变量（如 5s、2m），解析失败时使用默认值
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if v := os.Getenv(key); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			return d
+		}
+		log.Printf("环境变量 %s=%q 不是合法时长，使用默认值 %s", key, v, defaultValue)
+	}
+	return defaultValue
+}
 
+// 按逗号拆分配置项，去除空白和空项
+func splitAndTrim(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// 从环境变量加载 Redis 配置（.env 已在 main 中加载）
+func loadRedisConfig() *RedisConfig {
 	db, _ := strconv.Atoi(getEnvWithDefault("REDIS_DB", "0"))
 	pool, _ := strconv.Atoi(getEnvWithDefault("REDIS_POOL_SIZE", "10"))
 
 	return &RedisConfig{
 		Host:     getEnvWithDefault("REDIS_HOST", "localhost"),
 		Port:     getEnvWithDefault("REDIS_PORT", "6379"),
+		Username: getEnvWithDefault("REDIS_USERNAME", ""),
 		Password: getEnvWithDefault("REDIS_PASSWORD", ""),
 		DB:       db,
 		PoolSize: pool,
+
+		TLS:           getEnvWithDefault("REDIS_TLS", "false") == "true",
+		CACert:        getEnvWithDefault("REDIS_CA_CERT", ""),
+		TLSSkipVerify: getEnvWithDefault("REDIS_TLS_INSECURE_SKIP_VERIFY", "false") == "true",
+
+		SentinelMaster:   getEnvWithDefault("REDIS_SENTINEL_MASTER", ""),
+		SentinelAddrs:    splitAndTrim(getEnvWithDefault("REDIS_SENTINEL_ADDRS", "")),
+		SentinelPassword: getEnvWithDefault("REDIS_SENTINEL_PASSWORD", ""),
+
+		ClusterAddrs: splitAndTrim(getEnvWithDefault("REDIS_CLUSTER_ADDRS", "")),
 	}
 }
 
+// 构造 Redis TLS 配置，未启用 TLS 时返回 nil
+func redisTLSConfig(cfg *RedisConfig) *tls.Config {
+	if !cfg.TLS {
+		return nil
+	}
+	tlsCfg := &tls.Config{MinVersion: tls.VersionTLS12, InsecureSkipVerify: cfg.TLSSkipVerify}
+	if cfg.CACert != "" {
+		pem, err := os.ReadFile(cfg.CACert)
+		if err != nil {
+			log.Fatalf("读取 REDIS_CA_CERT 失败: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			log.Fatalf("REDIS_CA_CERT 中没有有效的 PEM 证书: %s", cfg.CACert)
+		}
+		tlsCfg.RootCAs = pool
+	}
+	return tlsCfg
+}
+
 // 初始化 Redis 连接
 func initRedis() {
 	cfg := loadRedisConfig()
 	addr := fmt.Sprintf("%s:%s", cfg.Host, cfg.Port)
-
-	rdb = redis.NewClient(&redis.Options{
-		Addr:         addr,
-		Password:     cfg.Password,
-		DB:           cfg.DB,
-		PoolSize:     cfg.PoolSize,
-		DialTimeout:  10 * time.Second,
-		ReadTimeout:  30 * time.Second,
-		WriteTimeout: 30 * time.Second,
-		PoolTimeout:  30 * time.Second,
-	})
+	tlsCfg := redisTLSConfig(cfg)
+
+	keyPrefix = getEnvWithDefault("KEY_PREFIX", "")
+	keyRetryQueue = redisKey(keyRetryQueue)
+	keyDeadLetter = redisKey(keyDeadLetter)
+
+	switch {
+	case len(cfg.ClusterAddrs) > 0:
+		redisClusterMode = true
+		addr = "cluster " + strings.Join(cfg.ClusterAddrs, ",")
+		rdb = redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:        cfg.ClusterAddrs,
+			Username:     cfg.Username,
+			Password:     cfg.Password,
+			TLSConfig:    tlsCfg,
+			PoolSize:     cfg.PoolSize,
+			DialTimeout:  10 * time.Second,
+			ReadTimeout:  30 * time.Second,
+			WriteTimeout: 30 * time.Second,
+			PoolTimeout:  30 * time.Second,
+		})
+	case cfg.SentinelMaster != "":
+		if len(cfg.SentinelAddrs) == 0 {
+			log.Fatalf("已配置 REDIS_SENTINEL_MASTER，但 REDIS_SENTINEL_ADDRS 为空")
+		}
+		addr = fmt.Sprintf("sentinel %s@%s", cfg.SentinelMaster, strings.Join(cfg.SentinelAddrs, ","))
+		rdb = redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:       cfg.SentinelMaster,
+			SentinelAddrs:    cfg.SentinelAddrs,
+			SentinelPassword: cfg.SentinelPassword,
+			Username:         cfg.Username,
+			Password:         cfg.Password,
+			TLSConfig:        tlsCfg,
+			DB:               cfg.DB,
+			PoolSize:         cfg.PoolSize,
+			DialTimeout:      10 * time.Second,
+			ReadTimeout:      30 * time.Second,
+			WriteTimeout:     30 * time.Second,
+			PoolTimeout:      30 * time.Second,
+		})
+	default:
+		rdb = redis.NewClient(&redis.Options{
+			Addr:         addr,
+			Username:     cfg.Username,
+			Password:     cfg.Password,
+			TLSConfig:    tlsCfg,
+			DB:           cfg.DB,
+			PoolSize:     cfg.PoolSize,
+			DialTimeout:  10 * time.Second,
+			ReadTimeout:  30 * time.Second,
+			WriteTimeout: 30 * time.Second,
+			PoolTimeout:  30 * time.Second,
+		})
+	}
 
 	if pong, err := rdb.Ping(context.Background()).Result(); err != nil {
-		log.Fatalf("Redis连接失败: %v", err)
+		if !memoryFallbackEnabled() {
+			log.Fatalf("Redis连接失败: %v", err)
+		}
+		log.Printf("Redis连接失败，将以内存存储降级运行: %v", err)
 	} else {
-		log.Printf("Redis连接成功: %s (地址: %s, DB: %d)", pong, addr, cfg.DB)
+		log.Printf("Redis连接成功: %s (地址: %s, DB: %d, TLS: %t)", pong, addr, cfg.DB, cfg.TLS)
 	}
 }
 
-// extractCode 提取 4–8 位数字验证码
-func extractCode(text string) string {
-	if m := reCodeSpecific.FindStringSubmatch(text); len(m) == 2 {
-		return m[1] // 「验证码 … 123456」
-	}
-	// fallback：取最后一串数字
-	nums := reCodeFallback.FindAllString(text, -1)
-	if len(nums) > 0 {
-		return nums[len(nums)-1]
-	}
-	return ""
-}
-
 /* ---------- 路由处理 ---------- */
 
 // POST /api/receive_sms
@@ -121,88 +250,225 @@ func receiveSMS(c *gin.Context) {
 
 	// 1) 读取并打印原始请求体
 	bodyBytes, err := c.GetRawData()
-	if err != nil {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) { // gzip 解压后超过 GZIP_MAX_DECOMPRESSED_BYTES
+		respondError(c, http.StatusRequestEntityTooLarge, ErrPayloadTooLarge, "请求体过大", err)
+		return
+	} else if err != nil {
 		log.Printf("读取请求体失败: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "读取请求体失败"})
+		respondError(c, http.StatusBadRequest, ErrInvalidArgument, "读取请求体失败", nil)
 		return
 	}
-	log.Printf("收到原始请求体: %s", string(bodyBytes))
+	debugf("收到原始请求体: %s", string(bodyBytes))
 	c.Request.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
 
-	// 2) 解析 JSON
-	if err := c.ShouldBindJSON(&sms); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误", "message": err.Error()})
+	// 2) 解析请求体（JSON / XML / 表单 / protobuf，见 bindSMS）
+	if err := bindSMS(c, &sms); err != nil {
+		respondError(c, http.StatusBadRequest, ErrInvalidArgument, "参数错误", err)
 		return
 	}
 
-	// 3) 提取验证码
-	code := extractCode(sms.Content)
-	if code == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "未找到验证码数字"})
+	// 3) 校验设备令牌，记录上报的设备
+	device, ok := authenticateDevice(c, sms.DeviceToken)
+	if !ok {
 		return
 	}
-	sms.Content = code // 仅保存数字验证码
+	sms.Device, sms.DeviceToken = device, "" // 不信任请求体中的 device，令牌也不写入队列和存储
 
-	// 4) 序列化并写 Redis
-	keyHistoric := fmt.Sprintf("sms:%s:%d", sms.From, sms.ReceivedAt)
-	data, _ := json.Marshal(sms)
+	// 4) 幂等：重试上报的同一条短信直接返回第一次的响应，不重复保存和转发
+	idem, ok := beginIdempotent(c, sms)
+	if !ok {
+		return
+	}
+	if idem != nil {
+		defer idem.finish(c)
+	}
 
-	ctx := context.Background()
-	if err := rdb.Set(ctx, keyHistoric, data, 2*time.Minute).Err(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "缓存存储失败", "message": err.Error()})
+	// 5) 异步接收时登记任务后立即返回，由接收队列或后台 worker 处理，处理结果通过 GET /api/jobs/:id 查询
+	if ingestAsyncEnabled() {
+		job, streamID, err := submitIngest(c.Request.Context(), sms)
+		if errors.Is(err, errIngestQueueFull) {
+			respondError(c, http.StatusServiceUnavailable, ErrQueueFull, "异步处理队列已满，请稍后重试", nil)
+			return
+		} else if err != nil {
+			respondError(c, http.StatusInternalServerError, ErrInternal, "写入接收队列失败", err)
+			return
+		}
+		c.Header("Location", strings.TrimSuffix(c.FullPath(), "receive_sms")+"jobs/"+job.ID)
+		if wantsProtobuf(c) {
+			c.ProtoBuf(http.StatusAccepted, &smspb.ReceiveSMSResponse{CacheKey: job.CacheKey, StreamId: streamID, Accepted: true})
+			return
+		}
+		data := gin.H{
+			"job_id":    job.ID,
+			"cache_key": job.CacheKey,
+			"from":      sms.From,
+			"timestamp": sms.ReceivedAt,
+		}
+		if streamID != "" {
+			data["stream_id"] = streamID
+		}
+		c.JSON(http.StatusAccepted, gin.H{"status": "accepted", "data": data})
 		return
 	}
-	_ = rdb.Set(ctx, fmt.Sprintf("latest_sms:%s", sms.From), data, 2*time.Minute).Err()
 
-	// 5) 日志
-	log.Printf("收到短信 - 来源:%s 验证码:%s 时间:%s",
-		sms.From, sms.Content, time.UnixMilli(sms.ReceivedAt).Format("2006-01-02 15:04:05"))
+	// 6) 提取验证码、保存并转发
+	code, keyHistoric, err := processSMS(context.Background(), sms)
+	if errors.Is(err, errNoCode) {
+		respondError(c, http.StatusBadRequest, ErrNoCodeFound, "未找到验证码数字", nil)
+		return
+	} else if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrInternal, "缓存存储失败", err)
+		return
+	}
 
-	// 6) 响应
+	// 7) 响应
+	if wantsProtobuf(c) {
+		c.ProtoBuf(http.StatusOK, &smspb.ReceiveSMSResponse{Code: code, CacheKey: keyHistoric})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"status": "success",
 		"data": gin.H{
 			"cache_key": keyHistoric,
 			"from":      sms.From,
 			"timestamp": sms.ReceivedAt,
-			"code":      sms.Content,
+			"code":      code,
 		},
 	})
 }
 
-// GET /api/latest_sms/:phone
+// 按 Content-Type 解析接收短信的请求体，未声明或无法识别时按 JSON 解析
+func bindSMS(c *gin.Context, sms *SMS) error {
+	switch c.ContentType() {
+	case binding.MIMEPROTOBUF:
+		return bindProtoSMS(c, sms)
+	case binding.MIMEXML, binding.MIMEXML2: // 部分旧款短信网关只能发送 XML
+		return c.ShouldBindXML(sms)
+	case binding.MIMEPOSTForm: // 部分安卓自动化应用只能提交表单
+		return c.ShouldBindWith(sms, binding.Form)
+	case binding.MIMEMultipartPOSTForm:
+		return c.ShouldBindWith(sms, binding.FormMultipart)
+	}
+	return c.ShouldBindJSON(sms)
+}
+
+// 短信中未提取到验证码
+var errNoCode = errors.New("未找到验证码数字")
+
+// processSMS 提取验证码、写入存储并转发，返回验证码和缓存 key；
+// HTTP 接口与接收队列消费者共用。不含验证码的短信仍会保存历史和转发，返回 errNoCode。
+// 保留规则按原始号码匹配，写入存储前再替换为号码哈希（启用时），转发仍使用原始号码
+func processSMS(ctx context.Context, sms SMS) (code, cacheKey string, err error) {
+	code = extractCode(sms.From, sms.Content)
+	go recordSMSStats(sms.From, sms.ReceivedAt, code != "")
+	if code == "" {
+		// 没有验证码的短信不缓存，但仍写入历史并交给告警类通道（如 PagerDuty）检查关键词
+		rec := SMSRecord{From: sms.From, RawContent: sms.Content, ReceivedAt: sms.ReceivedAt, Device: sms.Device}
+		if applyRetention(&rec) {
+			rec.From = phoneKey(sms.From)
+			if err := store.SaveSMS(ctx, rec); err != nil {
+				log.Printf("保存短信失败: %v", err)
+			} else {
+				notifyNewSMS(rec)
+				publishSMSEvent(rec)
+			}
+		}
+		dispatchForward(ForwardMessage{From: sms.From, RawContent: sms.Content, ReceivedAt: sms.ReceivedAt})
+		return "", "", errNoCode
+	}
+
+	// 写入存储（保留规则设置为丢弃的号码不保存）
+	cacheKey = smsCacheKey(phoneKey(sms.From), sms.ReceivedAt)
+	rec := SMSRecord{
+		From:       sms.From,
+		Code:       code,
+		RawContent: sms.Content,
+		ReceivedAt: sms.ReceivedAt,
+		CacheKey:   cacheKey,
+		TTL:        requestedSMSTTL(sms.TTL),
+		Device:     sms.Device,
+	}
+	if applyRetention(&rec) {
+		rec.From = phoneKey(sms.From)
+		if err := store.SaveSMS(ctx, rec); err != nil {
+			return "", "", err
+		}
+		notifyNewSMS(rec)
+		publishSMSEvent(rec)
+	}
+
+	// 转发到已启用的通道（失败不影响响应）
+	dispatchForward(ForwardMessage{
+		From:       sms.From,
+		Code:       code,
+		RawContent: sms.Content,
+		ReceivedAt: sms.ReceivedAt,
+		CacheKey:   cacheKey,
+	})
+	go notifySubscribers(sms.From, ForwardMessage{Code: code, RawContent: sms.Content, ReceivedAt: sms.ReceivedAt, CacheKey: cacheKey})
+
+	log.Printf("收到短信 - 来源:%s 验证码:%s 时间:%s",
+		sms.From, code, time.UnixMilli(sms.ReceivedAt).Format("2006-01-02 15:04:05"))
+	return code, cacheKey, nil
+}
+
+// GET /api/latest_sms/:phone?since=<时间>
+// since 为触发短信的时间时，可避免自动化测试拿到上一次的旧验证码
 func getLatestSMS(c *gin.Context) {
-	phone := c.Param("phone")
-	log.Printf("接收到查询请求，phone参数: %s", phone)
-	log.Printf("phone参数长度: %d", len(phone))
+	phone := resolvePhoneAlias(c.Request.Context(), c.Param("phone"))
+	debugf("接收到查询请求，phone参数: %s", phone)
+	debugf("phone参数长度: %d", len(phone))
 
 	if phone == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "手机号不能为空"})
+		respondError(c, http.StatusBadRequest, ErrInvalidArgument, "手机号不能为空", nil)
+		return
+	}
+	since, err := parseTimeParam(c.Query("since"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrInvalidArgument, "since 参数错误", err)
 		return
 	}
 
-	ctx := context.Background()
-	redisKey := fmt.Sprintf("latest_sms:%s", phone)
-	log.Printf("查询Redis key: %s", redisKey)
-
-	data, err := rdb.Get(ctx, redisKey).Result()
-	if err == redis.Nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "未找到该手机号的短信记录"})
+	rec, err := store.GetLatest(context.Background(), phoneKey(phone))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrInternal, "查询失败", err)
 		return
-	} else if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败", "message": err.Error()})
+	} else if rec == nil || rec.ReceivedAt < since {
+		respondError(c, http.StatusNotFound, ErrSMSNotFound, "未找到该手机号的短信记录", nil)
 		return
 	}
-
-	var sms SMS
-	if err := json.Unmarshal([]byte(data), &sms); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据解析失败"})
+	// 条件请求：验证码未变化时返回 304，高频轮询的客户端不必重复下载和解析
+	etag := latestSMSETag(rec)
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "no-cache")
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"status": "success", "data": sms})
+	respondSMS(c, phone, rec)
+}
+
+// 最新验证码的 ETag，由记录的 cache key 与验证码计算，收到新验证码后随之变化
+func latestSMSETag(rec *SMSRecord) string {
+	sum := sha256.Sum256([]byte(smsCacheKey(rec.From, rec.ReceivedAt) + "\x00" + rec.Code))
+	return `"` + hex.EncodeToString(sum[:8]) + `"`
 }
 
-// POST /api/query_sms
+// 判断 If-None-Match 是否命中：支持逗号分隔的多个值、* 以及弱校验（W/ 前缀）
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// POST /api/query_sms?since=<时间>&from=<来源号码前缀>
+// 请求体中的 phone 与查询参数 from 二选一：phone 查询该号码的最新验证码，
+// from 在来源号码以其开头的全部号码中查找最新验证码（需要 SQL 历史存储）
 func querySMS(c *gin.Context) {
 	var req QueryRequest
 
@@ -210,63 +476,261 @@ func querySMS(c *gin.Context) {
 	bodyBytes, err := c.GetRawData()
 	if err != nil {
 		log.Printf("读取请求体失败: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "读取请求体失败"})
+		respondError(c, http.StatusBadRequest, ErrInvalidArgument, "读取请求体失败", nil)
 		return
 	}
-	log.Printf("收到查询请求体: %s", string(bodyBytes))
+	debugf("收到查询请求体: %s", string(bodyBytes))
 	c.Request.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
 
-	// 2) 解析 JSON
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误", "message": err.Error()})
+	// 2) 解析 JSON（或 protobuf）；只按 from 查询时可以不传请求体
+	from := c.Query("from")
+	if err := bindQuery(c, &req); err != nil && from == "" {
+		respondError(c, http.StatusBadRequest, ErrInvalidArgument, "参数错误", err)
+		return
+	}
+	since, err := parseTimeParam(c.Query("since"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrInvalidArgument, "since 参数错误", err)
 		return
 	}
 
-	log.Printf("查询手机号: %s", req.Phone)
-
-	if req.Phone == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "手机号不能为空"})
+	var rec *SMSRecord
+	switch {
+	case req.Phone != "":
+		req.Phone = resolvePhoneAlias(c.Request.Context(), req.Phone)
+		log.Printf("查询手机号: %s", req.Phone)
+		rec, err = store.GetLatest(context.Background(), phoneKey(req.Phone))
+		if rec != nil && rec.ReceivedAt < since {
+			rec = nil
+		}
+	case from != "":
+		log.Printf("按来源号码前缀查询: %s", from)
+		rec, err = latestSMSBySender(c.Request.Context(), from, since)
+		if errors.Is(err, errNoSQLHistory) {
+			respondError(c, http.StatusNotImplemented, ErrSQLRequired, err.Error(), nil)
+			return
+		}
+	default:
+		respondError(c, http.StatusBadRequest, ErrInvalidArgument, "手机号不能为空", nil)
 		return
 	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrInternal, "查询失败", err)
+		return
+	} else if rec == nil {
+		respondError(c, http.StatusNotFound, ErrSMSNotFound, "未找到该手机号的短信记录", nil)
+		return
+	}
+	sender := req.Phone
+	if sender == "" {
+		sender = rec.From
+		if phoneHashKey != nil { // 存储中只有哈希，from 为精确匹配的完整号码
+			sender = from
+		}
+	}
 
-	ctx := context.Background()
-	redisKey := fmt.Sprintf("latest_sms:%s", req.Phone)
-	log.Printf("查询Redis key: %s", redisKey)
+	log.Printf("查询成功 - 来源:%s 验证码:%s", sender, rec.Code)
+	respondSMS(c, sender, rec)
+}
 
-	data, err := rdb.Get(ctx, redisKey).Result()
-	if err == redis.Nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "未找到该手机号的短信记录"})
+// POST /api/query_sms/batch?since=<时间>
+// 一次查询多个号码的最新验证码，按请求中的顺序逐个返回；未找到的号码带 error 说明，不影响其他号码
+func batchQuerySMS(c *gin.Context) {
+	var req BatchQueryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, ErrInvalidArgument, "参数错误", err)
 		return
-	} else if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败", "message": err.Error()})
+	}
+	if len(req.Phones) == 0 || len(req.Phones) > batchQueryMax {
+		respondError(c, http.StatusBadRequest, ErrInvalidArgument, fmt.Sprintf("phones 需包含 1~%d 个号码", batchQueryMax), nil)
+		return
+	}
+	since, err := parseTimeParam(c.Query("since"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrInvalidArgument, "since 参数错误", err)
 		return
 	}
 
-	var sms SMS
-	if err := json.Unmarshal([]byte(data), &sms); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据解析失败"})
+	ctx := c.Request.Context()
+	results := make([]gin.H, 0, len(req.Phones))
+	seen := make(map[string]bool, len(req.Phones))
+	found := 0
+	for _, phone := range req.Phones {
+		if phone == "" || seen[phone] {
+			continue
+		}
+		seen[phone] = true
+		target := resolvePhoneAlias(ctx, phone) // 结果中的 phone 保持请求中的写法（可能是别名）
+		rec, err := store.GetLatest(ctx, phoneKey(target))
+		switch {
+		case err != nil:
+			results = append(results, gin.H{"phone": phone, "error": "查询失败", "message": err.Error()})
+		case rec == nil || rec.ReceivedAt < since:
+			results = append(results, gin.H{"phone": phone, "error": "未找到该手机号的短信记录"})
+		default:
+			found++
+			results = append(results, gin.H{"phone": phone, "data": smsResponse(c, target, rec)})
+		}
+	}
+
+	log.Printf("批量查询 %d 个号码，%d 个有验证码", len(results), found)
+	c.JSON(http.StatusOK, gin.H{"status": "success", "data": results})
+}
+
+// POST /api/consume_sms
+// 取出最新验证码并删除，并行的测试不会拿到同一个验证码；之后查询最新短信返回 404，历史记录保留
+func consumeSMS(c *gin.Context) {
+	var req QueryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, ErrInvalidArgument, "参数错误", err)
 		return
 	}
 
-	log.Printf("查询成功 - 来源:%s 验证码:%s", sms.From, sms.Content)
-	c.JSON(http.StatusOK, gin.H{"status": "success", "data": sms})
+	req.Phone = resolvePhoneAlias(c.Request.Context(), req.Phone)
+	rec, err := store.ConsumeLatest(c.Request.Context(), phoneKey(req.Phone))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrInternal, "查询失败", err)
+		return
+	} else if rec == nil {
+		respondError(c, http.StatusNotFound, ErrSMSNotFound, "未找到该手机号的短信记录", nil)
+		return
+	}
+	log.Printf("验证码已消费 - 来源:%s 验证码:%s", req.Phone, rec.Code)
+	rec.ExpiresAt = 0 // 已取出，不再返回过期时间
+	c.JSON(http.StatusOK, gin.H{"status": "success", "data": smsResponse(c, req.Phone, rec)})
+}
+
+/* ---------- 接口版本 ---------- */
+
+// 接口版本：/api/v1 为当前版本，/api 为兼容旧路径的别名。
+// 不兼容的改动（响应格式、字段改名）只用于新版本，旧路径保持原有格式
+const (
+	apiVersionLegacy = 0
+	apiVersionV1     = 1
+)
+
+const ctxAPIVersion = "api_version"
+
+// 在请求上下文中记录路由组对应的接口版本
+func withAPIVersion(version int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(ctxAPIVersion, version)
+		c.Next()
+	}
+}
+
+// 当前请求的接口版本，未经版本路由组时为 apiVersionLegacy
+func apiVersion(c *gin.Context) int {
+	return c.GetInt(ctxAPIVersion)
+}
+
+// 按接口版本返回验证码记录；sender 为请求的号码（启用号码哈希时存储中只有哈希）
+func smsResponse(c *gin.Context, sender string, rec *SMSRecord) any {
+	if apiVersion(c) == apiVersionLegacy {
+		return SMS{From: sender, Content: rec.Code, ReceivedAt: rec.ReceivedAt}
+	}
+	return recordView(sender, *rec)
+}
+
+// 记录的 v1 视图，GraphQL 结果同样使用
+func recordView(sender string, rec SMSRecord) SMSView {
+	if rec.CacheKey == "" {
+		rec.CacheKey = smsCacheKey(rec.From, rec.ReceivedAt)
+	}
+	v := SMSView{Sender: sender, Code: rec.Code, RawContent: rec.RawContent, ReceivedAt: rec.ReceivedAt, CacheKey: rec.CacheKey, Device: rec.Device}
+	if remaining := time.Until(time.UnixMilli(rec.ExpiresAt)); rec.ExpiresAt > 0 && remaining > 0 {
+		v.ExpiresAt = rec.ExpiresAt
+		v.TTLRemaining = int64((remaining + time.Second - 1) / time.Second) // 向上取整，未过期时至少为 1
+	}
+	return v
+}
+
+// 注册短信接口，/api/v1 与 /api 共用
+func registerAPIRoutes(api *gin.RouterGroup) {
+	api.POST("/receive_sms", decompressRequest(), receiveSMS)
+	api.GET("/latest_sms/:phone", getLatestSMS)
+	api.GET("/wait_sms/:phone", waitSMS)
+	api.GET("/stream/:phone", streamSMS)
+	api.POST("/query_sms", querySMS) // 新增POST查询接口
+	api.POST("/query_sms/batch", batchQuerySMS)
+	api.POST("/consume_sms", consumeSMS)
+	api.POST("/verify_code", verifyCode)
+	api.GET("/forward_status/:cache_key", getForwardStatus)
+	api.GET("/jobs/:id", getJobHandler)
+	api.GET("/sms/:phone/history", getSMSHistory)
+	api.GET("/find_by_code/:code", findByCode)
+	api.GET("/stats/:phone", getPhoneStats)
+	registerDeviceRoutes(api)
+	if token := getEnvWithDefault("ADMIN_TOKEN", ""); token != "" { // 需要管理令牌的接口，未配置时不开放
+		auth := adminAuth(token)
+		api.GET("/phones", auth, listActivePhonesHandler)
+		api.GET("/search", auth, searchSMS) // 可跨号码检索全部历史验证码
+		api.GET("/export", auth, exportSMS)
+		api.DELETE("/sms/:phone", auth, deleteSMS) // 同时删除 SQL 历史，与清除数据一样需要管理令牌
+		api.DELETE("/sms_cache/:cache_key", auth, deleteSMSByCacheKey)
+		api.POST("/subscriptions", auth, createSubscription) // 回调中带有验证码，不能由匿名调用方注册
+		api.GET("/subscriptions", auth, listSubscriptionsHandler)
+		api.DELETE("/subscriptions/:id", auth, deleteSubscriptionHandler)
+		api.DELETE("/data/:phone", auth, purgePhoneData)
+	}
 }
 
 /* ---------- 启动入口 ---------- */
 
 func main() {
-	initRedis()
+	_ = godotenv.Load()
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrateCommand(os.Args[2:])
+		return
+	}
 
-	r := gin.Default()
-	r.Use(gin.Logger(), gin.Recovery())
+	if redisRequired() {
+		initRedis()
+	}
+	if etcdEnabled() {
+		initEtcd()
+	}
+	initSettings()
+	initEncryption()
+	initPhoneHashing()
+	initStorage()
+	initLatestCache()
+	initArchiver()
+	initRetention()
+	initEvents()
+	initForwarders()
+	initRouting()
+	initEtcdConfig()
+	initDeliveryStatus()
+	initSubscriptions()
+	initDevices()
+	initVerify()
+	initStats()
+	initAudit()
+	initDecompression()
+	initRateLimits()
+	startForwardWorkers()
+	initRetryQueue()
+	initIngestStream()
+	initIngestAsync()
+	initIdempotency()
+	initGRPC()
 
-	api := r.Group("/api")
-	{
-		api.POST("/receive_sms", receiveSMS)
-		api.GET("/latest_sms/:phone", getLatestSMS)
-		api.POST("/query_sms", querySMS) // 新增POST查询接口
+	r := gin.Default()
+	r.Use(gin.Logger(), gin.Recovery(), requestID())
+	r.NoRoute(notFoundHandler)
+	if cfg := loadCORSConfig(); cfg != nil {
+		r.Use(corsMiddleware(cfg))
+		log.Printf("CORS 已启用，允许来源: %s", strings.Join(cfg.Origins, ", "))
 	}
 
+	registerAPIRoutes(r.Group("/api/v1", withAPIVersion(apiVersionV1)))
+	registerAPIRoutes(r.Group("/api", withAPIVersion(apiVersionLegacy))) // 兼容已部署的转发器配置
+	registerAdminRoutes(r)
+	registerDocsRoutes(r)
+	registerGraphQLRoutes(r)
+	registerHealthRoutes(r)
+
 	port := getEnvWithDefault("SERVER_PORT", "8080")
 	log.Printf("短信转发服务启动在端口 %s", port)
 	if err := r.Run(":" + port); err != nil {
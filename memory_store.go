@@ -0,0 +1,391 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+/* ---------- 内存存储（Redis 不可用时降级） ---------- */
+
+// 内存中的一条记录及其过期时间
+type memoryEntry struct {
+	rec       SMSRecord
+	expiresAt time.Time
+}
+
+// MemoryStorage 进程内带过期时间的存储，超过容量时淘汰最早写入的记录
+type MemoryStorage struct {
+	mu         sync.Mutex
+	maxEntries int
+	entries    []memoryEntry // 按写入顺序
+}
+
+func newMemoryStorage(maxEntries int) *MemoryStorage {
+	if maxEntries <= 0 {
+		maxEntries = 10000
+	}
+	return &MemoryStorage{maxEntries: maxEntries}
+}
+
+// 清理过期记录，调用方需持有锁
+func (m *MemoryStorage) pruneLocked(now time.Time) {
+	live := m.entries[:0]
+	for _, e := range m.entries {
+		if now.Before(e.expiresAt) {
+			live = append(live, e)
+		}
+	}
+	m.entries = live
+}
+
+// SaveSMS 与 Redis 一致，不含验证码的短信不保存
+func (m *MemoryStorage) SaveSMS(ctx context.Context, rec SMSRecord) error {
+	if rec.Code == "" {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.pruneLocked(now)
+	if over := len(m.entries) + 1 - m.maxEntries; over > 0 {
+		m.entries = append(m.entries[:0], m.entries[over:]...)
+	}
+	m.entries = append(m.entries, memoryEntry{rec: rec, expiresAt: now.Add(rec.ttl())})
+	return nil
+}
+
+// GetLatest 返回号码最后写入的记录，与 Redis latest_sms 的覆盖语义一致
+func (m *MemoryStorage) GetLatest(ctx context.Context, phone string) (*SMSRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.pruneLocked(time.Now())
+	for i := len(m.entries) - 1; i >= 0; i-- {
+		if rec := m.entries[i].rec; rec.From == phone {
+			rec.ExpiresAt = m.entries[i].expiresAt.UnixMilli()
+			return &rec, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *MemoryStorage) GetHistory(ctx context.Context, phone string, limit int, before int64) ([]SMSRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.pruneLocked(time.Now())
+	records := []SMSRecord{}
+	for _, e := range m.entries {
+		if e.rec.From == phone && (before == 0 || e.rec.ReceivedAt < before) {
+			records = append(records, e.rec)
+		}
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].ReceivedAt > records[j].ReceivedAt })
+	if len(records) > limit {
+		records = records[:limit]
+	}
+	return records, nil
+}
+
+func (m *MemoryStorage) Delete(ctx context.Context, phone string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	kept := m.entries[:0]
+	for _, e := range m.entries {
+		if e.rec.From != phone {
+			kept = append(kept, e)
+		}
+	}
+	m.entries = kept
+	return nil
+}
+
+func (m *MemoryStorage) DeleteRecord(ctx context.Context, phone string, receivedAt int64) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	found := false
+	kept := m.entries[:0]
+	for _, e := range m.entries {
+		if e.rec.From == phone && e.rec.ReceivedAt == receivedAt {
+			found = true
+			continue
+		}
+		kept = append(kept, e)
+	}
+	m.entries = kept
+	return found, nil
+}
+
+// ConsumeLatest 内存中没有独立的最新记录，取出最新一条后删除号码的全部记录，避免更早的验证码被当作最新返回
+func (m *MemoryStorage) ConsumeLatest(ctx context.Context, phone string) (*SMSRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.pruneLocked(time.Now())
+	var latest *SMSRecord
+	kept := m.entries[:0]
+	for _, e := range m.entries {
+		if e.rec.From == phone {
+			rec := e.rec
+			latest = &rec
+			continue
+		}
+		kept = append(kept, e)
+	}
+	m.entries = kept
+	return latest, nil
+}
+
+// 取出全部未过期记录并清空，用于 Redis 恢复后回写；记录带上 ExpiresAt，回写时沿用剩余有效期而不是重新计时
+func (m *MemoryStorage) drain() []SMSRecord {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	records := make([]SMSRecord, 0, len(m.entries))
+	for _, e := range m.entries {
+		if !now.Before(e.expiresAt) {
+			continue
+		}
+		rec := e.rec
+		rec.ExpiresAt = e.expiresAt.UnixMilli()
+		records = append(records, rec)
+	}
+	m.entries = nil
+	return records
+}
+
+// 回写失败时放回未写入的记录
+func (m *MemoryStorage) restore(records []SMSRecord) {
+	for _, rec := range records {
+		_ = m.SaveSMS(context.Background(), rec)
+	}
+}
+
+// FindByCode 内存中只保留未过期的记录，反查范围限于缓存有效期内
+func (m *MemoryStorage) FindByCode(ctx context.Context, code string, since int64, limit int) ([]SMSRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.pruneLocked(time.Now())
+	records := []SMSRecord{}
+	for i := len(m.entries) - 1; i >= 0 && len(records) < limit; i-- {
+		if rec := m.entries[i].rec; rec.Code == code && rec.ReceivedAt >= since {
+			records = append(records, rec)
+		}
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].ReceivedAt > records[j].ReceivedAt })
+	return records, nil
+}
+
+// ListActivePhones 最新记录为号码最后写入的记录，与 GetLatest 一致
+func (m *MemoryStorage) ListActivePhones(ctx context.Context) ([]ActivePhone, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.pruneLocked(time.Now())
+	byPhone := map[string]*ActivePhone{}
+	for _, e := range m.entries {
+		p := byPhone[e.rec.From]
+		if p == nil {
+			p = &ActivePhone{Phone: e.rec.From}
+			byPhone[e.rec.From] = p
+		}
+		p.Cached++
+		p.LatestReceivedAt, p.ExpiresAt = e.rec.ReceivedAt, e.expiresAt.UnixMilli()
+	}
+	phones := make([]ActivePhone, 0, len(byPhone))
+	for _, p := range byPhone {
+		phones = append(phones, *p)
+	}
+	sortActivePhones(phones)
+	return phones, nil
+}
+
+// 是否启用内存降级，启用后 Redis 连接失败不再导致启动失败
+func memoryFallbackEnabled() bool {
+	return getEnvWithDefault("STORAGE_MEMORY_FALLBACK", "true") == "true"
+}
+
+// fallbackStorage 优先使用 primary（Redis），出错时切换到内存存储，
+// 后台定期探测，恢复后将内存中的记录回写 primary 再切回
+type fallbackStorage struct {
+	primary  Storage
+	memory   *MemoryStorage
+	ping     func(ctx context.Context) error
+	interval time.Duration
+
+	mu       sync.Mutex // 保护 degraded；降级期间的写入与恢复回写互斥，避免回写后遗漏新记录
+	degraded bool
+}
+
+func newFallbackStorage(primary Storage, ping func(ctx context.Context) error) *fallbackStorage {
+	maxEntries, _ := strconv.Atoi(getEnvWithDefault("MEMORY_STORE_MAX_ENTRIES", "10000"))
+	return &fallbackStorage{
+		primary:  primary,
+		memory:   newMemoryStorage(maxEntries),
+		ping:     ping,
+		interval: getEnvDuration("STORAGE_RECOVERY_INTERVAL", 5*time.Second),
+	}
+}
+
+func (s *fallbackStorage) isDegraded() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.degraded
+}
+
+// primary 出错时判断是否需要降级：探测仍能连通说明是数据本身的错误（如解析失败），不降级
+func (s *fallbackStorage) failover(err error) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if s.ping(ctx) == nil {
+		return false
+	}
+	s.markDown(err)
+	return true
+}
+
+// 切换到内存存储并启动恢复探测
+func (s *fallbackStorage) markDown(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.degraded {
+		return
+	}
+	s.degraded = true
+	log.Printf("Redis不可用，切换到内存存储: %v", err)
+	go s.recoverLoop()
+}
+
+// 定期探测 primary，恢复后回写内存记录
+func (s *fallbackStorage) recoverLoop() {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		ctx, cancel := context.WithTimeout(context.Background(), s.interval)
+		err := s.ping(ctx)
+		cancel()
+		if err == nil && s.tryRecover() {
+			return
+		}
+	}
+}
+
+// 持锁回写全部内存记录后切回 primary；回写失败时保持降级，下次再试
+func (s *fallbackStorage) tryRecover() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records := s.memory.drain()
+	sort.Slice(records, func(i, j int) bool { return records[i].ReceivedAt < records[j].ReceivedAt }) // 保证 latest 为最新一条
+	for i, rec := range records {
+		if rec.ttl() <= 0 { // 回写期间已过期
+			continue
+		}
+		if err := s.primary.SaveSMS(context.Background(), rec); err != nil {
+			s.memory.restore(records[i:])
+			log.Printf("Redis回写失败，继续使用内存存储: %v", err)
+			return false
+		}
+	}
+	s.degraded = false
+	log.Printf("Redis已恢复，已回写 %d 条内存记录", len(records))
+	return true
+}
+
+func (s *fallbackStorage) SaveSMS(ctx context.Context, rec SMSRecord) error {
+	if !s.isDegraded() {
+		err := s.primary.SaveSMS(ctx, rec)
+		if err == nil || !s.failover(err) {
+			return err
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.degraded { // 刚刚恢复
+		return s.primary.SaveSMS(ctx, rec)
+	}
+	return s.memory.SaveSMS(ctx, rec)
+}
+
+func (s *fallbackStorage) GetLatest(ctx context.Context, phone string) (*SMSRecord, error) {
+	if !s.isDegraded() {
+		rec, err := s.primary.GetLatest(ctx, phone)
+		if err == nil || !s.failover(err) {
+			return rec, err
+		}
+	}
+	return s.memory.GetLatest(ctx, phone)
+}
+
+func (s *fallbackStorage) GetHistory(ctx context.Context, phone string, limit int, before int64) ([]SMSRecord, error) {
+	if !s.isDegraded() {
+		records, err := s.primary.GetHistory(ctx, phone, limit, before)
+		if err == nil || !s.failover(err) {
+			return records, err
+		}
+	}
+	return s.memory.GetHistory(ctx, phone, limit, before)
+}
+
+// Delete 同时删除内存记录；降级期间只能删除内存中的记录
+func (s *fallbackStorage) Delete(ctx context.Context, phone string) error {
+	_ = s.memory.Delete(ctx, phone)
+	if s.isDegraded() {
+		return nil
+	}
+	if err := s.primary.Delete(ctx, phone); err != nil && !s.failover(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *fallbackStorage) DeleteRecord(ctx context.Context, phone string, receivedAt int64) (bool, error) {
+	found, _ := s.memory.DeleteRecord(ctx, phone, receivedAt)
+	if s.isDegraded() {
+		return found, nil
+	}
+	ok, err := s.primary.DeleteRecord(ctx, phone, receivedAt)
+	if err != nil && !s.failover(err) {
+		return false, err
+	}
+	return found || ok, nil
+}
+
+func (s *fallbackStorage) ConsumeLatest(ctx context.Context, phone string) (*SMSRecord, error) {
+	if !s.isDegraded() {
+		rec, err := s.primary.ConsumeLatest(ctx, phone)
+		if err == nil || !s.failover(err) {
+			return rec, err
+		}
+	}
+	return s.memory.ConsumeLatest(ctx, phone)
+}
+
+func (s *fallbackStorage) FindByCode(ctx context.Context, code string, since int64, limit int) ([]SMSRecord, error) {
+	if !s.isDegraded() {
+		records, err := s.primary.FindByCode(ctx, code, since, limit)
+		if err == nil || !s.failover(err) {
+			return records, err
+		}
+	}
+	return s.memory.FindByCode(ctx, code, since, limit)
+}
+
+func (s *fallbackStorage) ListActivePhones(ctx context.Context) ([]ActivePhone, error) {
+	if !s.isDegraded() {
+		phones, err := s.primary.ListActivePhones(ctx)
+		if err == nil || !s.failover(err) {
+			return phones, err
+		}
+	}
+	return s.memory.ListActivePhones(ctx)
+}
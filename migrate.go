@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+/* ---------- 存储迁移命令 ---------- */
+
+// migrationSource 可作为迁移来源的存储，按需实现全量遍历
+type migrationSource interface {
+	eachRecord(ctx context.Context, fn func(SMSRecord) error) error
+}
+
+// runMigrateCommand 处理 `sms-forwarder migrate -from <后端> -to <后端>`，
+// 连接参数沿用各后端的环境变量（REDIS_*、SQLITE_PATH、POSTGRES_DSN、MYSQL_DSN、BBOLT_PATH、ETCD_*）
+func runMigrateCommand(args []string) {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	from := fs.String("from", "", "来源存储：redis / sqlite / postgres / mysql / bbolt / etcd")
+	to := fs.String("to", "", "目标存储：redis / sqlite / postgres / mysql / bbolt / etcd")
+	dryRun := fs.Bool("dry-run", false, "只统计来源记录数，不写入目标")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "用法: %s migrate -from <后端> -to <后端> [-dry-run]\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	src, dst := strings.ToLower(*from), strings.ToLower(*to)
+	if src == "" || dst == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+	if src == dst {
+		log.Fatalf("来源与目标不能是同一种存储: %s", src)
+	}
+
+	loadStorageConfig()
+	initEncryption() // 来源与目标的加密设置需一致
+	source, err := openMigrationBackend(src)
+	if err != nil {
+		log.Fatalf("打开来源存储 %s 失败: %v", src, err)
+	}
+	sourceIter, ok := source.(migrationSource)
+	if !ok {
+		log.Fatalf("存储 %s 不支持作为迁移来源", src)
+	}
+	var target Storage
+	if !*dryRun {
+		if target, err = openMigrationBackend(dst); err != nil {
+			log.Fatalf("打开目标存储 %s 失败: %v", dst, err)
+		}
+	}
+
+	ctx := context.Background()
+	start := time.Now()
+	copied, expired := 0, 0
+	err = sourceIter.eachRecord(ctx, func(rec SMSRecord) error {
+		if rec.ExpiresAt > 0 && rec.ttl() <= 0 {
+			expired++
+		}
+		if target != nil {
+			rec.ID = 0
+			if err := target.SaveSMS(ctx, rec); err != nil {
+				return fmt.Errorf("写入 %s 失败: %w", rec.CacheKey, err)
+			}
+		}
+		if copied++; copied%1000 == 0 {
+			log.Printf("已迁移 %d 条记录", copied)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Fatalf("迁移中断 (已迁移 %d 条): %v", copied, err)
+	}
+	if *dryRun {
+		log.Printf("来源 %s 共 %d 条记录（其中 %d 条缓存已过期，只写入历史）", src, copied, expired)
+		return
+	}
+	log.Printf("迁移完成: %s → %s, 共 %d 条记录（其中 %d 条缓存已过期，只写入历史），耗时 %s",
+		src, dst, copied, expired, time.Since(start).Round(time.Millisecond))
+}
+
+// 按名称打开单个存储后端，不做内存降级与组合
+func openMigrationBackend(name string) (Storage, error) {
+	switch name {
+	case "redis":
+		if rdb == nil {
+			initRedis()
+		}
+		if err := rdb.Ping(context.Background()).Err(); err != nil {
+			return nil, err
+		}
+		return &RedisStorage{client: rdb}, nil
+	case "bbolt":
+		return openBoltStorage(getEnvWithDefault("BBOLT_PATH", "sms.bolt"), getEnvDuration("BBOLT_SWEEP_INTERVAL", 30*time.Second))
+	case "etcd":
+		if etcdClient == nil {
+			initEtcd()
+		}
+		return &EtcdStorage{client: etcdClient, prefix: etcdPrefix}, nil
+	default:
+		s, version, err := openSQLBackend(name)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("%s已就绪 (schema 版本: %d)", s.dialect.name, version)
+		return s, nil
+	}
+}
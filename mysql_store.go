@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql" // 注册 database/sql 驱动 mysql
+)
+
+/* ---------- MySQL / MariaDB 历史存储 ---------- */
+
+var mysqlDialect = &sqlDialect{
+	name: "MySQL",
+	migrations: []string{
+		// 1: 短信历史表
+		`CREATE TABLE sms_history (
+			id          BIGINT       NOT NULL AUTO_INCREMENT PRIMARY KEY,
+			phone       VARCHAR(64)  NOT NULL,
+			code        VARCHAR(16)  NOT NULL DEFAULT '',
+			raw_content TEXT         NOT NULL,
+			received_at BIGINT       NOT NULL,
+			cache_key   VARCHAR(255) NOT NULL DEFAULT '',
+			created_at  BIGINT       NOT NULL
+		) DEFAULT CHARSET = utf8mb4`,
+		// 2: 按号码查询历史的索引
+		`CREATE INDEX idx_sms_history_phone_time ON sms_history (phone, received_at DESC)`,
+		// 3: 各号码的最新记录
+		`CREATE TABLE sms_latest (
+			phone       VARCHAR(64) NOT NULL PRIMARY KEY,
+			history_id  BIGINT      NOT NULL,
+			received_at BIGINT      NOT NULL
+		) DEFAULT CHARSET = utf8mb4`,
+		// 4: 缓存有效期，Redis 未命中时据此判断 SQL 中的最新记录是否仍有效
+		`ALTER TABLE sms_history ADD COLUMN expires_at BIGINT NOT NULL DEFAULT 0`,
+		// 5: 归档按写入时间取旧记录
+		`CREATE INDEX idx_sms_history_created ON sms_history (created_at)`,
+		// 6: 按来源号码保留规则计算的删除时间，0 表示不过期
+		`ALTER TABLE sms_history ADD COLUMN retain_until BIGINT NOT NULL DEFAULT 0`,
+		// 7: 上报短信的设备 ID，未使用设备令牌时为空
+		`ALTER TABLE sms_history ADD COLUMN device VARCHAR(64) NOT NULL DEFAULT ''`,
+		// 8: 号码别名
+		`CREATE TABLE phone_aliases (
+			alias      VARCHAR(64) NOT NULL PRIMARY KEY,
+			phone      TEXT        NOT NULL,
+			updated_at BIGINT      NOT NULL
+		) DEFAULT CHARSET = utf8mb4`,
+		// 9: 启用 STORAGE_ENCRYPTION_KEY 后 code、raw_content 保存为 enc:v1:<base64>，6 位验证码加密后约 55 个字符，
+		// 严格模式下超出 VARCHAR(16) / TEXT 的写入会失败
+		`ALTER TABLE sms_history
+			MODIFY code        VARCHAR(255) NOT NULL DEFAULT '',
+			MODIFY raw_content MEDIUMTEXT   NOT NULL`,
+	},
+	// 没有 ON CONFLICT … WHERE，用 IF 保留较新的记录；赋值按从左到右执行，history_id 必须在 received_at 之前更新。
+	// 为兼容 MariaDB 使用 VALUES() 而不是 8.0 的行别名写法
+	upsertLatest: `INSERT INTO sms_latest (phone, history_id, received_at) VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			history_id  = IF(VALUES(received_at) >= received_at, VALUES(history_id), history_id),
+			received_at = GREATEST(received_at, VALUES(received_at))`,
+}
+
+// 连接 MySQL / MariaDB，dsn 形如 user:pass@tcp(host:3306)/sms
+func openMySQLStore(dsn string) (*SQLStore, error) {
+	if dsn == "" {
+		return nil, errors.New("未配置 MYSQL_DSN")
+	}
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(10)
+	db.SetConnMaxLifetime(5 * time.Minute) // 早于服务端 wait_timeout 回收连接
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &SQLStore{db: db, dialect: mysqlDialect}, nil
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+)
+
+/* ---------- 号码哈希（隐私模式） ---------- */
+
+// 号码哈希密钥，为空表示不启用
+var phoneHashKey []byte
+
+// 配置 PHONE_HASH_KEY 后，存储中的号码（包括 Redis key、SQL 列和 cache_key）一律替换为 HMAC-SHA256 哈希
+func initPhoneHashing() {
+	if key := getEnvWithDefault("PHONE_HASH_KEY", ""); key != "" {
+		phoneHashKey = []byte(key)
+		log.Printf("号码哈希已启用，存储中不保存原始号码")
+	}
+}
+
+// phoneKey 返回号码在存储中使用的标识；未启用哈希时原样返回。
+// 查询时用同一密钥对请求中的号码计算哈希即可命中，无需保存原始号码
+func phoneKey(phone string) string {
+	if phoneHashKey == nil {
+		return phone
+	}
+	mac := hmac.New(sha256.New, phoneHashKey)
+	mac.Write([]byte(phone))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+
+	"sms-forwarder/smspb"
+)
+
+/* ---------- Protobuf 请求 / 响应 ---------- */
+
+// 接收与查询接口除 JSON 外支持 application/x-protobuf，消息定义与 gRPC 接口共用（proto/sms_forwarder.proto）：
+// 请求体按 Content-Type 解析，响应按 Accept 协商；错误响应仍为 JSON
+
+// 请求体是否为 protobuf
+func isProtobufRequest(c *gin.Context) bool {
+	return c.ContentType() == binding.MIMEPROTOBUF
+}
+
+// 客户端是否要求 protobuf 响应；未带 Accept 或接受任意格式时返回 JSON
+func wantsProtobuf(c *gin.Context) bool {
+	return c.NegotiateFormat(binding.MIMEJSON, binding.MIMEPROTOBUF) == binding.MIMEPROTOBUF
+}
+
+// 解析 protobuf 的接收短信请求体
+func bindProtoSMS(c *gin.Context, sms *SMS) error {
+	var req smspb.ReceiveSMSRequest
+	if err := c.ShouldBindWith(&req, binding.ProtoBuf); err != nil {
+		return err
+	}
+	if req.From == "" || req.Content == "" || req.ReceivedAt == 0 {
+		return errors.New("from、content、received_at 不能为空")
+	}
+	*sms = SMS{From: req.From, Content: req.Content, ReceivedAt: req.ReceivedAt, TTL: int(req.Ttl)}
+	return nil
+}
+
+// 解析查询请求体
+func bindQuery(c *gin.Context, req *QueryRequest) error {
+	if !isProtobufRequest(c) {
+		return c.ShouldBindJSON(req)
+	}
+	var pb smspb.GetLatestSMSRequest
+	if err := c.ShouldBindWith(&pb, binding.ProtoBuf); err != nil {
+		return err
+	}
+	if pb.Phone == "" {
+		return errors.New("phone 不能为空")
+	}
+	req.Phone = pb.Phone
+	return nil
+}
+
+// 返回查询到的验证码
+func respondSMS(c *gin.Context, sender string, rec *SMSRecord) {
+	if wantsProtobuf(c) {
+		c.ProtoBuf(http.StatusOK, toProtoRecord(sender, *rec))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success", "data": smsResponse(c, sender, rec)})
+}
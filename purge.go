@@ -0,0 +1,303 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+)
+
+/* ---------- 按号码清除数据 ---------- */
+
+// PurgeResult 按号码清除数据时各类数据的删除数量
+type PurgeResult struct {
+	History       int `json:"history"`       // 缓存和历史中的短信记录
+	Archived      int `json:"archived"`      // S3 归档中的记录
+	Subscriptions int `json:"subscriptions"` // 回调订阅
+	Aliases       int `json:"aliases"`       // 指向该号码的别名
+	Jobs          int `json:"jobs"`          // 异步接收任务
+	Idempotency   int `json:"idempotency"`   // 接收接口的幂等记录
+	ForwardJobs   int `json:"forward_jobs"`  // 转发重试队列和死信列表中的任务
+	Keys          int `json:"keys"`          // 统计、投递状态、验证失败次数等其他 Redis key
+}
+
+// DELETE /api/data/:phone
+// 删除号码的全部数据并写入审计记录，用于处理用户的数据删除请求。
+// 与 DELETE /api/sms/:phone 不同，除短信记录外还会清除归档、订阅、别名、异步任务、幂等记录、转发队列和统计；
+// 某一步失败时其余步骤照常执行，返回 500 并列出未完成的步骤，可重复调用直到成功
+func purgePhoneData(c *gin.Context) {
+	ctx := c.Request.Context()
+	phone := resolvePhoneAlias(ctx, c.Param("phone"))
+
+	result, failed := purgePhone(ctx, phone)
+	auditID, err := writeAudit(ctx, c, AuditEntry{
+		Action:  auditPhonePurge,
+		Subject: auditPhoneSubject(phone),
+		Result:  result,
+		Errors:  failed,
+	})
+	if err != nil {
+		log.Printf("写入审计记录失败: %v", err)
+		failed = append(failed, "audit: "+err.Error())
+	}
+	if len(failed) > 0 {
+		respondError(c, http.StatusInternalServerError, ErrInternal, "清除数据失败", gin.H{"deleted": result, "failed": failed})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success", "data": gin.H{"phone": phone, "deleted": result, "audit_id": auditID}})
+}
+
+// purgePhone 依次清除号码的各类数据，返回删除数量及失败的步骤
+func purgePhone(ctx context.Context, phone string) (PurgeResult, []string) {
+	key := phoneKey(phone)
+	var (
+		result PurgeResult
+		failed []string
+	)
+	run := func(step string, n *int, fn func() (int, error)) {
+		count, err := fn()
+		*n += count
+		if err != nil {
+			log.Printf("清除号码数据失败 (%s): %v", step, err)
+			failed = append(failed, step+": "+err.Error())
+		}
+	}
+
+	run("history", &result.History, func() (int, error) { return purgeHistory(ctx, key) })
+	if archiver != nil {
+		run("archive", &result.Archived, func() (int, error) { return archiver.purgePhone(ctx, key) })
+	}
+	run("subscriptions", &result.Subscriptions, func() (int, error) { return purgeSubscriptions(ctx, key) })
+	run("aliases", &result.Aliases, func() (int, error) { return purgeAliases(ctx, key) })
+	run("jobs", &result.Jobs, func() (int, error) { return purgeJobs(ctx, key) })
+	run("idempotency", &result.Idempotency, func() (int, error) { return purgeIdempotency(ctx, key) })
+	run("forward_jobs", &result.ForwardJobs, func() (int, error) { return purgeForwardJobs(ctx, key) })
+	run("keys", &result.Keys, func() (int, error) { return purgePhoneKeys(ctx, key) })
+	return result, failed
+}
+
+// 删除缓存和历史中的短信；Redis 的验证码反查索引中只有 cache key，需要先读出历史才知道验证码
+func purgeHistory(ctx context.Context, phone string) (int, error) {
+	const batch = 1000
+	var records []SMSRecord
+	for before := int64(0); ; {
+		page, err := store.GetHistory(ctx, phone, batch, before)
+		if err != nil {
+			return 0, err
+		}
+		records = append(records, page...)
+		if len(page) < batch {
+			break
+		}
+		before = page[len(page)-1].ReceivedAt
+	}
+	if rdb != nil && len(records) > 0 {
+		if _, err := rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for _, rec := range records {
+				if rec.Code != "" {
+					pipe.ZRem(ctx, codeIndexKey(rec.Code), smsCacheKey(phone, rec.ReceivedAt))
+				}
+			}
+			return nil
+		}); err != nil {
+			return 0, err
+		}
+	}
+	if err := store.Delete(ctx, phone); err != nil {
+		return 0, err
+	}
+	return len(records), nil
+}
+
+func purgeSubscriptions(ctx context.Context, phone string) (int, error) {
+	subs, err := listSubscriptions(ctx, phone)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, sub := range subs {
+		if ok, err := deleteSubscription(ctx, sub.ID); err != nil {
+			return n, err
+		} else if ok {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// 别名中保存的是原始号码
+func purgeAliases(ctx context.Context, phone string) (int, error) {
+	aliases, err := listAliases(ctx)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, a := range aliases {
+		if phoneKey(a.Phone) != phone {
+			continue
+		}
+		if ok, err := deleteAlias(ctx, a.Alias); err != nil {
+			return n, err
+		} else if ok {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func purgeJobs(ctx context.Context, phone string) (int, error) {
+	if rdb == nil {
+		memoryJobs.Lock()
+		defer memoryJobs.Unlock()
+		n := 0
+		for id, j := range memoryJobs.m {
+			if phoneKey(j.From) == phone {
+				delete(memoryJobs.m, id)
+				n++
+			}
+		}
+		return n, nil
+	}
+	return purgeMatchingKeys(ctx, redisKey("ingest_job:"), func(v string) bool {
+		var j IngestJob
+		return openJSON(v, &j) == nil && phoneKey(j.From) == phone
+	})
+}
+
+// 幂等记录中保存了第一次的响应，其中包含号码
+func purgeIdempotency(ctx context.Context, phone string) (int, error) {
+	if rdb == nil {
+		memoryIdempotency.Lock()
+		defer memoryIdempotency.Unlock()
+		n := 0
+		for k, e := range memoryIdempotency.m {
+			if e.entry.Phone == phone {
+				delete(memoryIdempotency.m, k)
+				n++
+			}
+		}
+		return n, nil
+	}
+	return purgeMatchingKeys(ctx, redisKey("idempotency:"), func(v string) bool {
+		var e idempotencyEntry
+		return openJSON(v, &e) == nil && e.Phone == phone
+	})
+}
+
+// 删除以 prefix 开头、值满足 match 的 key，返回删除数量；用于没有按号码索引的数据，需要逐个读取
+func purgeMatchingKeys(ctx context.Context, prefix string, match func(string) bool) (int, error) {
+	keys, err := scanPattern(ctx, rdb, globEscaper.Replace(prefix)+"*")
+	if err != nil || len(keys) == 0 {
+		return 0, err
+	}
+	// 集群模式下 key 分布在不同 slot，逐个 GET 而不是 MGET
+	cmds := make([]*redis.StringCmd, len(keys))
+	if _, err := rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, k := range keys {
+			cmds[i] = pipe.Get(ctx, k)
+		}
+		return nil
+	}); err != nil && err != redis.Nil {
+		return 0, err
+	}
+	var matched []string
+	for i, cmd := range cmds {
+		if v, err := cmd.Result(); err == nil && match(v) {
+			matched = append(matched, keys[i])
+		}
+	}
+	return deleteKeys(ctx, matched)
+}
+
+// 逐个删除 key，返回实际删除的数量
+func deleteKeys(ctx context.Context, keys []string) (int, error) {
+	if len(keys) == 0 {
+		return 0, nil
+	}
+	cmds := make([]*redis.IntCmd, len(keys))
+	if _, err := rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, k := range keys {
+			cmds[i] = pipe.Del(ctx, k)
+		}
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, cmd := range cmds {
+		n += int(cmd.Val())
+	}
+	return n, nil
+}
+
+// 转发重试队列和死信列表中的任务带有短信内容；未使用 Redis 时不启用重试队列
+func purgeForwardJobs(ctx context.Context, phone string) (int, error) {
+	if rdb == nil {
+		return 0, nil
+	}
+	match := func(v string) bool {
+		var job RetryJob
+		return openJSON(v, &job) == nil && phoneKey(job.Message.From) == phone
+	}
+	n := 0
+	pending, err := rdb.ZRange(ctx, keyRetryQueue, 0, -1).Result()
+	if err != nil {
+		return 0, err
+	}
+	for _, m := range pending {
+		if match(m) {
+			removed, err := rdb.ZRem(ctx, keyRetryQueue, m).Result()
+			if err != nil {
+				return n, err
+			}
+			n += int(removed)
+		}
+	}
+	dead, err := rdb.LRange(ctx, keyDeadLetter, 0, -1).Result()
+	if err != nil {
+		return n, err
+	}
+	for _, item := range dead {
+		if match(item) {
+			removed, err := rdb.LRem(ctx, keyDeadLetter, 0, item).Result()
+			if err != nil {
+				return n, err
+			}
+			n += int(removed)
+		}
+	}
+	return n, nil
+}
+
+// 统计、投递状态和验证失败次数
+func purgePhoneKeys(ctx context.Context, phone string) (int, error) {
+	if rdb == nil {
+		prefix := redisKey("verify_attempts:" + phone + ":")
+		memoryVerifyAttempts.Lock()
+		defer memoryVerifyAttempts.Unlock()
+		n := 0
+		for k := range memoryVerifyAttempts.m {
+			if strings.HasPrefix(k, prefix) {
+				delete(memoryVerifyAttempts.m, k)
+				n++
+			}
+		}
+		return n, nil
+	}
+	keys := []string{statsKey(phone)}
+	for _, pattern := range []string{
+		globEscaper.Replace(redisKey("forward_status:sms:"+redisHashTag(phone)+":")) + "*", // cache key 格式见 smsCacheKey
+		globEscaper.Replace(redisKey("verify_attempts:"+phone+":")) + "*",
+	} {
+		found, err := scanPattern(ctx, rdb, pattern)
+		if err != nil {
+			return 0, fmt.Errorf("扫描 Redis 失败: %w", err)
+		}
+		keys = append(keys, found...)
+	}
+	return deleteKeys(ctx, keys)
+}
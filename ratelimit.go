@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+/* ---------- 转发通道限流 ---------- */
+
+// channelLimiter 单个通道的限流器及溢出队列，超出速率的消息排队等待
+type channelLimiter struct {
+	limiter *rate.Limiter
+	queue   chan forwardTask
+}
+
+// 按通道标识索引，只包含配置了限流的通道
+var channelLimiters = make(map[string]*channelLimiter)
+
+// 解析 "20/m"、"1/s"、"100/h" 形式的速率，返回每个周期的次数和周期
+func parseRateLimit(s string) (int, time.Duration, error) {
+	n, unit, ok := strings.Cut(strings.TrimSpace(s), "/")
+	count, err := strconv.Atoi(n)
+	if !ok || err != nil || count <= 0 {
+		return 0, 0, fmt.Errorf("格式应为 次数/周期，如 20/m")
+	}
+	switch strings.ToLower(unit) {
+	case "s":
+		return count, time.Second, nil
+	case "m":
+		return count, time.Minute, nil
+	case "h":
+		return count, time.Hour, nil
+	}
+	return 0, 0, fmt.Errorf("周期只支持 s / m / h")
+}
+
+// 为配置了 <通道>_RATE_LIMIT 的通道创建限流器，需在 initForwarders 之后调用
+func initRateLimits() {
+	for name := range forwardersByName {
+		prefix := forwarderEnvPrefix(name)
+		spec := getEnvWithDefault(prefix+"_RATE_LIMIT", "")
+		if spec == "" {
+			continue
+		}
+		count, period, err := parseRateLimit(spec)
+		if err != nil {
+			log.Fatalf("%s_RATE_LIMIT=%s 无效: %v", prefix, spec, err)
+		}
+		queueSize, _ := strconv.Atoi(getEnvWithDefault(prefix+"_RATE_QUEUE_SIZE", "500"))
+
+		cl := &channelLimiter{
+			limiter: rate.NewLimiter(rate.Every(period/time.Duration(count)), count),
+			queue:   make(chan forwardTask, queueSize),
+		}
+		channelLimiters[name] = cl
+		go cl.run()
+		log.Printf("转发通道 %s 已限流: %s (排队上限 %d)", name, spec, queueSize)
+	}
+}
+
+// 按速率依次投递排队中的任务
+func (cl *channelLimiter) run() {
+	for task := range cl.queue {
+		if err := cl.limiter.Wait(context.Background()); err != nil {
+			log.Printf("限流等待失败: %v", err)
+		}
+		runForwardTask(task)
+	}
+}
+
+// 将任务放入通道的限流队列；队列已满时返回 false
+func (cl *channelLimiter) enqueue(task forwardTask) bool {
+	select {
+	case cl.queue <- task:
+		return true
+	default:
+		return false
+	}
+}
+
+/* ---------- 接口限流响应 ---------- */
+
+// RateLimitInfo 接口限流的当前状态，客户端据此退避
+type RateLimitInfo struct {
+	Limit     int       // 窗口内允许的次数
+	Remaining int       // 剩余次数
+	Reset     time.Time // 计数重置时间
+}
+
+// 写入 X-RateLimit-Limit / Remaining / Reset 响应头，Reset 为秒级时间戳
+func setRateLimitHeaders(c *gin.Context, info RateLimitInfo) {
+	if info.Remaining < 0 {
+		info.Remaining = 0
+	}
+	c.Header("X-RateLimit-Limit", strconv.Itoa(info.Limit))
+	c.Header("X-RateLimit-Remaining", strconv.Itoa(info.Remaining))
+	c.Header("X-RateLimit-Reset", strconv.FormatInt(info.Reset.Unix(), 10))
+}
+
+// 返回 429：除限流响应头外带 Retry-After，错误详情与响应头一致；旧路径 /api 的响应体为 {"error", "rate_limit"}
+func respondRateLimited(c *gin.Context, info RateLimitInfo, message string) {
+	info.Remaining = 0
+	retryAfter := int64(time.Until(info.Reset).Seconds() + 0.999) // 向上取整
+	if retryAfter < 1 {
+		retryAfter = 1
+	}
+	setRateLimitHeaders(c, info)
+	c.Header("Retry-After", strconv.FormatInt(retryAfter, 10))
+	rateLimit := gin.H{
+		"limit":       info.Limit,
+		"remaining":   0,
+		"reset":       info.Reset.Unix(),
+		"retry_after": retryAfter,
+	}
+	if isLegacyAPI(c) {
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": message, "rate_limit": rateLimit})
+		return
+	}
+	respondError(c, http.StatusTooManyRequests, ErrRateLimited, message, rateLimit)
+}
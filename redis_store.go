@@ -0,0 +1,436 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+/* ---------- Redis 存储 ---------- */
+
+// RedisStorage 以 sms:<号码>:<时间戳> 和 latest_sms:<号码> 缓存验证码，值为 cachedSMS 的 JSON；
+// 另以 sms_history:<号码> ZSET（score 为接收时间）保存历史，供分页查询
+type RedisStorage struct {
+	client redis.UniversalClient
+}
+
+// 是否连接 Redis 集群，由 initRedis 设置
+var redisClusterMode bool
+
+// 所有 Redis key 的前缀（KEY_PREFIX），多个环境共用同一个 Redis 时用于隔离
+var keyPrefix string
+
+// 加上 KEY_PREFIX 的实际 Redis key
+func redisKey(key string) string {
+	return keyPrefix + key
+}
+
+// 转义 SCAN 匹配模式中的通配符
+var globEscaper = strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`)
+
+// 集群模式下给号码加上 hash tag（{号码}），同一号码的 key 落在同一个 slot，
+// MGET / DEL 等多 key 操作才不会报 CROSSSLOT
+func redisHashTag(phone string) string {
+	if redisClusterMode {
+		return "{" + phone + "}"
+	}
+	return phone
+}
+
+func latestSMSKey(phone string) string {
+	return redisKey("latest_sms:" + redisHashTag(phone))
+}
+
+// 号码历史 ZSET 的 key
+func historyZSetKey(phone string) string {
+	return redisKey("sms_history:" + redisHashTag(phone))
+}
+
+// 历史 ZSET 保留时长，超过的记录在写入时清理
+var smsHistoryTTL = 24 * time.Hour
+
+// 号码全部历史 key 的匹配模式，转义前缀和号码中的通配符
+func smsKeyPattern(phone string) string {
+	return globEscaper.Replace(keyPrefix) + "sms:" + redisHashTag(globEscaper.Replace(phone)) + ":*"
+}
+
+// cachedSMS 缓存的值：沿用 SMS 的 JSON 格式（content 为验证码），另存原始内容；
+// 早期版本写入的值没有 raw_content，读取时为空
+type cachedSMS struct {
+	SMS
+	RawContent string `json:"raw_content,omitempty"`
+}
+
+// 验证码反查索引 ZSET：score 为接收时间，member 为短信的 cache key。
+// key 中使用验证码的 HMAC 摘要（启用号码哈希时以 PHONE_HASH_KEY 为密钥），不直接出现验证码
+func codeIndexKey(code string) string {
+	mac := hmac.New(sha256.New, phoneHashKey)
+	mac.Write([]byte(code))
+	return redisKey("sms_code:" + hex.EncodeToString(mac.Sum(nil))[:16])
+}
+
+// 将缓存的 SMS JSON（启用存储加密时为密文）转为存储记录
+func decodeCachedSMS(data string) (SMSRecord, error) {
+	var sms cachedSMS
+	if err := openJSON(data, &sms); err != nil {
+		return SMSRecord{}, fmt.Errorf("数据解析失败: %w", err)
+	}
+	return SMSRecord{
+		From:       sms.From,
+		Code:       sms.Content,
+		RawContent: sms.RawContent,
+		ReceivedAt: sms.ReceivedAt,
+		CacheKey:   smsCacheKey(sms.From, sms.ReceivedAt),
+		Device:     sms.Device,
+	}, nil
+}
+
+// SaveSMS 缓存验证码；不含验证码的短信不缓存。
+// 全部写入在同一个 MULTI/EXEC 事务中完成，避免只写入一半导致 latest 与历史不一致，也只需一次往返；
+// 同一号码的 key 带相同的 hash tag，集群模式下同样可以使用事务
+func (r *RedisStorage) SaveSMS(ctx context.Context, rec SMSRecord) error {
+	if rec.Code == "" {
+		return nil
+	}
+	data := sealJSON(cachedSMS{
+		SMS:        SMS{From: rec.From, Content: rec.Code, ReceivedAt: rec.ReceivedAt, Device: rec.Device},
+		RawContent: rec.RawContent,
+	})
+
+	ttl, historyTTL := rec.ttl(), rec.historyTTL()
+	hkey := historyZSetKey(rec.From)
+	cutoff := time.Now().Add(-historyTTL).UnixMilli()
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if ttl > 0 { // 迁移的已过期记录只写入历史
+			pipe.Set(ctx, redisKey(smsCacheKey(rec.From, rec.ReceivedAt)), data, ttl)
+			pipe.Set(ctx, latestSMSKey(rec.From), data, ttl)
+		}
+
+		// 历史 ZSET：写入后清理超出保留时长的记录，并顺延整个 key 的过期时间
+		pipe.ZAdd(ctx, hkey, &redis.Z{Score: float64(rec.ReceivedAt), Member: data})
+		pipe.ZRemRangeByScore(ctx, hkey, "-inf", "("+strconv.FormatInt(cutoff, 10))
+		if smsHistoryMax > 0 { // 只保留最近 N 条
+			pipe.ZRemRangeByRank(ctx, hkey, 0, int64(-smsHistoryMax-1))
+		}
+		pipe.Expire(ctx, hkey, historyTTL)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// 反查索引与号码的 key 不在同一个 slot，单独写入；索引写入失败不影响短信保存
+	ikey := codeIndexKey(rec.Code)
+	if _, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZAdd(ctx, ikey, &redis.Z{Score: float64(rec.ReceivedAt), Member: smsCacheKey(rec.From, rec.ReceivedAt)})
+		pipe.ZRemRangeByScore(ctx, ikey, "-inf", "("+strconv.FormatInt(cutoff, 10))
+		pipe.Expire(ctx, ikey, historyTTL)
+		return nil
+	}); err != nil {
+		log.Printf("写入验证码反查索引失败: %v", err)
+	}
+	return nil
+}
+
+// FindByCode 通过反查索引定位记录，再从号码的历史 ZSET 读取内容并确认验证码一致；
+// 已删除或已清理的历史记录自然不会返回
+func (r *RedisStorage) FindByCode(ctx context.Context, code string, since int64, limit int) ([]SMSRecord, error) {
+	keys, err := r.client.ZRevRangeByScore(ctx, codeIndexKey(code), &redis.ZRangeBy{
+		Min: strconv.FormatInt(since, 10), Max: "+inf", Count: int64(limit),
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	cmds := make([]*redis.StringSliceCmd, 0, len(keys))
+	if _, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			phone, receivedAt, ok := parseSMSCacheKey(key)
+			if !ok {
+				continue
+			}
+			score := strconv.FormatInt(receivedAt, 10)
+			cmds = append(cmds, pipe.ZRangeByScore(ctx, historyZSetKey(phone), &redis.ZRangeBy{Min: score, Max: score}))
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	records := []SMSRecord{}
+	for _, cmd := range cmds {
+		for _, m := range cmd.Val() {
+			if rec, err := decodeCachedSMS(m); err == nil && rec.Code == code {
+				records = append(records, rec)
+			}
+		}
+	}
+	return records, nil
+}
+
+// ListActivePhones 扫描 latest_sms:* 得到有验证码的号码，再扫描短信 key 统计各号码未过期的验证码条数
+func (r *RedisStorage) ListActivePhones(ctx context.Context) ([]ActivePhone, error) {
+	prefix := globEscaper.Replace(keyPrefix)
+	latestKeys, err := scanPattern(ctx, r.client, prefix+"latest_sms:*")
+	if err != nil {
+		return nil, err
+	}
+	smsKeys, err := scanPattern(ctx, r.client, prefix+"sms:*")
+	if err != nil {
+		return nil, err
+	}
+	counts := map[string]int{}
+	for _, key := range smsKeys {
+		if phone, _, ok := parseSMSCacheKey(strings.TrimPrefix(key, keyPrefix)); ok {
+			counts[phone]++
+		}
+	}
+
+	gets := make([]*redis.StringCmd, len(latestKeys))
+	pttls := make([]*redis.DurationCmd, len(latestKeys))
+	if _, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range latestKeys {
+			gets[i], pttls[i] = pipe.Get(ctx, key), pipe.PTTL(ctx, key)
+		}
+		return nil
+	}); err != nil && err != redis.Nil { // 扫描后过期的 key 返回 redis.Nil
+		return nil, err
+	}
+	now := time.Now()
+	phones := make([]ActivePhone, 0, len(latestKeys))
+	for i, get := range gets {
+		data, err := get.Result()
+		if err != nil {
+			continue
+		}
+		rec, err := decodeCachedSMS(data)
+		if err != nil {
+			log.Printf("跳过无法解析的最新短信 %s: %v", latestKeys[i], err)
+			continue
+		}
+		p := ActivePhone{Phone: rec.From, Cached: counts[rec.From], LatestReceivedAt: rec.ReceivedAt}
+		if ttl := pttls[i].Val(); ttl > 0 {
+			p.ExpiresAt = now.Add(ttl).UnixMilli()
+		}
+		phones = append(phones, p)
+	}
+	sortActivePhones(phones)
+	return phones, nil
+}
+
+func (r *RedisStorage) GetLatest(ctx context.Context, phone string) (*SMSRecord, error) {
+	var get *redis.StringCmd
+	var pttl *redis.DurationCmd
+	_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		get = pipe.Get(ctx, latestSMSKey(phone))
+		pttl = pipe.PTTL(ctx, latestSMSKey(phone))
+		return nil
+	})
+	if err == redis.Nil {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	rec, err := decodeCachedSMS(get.Val())
+	if err != nil {
+		return nil, err
+	}
+	if ttl := pttl.Val(); ttl > 0 { // 带上剩余有效期，供查询接口返回过期时间
+		rec.ExpiresAt = time.Now().Add(ttl).UnixMilli()
+	}
+	return &rec, nil
+}
+
+// 扫描号码的全部历史 key
+func (r *RedisStorage) scanKeys(ctx context.Context, phone string) ([]string, error) {
+	return scanPattern(ctx, r.client, smsKeyPattern(phone))
+}
+
+// 扫描匹配 pattern 的全部 key；集群模式下 SCAN 只作用于单个节点，需要遍历所有主节点
+func scanPattern(ctx context.Context, client redis.UniversalClient, pattern string) ([]string, error) {
+	cluster, ok := client.(*redis.ClusterClient)
+	if !ok {
+		return scanNode(ctx, client, pattern)
+	}
+
+	var (
+		mu   sync.Mutex
+		keys []string
+	)
+	err := cluster.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+		found, err := scanNode(ctx, node, pattern)
+		mu.Lock()
+		keys = append(keys, found...)
+		mu.Unlock()
+		return err
+	})
+	return keys, err
+}
+
+func scanNode(ctx context.Context, client redis.UniversalClient, pattern string) ([]string, error) {
+	var keys []string
+	iter := client.Scan(ctx, 0, pattern, 100).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	return keys, iter.Err()
+}
+
+// GetHistory 按接收时间倒序分页读取号码的历史 ZSET；before 不为 0 时只返回早于该时间的记录
+func (r *RedisStorage) GetHistory(ctx context.Context, phone string, limit int, before int64) ([]SMSRecord, error) {
+	max := "+inf"
+	if before > 0 {
+		max = "(" + strconv.FormatInt(before, 10) // 开区间，不含 before 本身
+	}
+	members, err := r.client.ZRevRangeByScore(ctx, historyZSetKey(phone), &redis.ZRangeBy{
+		Min: "-inf", Max: max, Count: int64(limit),
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]SMSRecord, 0, len(members))
+	for _, m := range members {
+		if rec, err := decodeCachedSMS(m); err == nil {
+			records = append(records, rec)
+		}
+	}
+	return records, nil
+}
+
+func (r *RedisStorage) Delete(ctx context.Context, phone string) error {
+	keys, err := r.scanKeys(ctx, phone)
+	if err != nil {
+		return err
+	}
+	return r.client.Del(ctx, append(keys, latestSMSKey(phone), historyZSetKey(phone))...).Err()
+}
+
+// ConsumeLatest 以 GETDEL 取出 latest_sms，并发请求只有一个能拿到；同时删除对应的短信 key，历史 ZSET 保留
+func (r *RedisStorage) ConsumeLatest(ctx context.Context, phone string) (*SMSRecord, error) {
+	data, err := r.client.GetDel(ctx, latestSMSKey(phone)).Result()
+	if err == redis.Nil {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	rec, err := decodeCachedSMS(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.client.Del(ctx, redisKey(rec.CacheKey)).Err(); err != nil {
+		log.Printf("删除已消费的短信 key %s 失败: %v", rec.CacheKey, err)
+	}
+	return &rec, nil
+}
+
+// DeleteRecord 删除短信 key 与历史 ZSET 中的记录；latest_sms 与该记录相同时一并删除，
+// 通过 WATCH latest_sms 避免误删期间新写入的最新记录
+func (r *RedisStorage) DeleteRecord(ctx context.Context, phone string, receivedAt int64) (bool, error) {
+	key := redisKey(smsCacheKey(phone, receivedAt))
+	latest := latestSMSKey(phone)
+	score := strconv.FormatInt(receivedAt, 10)
+
+	var found bool
+	err := r.client.Watch(ctx, func(tx *redis.Tx) error {
+		data, err := tx.Get(ctx, key).Result()
+		if err != nil && err != redis.Nil {
+			return err
+		}
+		latestData, err := tx.Get(ctx, latest).Result()
+		if err != nil && err != redis.Nil {
+			return err
+		}
+
+		var del *redis.IntCmd
+		var zrem *redis.IntCmd
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			del = pipe.Del(ctx, key)
+			zrem = pipe.ZRemRangeByScore(ctx, historyZSetKey(phone), score, score)
+			if data != "" && latestData == data { // latest_sms 与短信 key 写入的是同一个值
+				pipe.Del(ctx, latest)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		found = del.Val() > 0 || zrem.Val() > 0
+		return nil
+	}, latest)
+	return found, err
+}
+
+// 遍历全部记录（供迁移使用），按接收时间升序：短信 key 与历史 ZSET 合并去重，
+// 短信 key 仍有效的记录带上剩余有效期，只存在于历史中的记录 ExpiresAt 置为 1 表示已过期
+func (r *RedisStorage) eachRecord(ctx context.Context, fn func(SMSRecord) error) error {
+	prefix := globEscaper.Replace(keyPrefix)
+	keys, err := scanPattern(ctx, r.client, prefix+"sms:*")
+	if err != nil {
+		return err
+	}
+	live := make(map[string]SMSRecord, len(keys)) // cache key → 记录
+	for _, key := range keys {
+		data, err := r.client.Get(ctx, key).Result()
+		if err == redis.Nil {
+			continue
+		} else if err != nil {
+			return err
+		}
+		rec, err := decodeCachedSMS(data)
+		if err != nil {
+			log.Printf("跳过无法解析的 key %s: %v", key, err)
+			continue
+		}
+		if ttl, err := r.client.PTTL(ctx, key).Result(); err == nil && ttl > 0 {
+			rec.ExpiresAt = time.Now().Add(ttl).UnixMilli()
+		}
+		live[rec.CacheKey] = rec
+	}
+
+	hkeys, err := scanPattern(ctx, r.client, prefix+"sms_history:*")
+	if err != nil {
+		return err
+	}
+	var records []SMSRecord
+	for _, hkey := range hkeys {
+		members, err := r.client.ZRange(ctx, hkey, 0, -1).Result()
+		if err != nil {
+			return err
+		}
+		for _, m := range members {
+			rec, err := decodeCachedSMS(m)
+			if err != nil {
+				continue
+			}
+			if l, ok := live[rec.CacheKey]; ok {
+				rec = l
+				delete(live, rec.CacheKey)
+			} else {
+				rec.ExpiresAt = 1
+			}
+			records = append(records, rec)
+		}
+	}
+	for _, rec := range live {
+		records = append(records, rec)
+	}
+
+	sort.Slice(records, func(i, j int) bool { return records[i].ReceivedAt < records[j].ReceivedAt })
+	for _, rec := range records {
+		if err := fn(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+/* ---------- 按来源号码的保留规则 ---------- */
+
+// RetentionRule 单条保留规则，按来源号码正则匹配，接收短信时取第一条命中的规则
+type RetentionRule struct {
+	Name    string        `yaml:"name"`
+	Sender  string        `yaml:"sender"`  // 来源号码正则
+	Drop    bool          `yaml:"drop"`    // 不保存到任何存储（仍会转发）
+	TTL     time.Duration `yaml:"ttl"`     // 验证码缓存有效期，请求未指定 ttl 时使用
+	History time.Duration `yaml:"history"` // 历史保留时长，不填时 Redis 使用 SMS_HISTORY_TTL，SQL 不过期
+
+	senderRe *regexp.Regexp
+}
+
+// RetentionConfig 保留规则文件（YAML 或 JSON）
+type RetentionConfig struct {
+	Rules []RetentionRule `yaml:"rules"`
+}
+
+// 当前生效的保留规则，可在运行时整体替换（如 etcd 动态配置）
+var retentionRules atomic.Pointer[RetentionConfig]
+
+// 加载 RETENTION_RULES_FILE 指定的规则文件，需在 initStorage、initArchiver 之后调用
+func initRetention() {
+	path := getEnvWithDefault("RETENTION_RULES_FILE", "")
+	if path == "" {
+		return
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Fatalf("读取保留规则文件 %s 失败: %v", path, err)
+	}
+	cfg, err := parseRetentionConfig(data)
+	if err != nil {
+		log.Fatalf("解析保留规则文件 %s 失败: %v", path, err)
+	}
+	setRetentionRules(cfg)
+	log.Printf("已加载 %d 条保留规则: %s", len(cfg.Rules), path)
+}
+
+// 解析并校验保留规则（YAML 或 JSON）
+func parseRetentionConfig(data []byte) (*RetentionConfig, error) {
+	var cfg RetentionConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+	for i := range cfg.Rules {
+		rule := &cfg.Rules[i]
+		if rule.Name == "" {
+			rule.Name = "retention-" + strconv.Itoa(i+1)
+		}
+		var err error
+		if rule.senderRe, err = regexp.Compile(rule.Sender); err != nil {
+			return nil, fmt.Errorf("保留规则 %s 的 sender 正则无效: %w", rule.Name, err)
+		}
+	}
+	return &cfg, nil
+}
+
+var retentionSweepOnce sync.Once
+
+// 替换当前的保留规则；规则设置了 history 时启动 SQL 清理协程，
+// 启用归档时由归档任务先归档再删除，否则定期直接删除
+func setRetentionRules(cfg *RetentionConfig) {
+	retentionRules.Store(cfg)
+	for _, rule := range cfg.Rules {
+		if rule.History <= 0 {
+			continue
+		}
+		if source := historyArchiveSource(); source != nil && archiver == nil {
+			retentionSweepOnce.Do(func() {
+				go runRetentionSweeper(source, getEnvDuration("RETENTION_SWEEP_INTERVAL", 10*time.Minute))
+			})
+		}
+		return
+	}
+}
+
+// 返回号码命中的第一条保留规则，未命中返回 nil
+func retentionRuleFor(phone string) *RetentionRule {
+	cfg := retentionRules.Load()
+	if cfg == nil {
+		return nil
+	}
+	for i := range cfg.Rules {
+		if rule := &cfg.Rules[i]; rule.senderRe.MatchString(phone) {
+			return rule
+		}
+	}
+	return nil
+}
+
+// 按保留规则调整记录的有效期，返回 false 表示该记录不保存
+func applyRetention(rec *SMSRecord) bool {
+	rule := retentionRuleFor(rec.From)
+	if rule == nil {
+		return true
+	}
+	if rule.Drop {
+		return false
+	}
+	if rec.TTL == 0 {
+		rec.TTL = rule.TTL
+	}
+	rec.HistoryTTL = rule.History
+	return true
+}
+
+// 定期删除 SQL 中保留期已过的记录
+func runRetentionSweeper(source archiveSource, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if n, err := source.purgeRetained(context.Background(), time.Now().UnixMilli()); err != nil {
+			log.Printf("清理过期历史失败: %v", err)
+		} else if n > 0 {
+			log.Printf("按保留规则删除 %d 条历史记录", n)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+/* ---------- 转发失败重试队列 ---------- */
+
+// 重试队列的 Redis key，由 initRedis 加上 KEY_PREFIX
+var (
+	keyRetryQueue = "forward_retry"       // ZSET，score 为下次重试时间（毫秒）
+	keyDeadLetter = "forward_dead_letter" // LIST，超过最大重试次数的任务
+)
+
+// RetryJob 一次失败的转发任务
+type RetryJob struct {
+	ID        string         `json:"id"`
+	Forwarder string         `json:"forwarder"` // 通道标识
+	Message   ForwardMessage `json:"message"`
+	Attempts  int            `json:"attempts"`
+	LastError string         `json:"last_error"`
+	FailedAt  int64          `json:"failed_at"`         // 最近一次失败时间（毫秒）
+	Content   string         `json:"content,omitempty"` // 通道不接收原始内容时单独保存，重试时恢复 Message 的关键词判断
+}
+
+func newRetryJob(forwarder string, msg ForwardMessage) RetryJob {
+	job := RetryJob{Forwarder: forwarder, Message: msg}
+	if msg.content != msg.RawContent {
+		job.Content = msg.content
+	}
+	return job
+}
+
+// 重新投递的消息
+func (j RetryJob) message() ForwardMessage {
+	msg := j.Message
+	msg.content = j.Content
+	return msg
+}
+
+// RetryConfig 重试队列配置
+type RetryConfig struct {
+	Enabled       bool
+	MaxRetries    int
+	Backoff       time.Duration
+	DeadLetterMax int64
+}
+
+var retryCfg = RetryConfig{}
+
+// 加载重试配置并启动后台重试协程
+func initRetryQueue() {
+	maxRetries, _ := strconv.Atoi(getEnvWithDefault("FORWARD_MAX_RETRIES", "5"))
+	deadMax, _ := strconv.ParseInt(getEnvWithDefault("FORWARD_DEAD_LETTER_MAX", "1000"), 10, 64)
+	retryCfg = RetryConfig{
+		Enabled:       getEnvWithDefault("FORWARD_RETRY_ENABLED", "true") == "true",
+		MaxRetries:    maxRetries,
+		Backoff:       getEnvDuration("FORWARD_RETRY_BACKOFF", 10*time.Second),
+		DeadLetterMax: deadMax,
+	}
+	if rdb == nil { // bbolt 模式不使用 Redis
+		retryCfg.Enabled = false
+	}
+	if !retryCfg.Enabled || len(forwarders) == 0 {
+		return
+	}
+	go runRetryWorker()
+	log.Printf("转发重试队列已启用 (最大重试 %d 次, 初始间隔 %s)", retryCfg.MaxRetries, retryCfg.Backoff)
+}
+
+// 查找已启用通道的标识
+func forwarderID(f Forwarder) string {
+	for name, candidate := range forwardersByName {
+		if candidate == f {
+			return name
+		}
+	}
+	return ""
+}
+
+// 第 attempts 次失败后的等待时间：Backoff * 2^(attempts-1)
+func retryDelay(attempts int) time.Duration {
+	return retryCfg.Backoff * time.Duration(math.Pow(2, float64(attempts-1)))
+}
+
+// enqueueRetry 记录一次失败的转发；超过最大重试次数时转入死信列表
+func enqueueRetry(ctx context.Context, job RetryJob, err error) {
+	if !retryCfg.Enabled {
+		return
+	}
+	job.Attempts++
+	job.LastError = err.Error()
+	job.FailedAt = time.Now().UnixMilli()
+	if job.ID == "" {
+		job.ID = fmt.Sprintf("%s:%s:%d", job.Forwarder, phoneKey(job.Message.From), time.Now().UnixNano())
+	}
+	data := sealJSON(job)
+
+	if job.Attempts > retryCfg.MaxRetries {
+		pipe := rdb.TxPipeline()
+		pipe.LPush(ctx, keyDeadLetter, data)
+		pipe.LTrim(ctx, keyDeadLetter, 0, retryCfg.DeadLetterMax-1)
+		if _, err := pipe.Exec(ctx); err != nil {
+			log.Printf("写入死信列表失败: %v", err)
+			return
+		}
+		markDeliveryDead(job.Message.CacheKey, job.Forwarder)
+		log.Printf("%s转发重试 %d 次仍失败，已转入死信列表: %s", job.Forwarder, retryCfg.MaxRetries, job.ID)
+		return
+	}
+
+	next := time.Now().Add(retryDelay(job.Attempts)).UnixMilli()
+	if err := rdb.ZAdd(ctx, keyRetryQueue, &redis.Z{Score: float64(next), Member: data}).Err(); err != nil {
+		log.Printf("写入重试队列失败: %v", err)
+	}
+}
+
+// 后台协程：每秒取出到期任务重新投递
+func runRetryWorker() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for range ticker.C {
+		processDueRetries(context.Background())
+	}
+}
+
+func processDueRetries(ctx context.Context) {
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	members, err := rdb.ZRangeByScore(ctx, keyRetryQueue, &redis.ZRangeBy{Min: "-inf", Max: now, Count: 20}).Result()
+	if err != nil {
+		return
+	}
+
+	for _, member := range members {
+		// ZREM 成功才处理，多实例部署时避免重复投递
+		if removed, err := rdb.ZRem(ctx, keyRetryQueue, member).Result(); err != nil || removed == 0 {
+			continue
+		}
+
+		var job RetryJob
+		if err := openJSON(member, &job); err != nil {
+			log.Printf("重试任务解析失败，已丢弃: %v", err)
+			continue
+		}
+		f := forwardersByName[job.Forwarder]
+		if f == nil {
+			enqueueRetry(ctx, job, fmt.Errorf("转发通道 %s 未启用", job.Forwarder))
+			continue
+		}
+		// 限流通道的重试同样受速率限制，暂时无额度时推迟 1 秒，不计入重试次数
+		if cl := channelLimiters[job.Forwarder]; cl != nil && !cl.limiter.Allow() {
+			next := float64(time.Now().Add(time.Second).UnixMilli())
+			rdb.ZAdd(ctx, keyRetryQueue, &redis.Z{Score: next, Member: member})
+			continue
+		}
+		err := f.Forward(job.message())
+		markDeliveryResult(job.Message.CacheKey, job.Forwarder, err)
+		if err != nil {
+			log.Printf("%s第 %d 次重试失败: %v", f.Name(), job.Attempts, err)
+			enqueueRetry(ctx, job, err)
+			continue
+		}
+		log.Printf("%s重试投递成功: %s", f.Name(), job.ID)
+	}
+}
+
+// 读取死信列表
+func listDeadLetters(ctx context.Context, limit int64) ([]RetryJob, error) {
+	items, err := rdb.LRange(ctx, keyDeadLetter, 0, limit-1).Result()
+	if err != nil {
+		return nil, err
+	}
+	jobs := make([]RetryJob, 0, len(items))
+	for _, item := range items {
+		var job RetryJob
+		if err := openJSON(item, &job); err == nil {
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs, nil
+}
+
+// 将死信列表中的任务重新放回重试队列，重置重试次数
+func requeueDeadLetters(ctx context.Context) (int, error) {
+	count := 0
+	for {
+		item, err := rdb.RPop(ctx, keyDeadLetter).Result()
+		if err == redis.Nil {
+			return count, nil
+		} else if err != nil {
+			return count, err
+		}
+
+		var job RetryJob
+		if err := openJSON(item, &job); err != nil {
+			continue
+		}
+		job.Attempts = 0
+		data := sealJSON(job)
+		if err := rdb.ZAdd(ctx, keyRetryQueue, &redis.Z{Score: float64(time.Now().UnixMilli()), Member: data}).Err(); err != nil {
+			return count, err
+		}
+		count++
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"gopkg.in/yaml.v3"
+)
+
+/* ---------- 转发路由规则 ---------- */
+
+// RouteRule 单条路由规则，所有已配置的条件都满足才算命中
+type RouteRule struct {
+	Name       string   `yaml:"name"`
+	Sender     string   `yaml:"sender"`     // 来源号码正则
+	Keywords   []string `yaml:"keywords"`   // 短信内容包含任一关键词
+	HasCode    *bool    `yaml:"has_code"`   // 是否提取到验证码，不填表示不限
+	Forwarders []string `yaml:"forwarders"` // 命中后投递的通道标识
+	Continue   bool     `yaml:"continue"`   // 命中后是否继续匹配后续规则
+
+	senderRe *regexp.Regexp
+}
+
+// RoutingConfig 路由规则文件（YAML 或 JSON）
+type RoutingConfig struct {
+	Rules   []RouteRule `yaml:"rules"`
+	Default []string    `yaml:"default"` // 未命中任何规则时的通道，不填表示全部通道
+}
+
+// 当前生效的路由规则，为 nil 表示未配置；可在运行时整体替换（如 etcd 动态配置）
+var routing atomic.Pointer[RoutingConfig]
+
+// 加载 ROUTING_RULES_FILE 指定的规则文件，需在 initForwarders 之后调用
+func initRouting() {
+	path := getEnvWithDefault("ROUTING_RULES_FILE", "")
+	if path == "" {
+		return
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Fatalf("读取路由规则文件 %s 失败: %v", path, err)
+	}
+	cfg, err := parseRoutingConfig(data)
+	if err != nil {
+		log.Fatalf("解析路由规则文件 %s 失败: %v", path, err)
+	}
+	routing.Store(cfg)
+	log.Printf("已加载 %d 条转发路由规则: %s", len(cfg.Rules), path)
+}
+
+// 解析并校验路由规则（YAML 或 JSON）
+func parseRoutingConfig(data []byte) (*RoutingConfig, error) {
+	var cfg RoutingConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+	for i := range cfg.Rules {
+		rule := &cfg.Rules[i]
+		if rule.Name == "" {
+			rule.Name = "rule-" + strconv.Itoa(i+1)
+		}
+		if rule.Sender != "" {
+			var err error
+			if rule.senderRe, err = regexp.Compile(rule.Sender); err != nil {
+				return nil, fmt.Errorf("路由规则 %s 的 sender 正则无效: %w", rule.Name, err)
+			}
+		}
+		if err := validateRouteTargets(rule.Name, rule.Forwarders); err != nil {
+			return nil, err
+		}
+	}
+	if err := validateRouteTargets("default", cfg.Default); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// 校验规则中的通道标识：未注册返回错误，未启用给出警告
+func validateRouteTargets(rule string, names []string) error {
+	for _, name := range names {
+		if !forwarderRegistered(name) && forwardersByName[name] == nil {
+			return fmt.Errorf("路由规则 %s 引用了未知的转发通道: %s", rule, name)
+		}
+		if len(expandForwarderName(name)) == 0 {
+			log.Printf("路由规则 %s 引用的转发通道 %s 未启用，将被忽略", rule, name)
+		}
+	}
+	return nil
+}
+
+// 判断消息是否满足规则的所有条件
+func (r *RouteRule) match(msg ForwardMessage) bool {
+	if r.senderRe != nil && !r.senderRe.MatchString(msg.From) {
+		return false
+	}
+	if r.HasCode != nil && *r.HasCode != (msg.Code != "") {
+		return false
+	}
+	if len(r.Keywords) > 0 {
+		hit := false
+		for _, kw := range r.Keywords {
+			if strings.Contains(msg.RawContent, kw) {
+				hit = true
+				break
+			}
+		}
+		if !hit {
+			return false
+		}
+	}
+	return true
+}
+
+// routeMessage 返回消息应投递的通道；explicit 表示由规则命中选出
+func routeMessage(msg ForwardMessage) (targets []Forwarder, explicit bool) {
+	cfg := routing.Load()
+	if cfg == nil {
+		return forwarders, false
+	}
+
+	seen := make(map[string]bool)
+	add := func(names []string) {
+		for _, name := range names {
+			for _, id := range expandForwarderName(name) {
+				if f := forwardersByName[id]; f != nil && !seen[id] {
+					seen[id] = true
+					targets = append(targets, f)
+				}
+			}
+		}
+	}
+
+	for i := range cfg.Rules {
+		rule := &cfg.Rules[i]
+		if !rule.match(msg) {
+			continue
+		}
+		explicit = true
+		add(rule.Forwarders)
+		if !rule.Continue {
+			break
+		}
+	}
+	if explicit {
+		return targets, true
+	}
+
+	if len(cfg.Default) == 0 {
+		return forwarders, false
+	}
+	add(cfg.Default)
+	return targets, false
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+/* ---------- 历史搜索 ---------- */
+
+// 解析时间参数：毫秒时间戳、RFC3339 或本地时区的日期（2006-01-02），为空返回 0
+func parseTimeParam(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	if ms, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return ms, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.UnixMilli(), nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t.UnixMilli(), nil
+	}
+	return 0, fmt.Errorf("无法解析的时间: %s（支持毫秒时间戳、RFC3339、2006-01-02）", s)
+}
+
+// 从查询参数中读取 since / until，解析失败时已写入 400 响应并返回 false
+func bindTimeRange(c *gin.Context) (since, until int64, ok bool) {
+	var err error
+	if since, err = parseTimeParam(c.Query("since")); err != nil {
+		respondError(c, http.StatusBadRequest, ErrInvalidArgument, "since 参数错误", err)
+		return 0, 0, false
+	}
+	if until, err = parseTimeParam(c.Query("until")); err != nil {
+		respondError(c, http.StatusBadRequest, ErrInvalidArgument, "until 参数错误", err)
+		return 0, 0, false
+	}
+	return since, until, true
+}
+
+// GET /api/search?from=106&q=验证码&since=2024-06-01&until=2024-06-02&limit=50
+// 按来源号码前缀、内容关键词和接收时间范围搜索历史记录，需要启用 SQL 历史存储
+func searchSMS(c *gin.Context) {
+	s := sqlHistoryStore()
+	if s == nil {
+		respondError(c, http.StatusNotImplemented, ErrSQLRequired, "搜索需要启用 SQL 历史存储 (STORAGE_BACKEND=sqlite/postgres/mysql)", nil)
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 {
+		respondError(c, http.StatusBadRequest, ErrInvalidArgument, "limit 参数错误", nil)
+		return
+	}
+	if limit > 500 {
+		limit = 500
+	}
+	since, until, ok := bindTimeRange(c)
+	if !ok {
+		return
+	}
+
+	cursor, ok := bindCursor(c, cursorSearch)
+	if !ok {
+		return
+	}
+
+	filter := SearchFilter{Query: c.Query("q"), Since: since, Until: until}
+	if from := c.Query("from"); from != "" {
+		// 启用号码哈希时存储中没有原始号码，只能按完整号码精确匹配
+		filter.From, filter.Exact = phoneKey(from), phoneHashKey != nil
+	}
+	if cursor != nil {
+		id, err := strconv.ParseInt(cursor.Key, 10, 64)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, ErrInvalidCursor, "cursor 参数错误", errInvalidCursor)
+			return
+		}
+		filter.AfterAt, filter.AfterID = cursor.At, id
+	}
+
+	records, err := s.search(c.Request.Context(), filter, limit+1)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrInternal, "查询失败", err)
+		return
+	}
+	hasMore := len(records) > limit
+	if hasMore {
+		records = records[:limit]
+	}
+	pagination := gin.H{"limit": limit, "has_more": hasMore}
+	if hasMore {
+		pagination["next_until"] = records[len(records)-1].ReceivedAt
+		pagination["next_cursor"] = recordCursor(cursorSearch, records[len(records)-1])
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success", "data": records, "pagination": pagination})
+}
+
+// 未启用 SQL 历史存储，无法跨号码查询
+var errNoSQLHistory = errors.New("按来源号码前缀查询需要启用 SQL 历史存储 (STORAGE_BACKEND=sqlite/postgres/mysql)")
+
+// 在来源号码以 from 开头的全部号码中，查找接收时间不早于 since 的最新验证码；
+// 只返回仍是该号码最新记录的验证码，已被消费、删除或过期的不会返回。启用号码哈希时 from 需为完整号码
+func latestSMSBySender(ctx context.Context, from string, since int64) (*SMSRecord, error) {
+	s := sqlHistoryStore()
+	if s == nil {
+		return nil, errNoSQLHistory
+	}
+	filter := SearchFilter{From: phoneKey(from), Exact: phoneHashKey != nil, Since: since}
+	var found *SMSRecord
+	checked := make(map[string]bool)
+	err := s.eachMatch(ctx, filter, 50, searchMaxScan, func(rec SMSRecord) error {
+		if rec.Code == "" || checked[rec.From] {
+			return nil
+		}
+		checked[rec.From] = true
+		latest, err := store.GetLatest(ctx, rec.From)
+		if err != nil {
+			return err
+		}
+		if latest != nil && latest.ReceivedAt >= since {
+			found = latest
+			return errStopIteration
+		}
+		return nil
+	})
+	return found, err
+}
+
+// GET /api/find_by_code/:code?since=2024-06-01&limit=10
+// 按验证码反查最近收到该验证码的号码，用于排查验证码发到了哪个号码；包含已消费的历史记录。
+// 启用号码哈希时 from 为号码的哈希值
+func findByCode(c *gin.Context) {
+	code := c.Param("code")
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit <= 0 {
+		respondError(c, http.StatusBadRequest, ErrInvalidArgument, "limit 参数错误", nil)
+		return
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	since, err := parseTimeParam(c.Query("since"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrInvalidArgument, "since 参数错误", err)
+		return
+	}
+
+	records, err := store.FindByCode(c.Request.Context(), code, since, limit)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrInternal, "查询失败", err)
+		return
+	}
+	if len(records) == 0 {
+		respondError(c, http.StatusNotFound, ErrSMSNotFound, "未找到收到该验证码的记录", nil)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success", "data": records})
+}
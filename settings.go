@@ -0,0 +1,265 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+/* ---------- 运行时设置 ---------- */
+
+// 以下设置启动时从环境变量读取，可通过 /admin/settings 在运行时修改；
+// 修改只作用于当前实例，重启后恢复为环境变量的值
+
+// TTLSettings 验证码缓存有效期
+type TTLSettings struct {
+	Default time.Duration // 请求未指定 ttl 时使用（SMS_TTL）
+	Max     time.Duration // 请求可指定的最大值（SMS_TTL_MAX）
+}
+
+var ttlSettings atomic.Pointer[TTLSettings]
+
+// 当前生效的有效期设置，未初始化时使用默认值
+func currentTTLSettings() TTLSettings {
+	if s := ttlSettings.Load(); s != nil {
+		return *s
+	}
+	return TTLSettings{Default: 2 * time.Minute, Max: 30 * time.Minute}
+}
+
+// 设置有效期，Max 小于 Default 时调整为 Default
+func setTTLSettings(s TTLSettings) {
+	if s.Max < s.Default {
+		s.Max = s.Default
+	}
+	ttlSettings.Store(&s)
+}
+
+// 已在运行时停用的转发通道，按通道标识索引
+var disabledForwarders = struct {
+	sync.RWMutex
+	m map[string]bool
+}{m: make(map[string]bool)}
+
+func forwarderDisabled(name string) bool {
+	disabledForwarders.RLock()
+	defer disabledForwarders.RUnlock()
+	return disabledForwarders.m[name]
+}
+
+func setForwarderEnabled(name string, enabled bool) {
+	disabledForwarders.Lock()
+	defer disabledForwarders.Unlock()
+	if enabled {
+		delete(disabledForwarders.m, name)
+	} else {
+		disabledForwarders.m[name] = true
+	}
+}
+
+// 日志级别：debug 额外输出请求体等调试信息，info 为常规日志
+const (
+	logLevelDebug = "debug"
+	logLevelInfo  = "info"
+)
+
+var debugLogging atomic.Bool
+
+func currentLogLevel() string {
+	if debugLogging.Load() {
+		return logLevelDebug
+	}
+	return logLevelInfo
+}
+
+// 解析日志级别，返回是否为 debug
+func parseLogLevel(level string) (bool, error) {
+	switch strings.ToLower(level) {
+	case logLevelDebug:
+		return true, nil
+	case logLevelInfo:
+		return false, nil
+	}
+	return false, fmt.Errorf("未知的日志级别: %s（支持 debug / info）", level)
+}
+
+// 仅在 debug 级别输出的日志
+func debugf(format string, args ...any) {
+	if debugLogging.Load() {
+		log.Printf(format, args...)
+	}
+}
+
+// 从环境变量读取提取规则与日志级别；有效期设置由 loadStorageConfig 读取
+func initSettings() {
+	initExtraction()
+	debug, err := parseLogLevel(getEnvWithDefault("LOG_LEVEL", logLevelInfo))
+	if err != nil {
+		log.Fatalf("LOG_LEVEL 配置无效: %v", err)
+	}
+	debugLogging.Store(debug)
+}
+
+/* ---------- 运行时设置管理接口 ---------- */
+
+// settingsView /admin/settings 的响应
+type settingsView struct {
+	TTL struct {
+		Default string `json:"default"`
+		Max     string `json:"max"`
+	} `json:"ttl"`
+	Extraction struct {
+		Pattern  string           `json:"pattern"`  // 使用规则文件或 rules 时为空
+		Fallback string           `json:"fallback"` // 同上
+		Rules    []ExtractionRule `json:"rules"`    // 按顺序尝试的全部规则
+	} `json:"extraction"`
+	Forwarders []forwarderView `json:"forwarders"`
+	LogLevel   string          `json:"log_level"`
+}
+
+type forwarderView struct {
+	Name    string `json:"name"`    // 通道标识
+	Channel string `json:"channel"` // 通道显示名
+	Enabled bool   `json:"enabled"`
+}
+
+// settingsPatch PATCH /admin/settings 的请求体，未出现的字段保持不变
+type settingsPatch struct {
+	TTL *struct {
+		Default string `json:"default"`
+		Max     string `json:"max"`
+	} `json:"ttl"`
+	Extraction *struct {
+		Pattern  *string          `json:"pattern"`
+		Fallback *string          `json:"fallback"`
+		Rules    []ExtractionRule `json:"rules"` // 整体替换规则，不能与 pattern、fallback 同时使用
+	} `json:"extraction"`
+	Forwarders map[string]bool `json:"forwarders"` // 通道标识 → 是否启用
+	LogLevel   string          `json:"log_level"`
+}
+
+func currentSettingsView() settingsView {
+	var v settingsView
+	ttl := currentTTLSettings()
+	v.TTL.Default, v.TTL.Max = ttl.Default.String(), ttl.Max.String()
+	ex := currentExtraction()
+	v.Extraction.Pattern, v.Extraction.Fallback, v.Extraction.Rules = ex.Pattern, ex.Fallback, ex.Rules
+	names := make([]string, 0, len(forwardersByName))
+	for name := range forwardersByName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	v.Forwarders = make([]forwarderView, 0, len(names))
+	for _, name := range names {
+		v.Forwarders = append(v.Forwarders, forwarderView{
+			Name:    name,
+			Channel: forwardersByName[name].Name(),
+			Enabled: !forwarderDisabled(name),
+		})
+	}
+	v.LogLevel = currentLogLevel()
+	return v
+}
+
+// GET /admin/settings
+func getSettingsHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "success", "data": currentSettingsView()})
+}
+
+// PATCH /admin/settings
+// 先校验全部字段，任一项无效时不做任何修改
+func patchSettingsHandler(c *gin.Context) {
+	var req settingsPatch
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, ErrInvalidArgument, "参数错误", err)
+		return
+	}
+	invalid := func(err error) {
+		respondError(c, http.StatusBadRequest, ErrInvalidArgument, "设置无效", err)
+	}
+
+	ttl := currentTTLSettings()
+	if req.TTL != nil {
+		for _, f := range []struct {
+			name  string
+			value string
+			dst   *time.Duration
+		}{{"ttl.default", req.TTL.Default, &ttl.Default}, {"ttl.max", req.TTL.Max, &ttl.Max}} {
+			if f.value == "" {
+				continue
+			}
+			d, err := time.ParseDuration(f.value)
+			if err != nil || d <= 0 {
+				invalid(fmt.Errorf("%s 不是合法时长: %s", f.name, f.value))
+				return
+			}
+			*f.dst = d
+		}
+	}
+
+	var extraction *ExtractionSettings
+	if req.Extraction != nil {
+		var err error
+		switch ex := req.Extraction; {
+		case ex.Rules != nil && (ex.Pattern != nil || ex.Fallback != nil):
+			err = fmt.Errorf("extraction.rules 不能与 pattern、fallback 同时修改")
+		case ex.Rules != nil:
+			extraction, err = newExtractionRules(ex.Rules)
+		default:
+			// 当前规则来自规则文件时没有 pattern / fallback，未修改的一项使用内置规则
+			cur := currentExtraction()
+			pattern, fallback := cur.Pattern, cur.Fallback
+			if pattern == "" {
+				pattern, fallback = defaultCodePattern, defaultCodeFallback
+			}
+			if ex.Pattern != nil {
+				pattern = *ex.Pattern
+			}
+			if ex.Fallback != nil {
+				fallback = *ex.Fallback
+			}
+			extraction, err = newExtractionSettings(pattern, fallback)
+		}
+		if err != nil {
+			invalid(err)
+			return
+		}
+	}
+
+	for name := range req.Forwarders {
+		if forwardersByName[name] == nil {
+			invalid(fmt.Errorf("转发通道未启用或不存在: %s", name))
+			return
+		}
+	}
+	debug := debugLogging.Load()
+	if req.LogLevel != "" {
+		var err error
+		if debug, err = parseLogLevel(req.LogLevel); err != nil {
+			invalid(err)
+			return
+		}
+	}
+
+	if req.TTL != nil {
+		setTTLSettings(ttl)
+	}
+	if extraction != nil {
+		extractionSettings.Store(extraction)
+	}
+	for name, enabled := range req.Forwarders {
+		setForwarderEnabled(name, enabled)
+	}
+	debugLogging.Store(debug)
+
+	v := currentSettingsView()
+	log.Printf("运行时设置已更新: ttl=%s/%s 日志级别=%s", v.TTL.Default, v.TTL.Max, v.LogLevel)
+	c.JSON(http.StatusOK, gin.H{"status": "success", "data": v})
+}
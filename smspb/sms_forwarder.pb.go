@@ -0,0 +1,605 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.34.2
+// 	protoc        (unknown)
+// source: sms_forwarder.proto
+
+// 短信转发服务的 gRPC 接口，与 REST 接口功能一致
+
+package smspb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type ReceiveSMSRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	From       string `protobuf:"bytes,1,opt,name=from,proto3" json:"from,omitempty"`
+	Content    string `protobuf:"bytes,2,opt,name=content,proto3" json:"content,omitempty"`
+	ReceivedAt int64  `protobuf:"varint,3,opt,name=received_at,json=receivedAt,proto3" json:"received_at,omitempty"` // 毫秒时间戳
+	Ttl        int32  `protobuf:"varint,4,opt,name=ttl,proto3" json:"ttl,omitempty"`                                 // 可选：缓存有效期（秒），不超过 SMS_TTL_MAX
+}
+
+func (x *ReceiveSMSRequest) Reset() {
+	*x = ReceiveSMSRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_sms_forwarder_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ReceiveSMSRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReceiveSMSRequest) ProtoMessage() {}
+
+func (x *ReceiveSMSRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_sms_forwarder_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReceiveSMSRequest.ProtoReflect.Descriptor instead.
+func (*ReceiveSMSRequest) Descriptor() ([]byte, []int) {
+	return file_sms_forwarder_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ReceiveSMSRequest) GetFrom() string {
+	if x != nil {
+		return x.From
+	}
+	return ""
+}
+
+func (x *ReceiveSMSRequest) GetContent() string {
+	if x != nil {
+		return x.Content
+	}
+	return ""
+}
+
+func (x *ReceiveSMSRequest) GetReceivedAt() int64 {
+	if x != nil {
+		return x.ReceivedAt
+	}
+	return 0
+}
+
+func (x *ReceiveSMSRequest) GetTtl() int32 {
+	if x != nil {
+		return x.Ttl
+	}
+	return 0
+}
+
+type ReceiveSMSResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Code     string `protobuf:"bytes,1,opt,name=code,proto3" json:"code,omitempty"` // 启用接收队列时为空
+	CacheKey string `protobuf:"bytes,2,opt,name=cache_key,json=cacheKey,proto3" json:"cache_key,omitempty"`
+	StreamId string `protobuf:"bytes,3,opt,name=stream_id,json=streamId,proto3" json:"stream_id,omitempty"` // 启用接收队列时为队列中的消息 ID
+	Accepted bool   `protobuf:"varint,4,opt,name=accepted,proto3" json:"accepted,omitempty"`                // 为 true 表示已写入接收队列，稍后异步处理
+}
+
+func (x *ReceiveSMSResponse) Reset() {
+	*x = ReceiveSMSResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_sms_forwarder_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ReceiveSMSResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReceiveSMSResponse) ProtoMessage() {}
+
+func (x *ReceiveSMSResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_sms_forwarder_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReceiveSMSResponse.ProtoReflect.Descriptor instead.
+func (*ReceiveSMSResponse) Descriptor() ([]byte, []int) {
+	return file_sms_forwarder_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ReceiveSMSResponse) GetCode() string {
+	if x != nil {
+		return x.Code
+	}
+	return ""
+}
+
+func (x *ReceiveSMSResponse) GetCacheKey() string {
+	if x != nil {
+		return x.CacheKey
+	}
+	return ""
+}
+
+func (x *ReceiveSMSResponse) GetStreamId() string {
+	if x != nil {
+		return x.StreamId
+	}
+	return ""
+}
+
+func (x *ReceiveSMSResponse) GetAccepted() bool {
+	if x != nil {
+		return x.Accepted
+	}
+	return false
+}
+
+type GetLatestSMSRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Phone string `protobuf:"bytes,1,opt,name=phone,proto3" json:"phone,omitempty"`
+}
+
+func (x *GetLatestSMSRequest) Reset() {
+	*x = GetLatestSMSRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_sms_forwarder_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetLatestSMSRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetLatestSMSRequest) ProtoMessage() {}
+
+func (x *GetLatestSMSRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_sms_forwarder_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetLatestSMSRequest.ProtoReflect.Descriptor instead.
+func (*GetLatestSMSRequest) Descriptor() ([]byte, []int) {
+	return file_sms_forwarder_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GetLatestSMSRequest) GetPhone() string {
+	if x != nil {
+		return x.Phone
+	}
+	return ""
+}
+
+type WaitSMSRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Phone          string `protobuf:"bytes,1,opt,name=phone,proto3" json:"phone,omitempty"`
+	TimeoutSeconds int32  `protobuf:"varint,2,opt,name=timeout_seconds,json=timeoutSeconds,proto3" json:"timeout_seconds,omitempty"` // 默认 30，不超过 WAIT_SMS_MAX_TIMEOUT
+	After          int64  `protobuf:"varint,3,opt,name=after,proto3" json:"after,omitempty"`                                         // 只返回接收时间晚于该时间的验证码，默认（0）为请求时间
+}
+
+func (x *WaitSMSRequest) Reset() {
+	*x = WaitSMSRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_sms_forwarder_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *WaitSMSRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WaitSMSRequest) ProtoMessage() {}
+
+func (x *WaitSMSRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_sms_forwarder_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WaitSMSRequest.ProtoReflect.Descriptor instead.
+func (*WaitSMSRequest) Descriptor() ([]byte, []int) {
+	return file_sms_forwarder_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *WaitSMSRequest) GetPhone() string {
+	if x != nil {
+		return x.Phone
+	}
+	return ""
+}
+
+func (x *WaitSMSRequest) GetTimeoutSeconds() int32 {
+	if x != nil {
+		return x.TimeoutSeconds
+	}
+	return 0
+}
+
+func (x *WaitSMSRequest) GetAfter() int64 {
+	if x != nil {
+		return x.After
+	}
+	return 0
+}
+
+type StreamSMSRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Phone string `protobuf:"bytes,1,opt,name=phone,proto3" json:"phone,omitempty"`
+}
+
+func (x *StreamSMSRequest) Reset() {
+	*x = StreamSMSRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_sms_forwarder_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *StreamSMSRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamSMSRequest) ProtoMessage() {}
+
+func (x *StreamSMSRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_sms_forwarder_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamSMSRequest.ProtoReflect.Descriptor instead.
+func (*StreamSMSRequest) Descriptor() ([]byte, []int) {
+	return file_sms_forwarder_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *StreamSMSRequest) GetPhone() string {
+	if x != nil {
+		return x.Phone
+	}
+	return ""
+}
+
+type SMSRecord struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	From       string `protobuf:"bytes,1,opt,name=from,proto3" json:"from,omitempty"`
+	Code       string `protobuf:"bytes,2,opt,name=code,proto3" json:"code,omitempty"`                               // 不含验证码的短信为空
+	RawContent string `protobuf:"bytes,3,opt,name=raw_content,json=rawContent,proto3" json:"raw_content,omitempty"` // Redis 只缓存验证码，查询最新记录时可能为空
+	ReceivedAt int64  `protobuf:"varint,4,opt,name=received_at,json=receivedAt,proto3" json:"received_at,omitempty"`
+	CacheKey   string `protobuf:"bytes,5,opt,name=cache_key,json=cacheKey,proto3" json:"cache_key,omitempty"`
+}
+
+func (x *SMSRecord) Reset() {
+	*x = SMSRecord{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_sms_forwarder_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SMSRecord) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SMSRecord) ProtoMessage() {}
+
+func (x *SMSRecord) ProtoReflect() protoreflect.Message {
+	mi := &file_sms_forwarder_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SMSRecord.ProtoReflect.Descriptor instead.
+func (*SMSRecord) Descriptor() ([]byte, []int) {
+	return file_sms_forwarder_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *SMSRecord) GetFrom() string {
+	if x != nil {
+		return x.From
+	}
+	return ""
+}
+
+func (x *SMSRecord) GetCode() string {
+	if x != nil {
+		return x.Code
+	}
+	return ""
+}
+
+func (x *SMSRecord) GetRawContent() string {
+	if x != nil {
+		return x.RawContent
+	}
+	return ""
+}
+
+func (x *SMSRecord) GetReceivedAt() int64 {
+	if x != nil {
+		return x.ReceivedAt
+	}
+	return 0
+}
+
+func (x *SMSRecord) GetCacheKey() string {
+	if x != nil {
+		return x.CacheKey
+	}
+	return ""
+}
+
+var File_sms_forwarder_proto protoreflect.FileDescriptor
+
+var file_sms_forwarder_proto_rawDesc = []byte{
+	0x0a, 0x13, 0x73, 0x6d, 0x73, 0x5f, 0x66, 0x6f, 0x72, 0x77, 0x61, 0x72, 0x64, 0x65, 0x72, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x0f, 0x73, 0x6d, 0x73, 0x66, 0x6f, 0x72, 0x77, 0x61, 0x72,
+	0x64, 0x65, 0x72, 0x2e, 0x76, 0x31, 0x22, 0x74, 0x0a, 0x11, 0x52, 0x65, 0x63, 0x65, 0x69, 0x76,
+	0x65, 0x53, 0x4d, 0x53, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x66,
+	0x72, 0x6f, 0x6d, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x66, 0x72, 0x6f, 0x6d, 0x12,
+	0x18, 0x0a, 0x07, 0x63, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x07, 0x63, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x12, 0x1f, 0x0a, 0x0b, 0x72, 0x65, 0x63,
+	0x65, 0x69, 0x76, 0x65, 0x64, 0x5f, 0x61, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0a,
+	0x72, 0x65, 0x63, 0x65, 0x69, 0x76, 0x65, 0x64, 0x41, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x74, 0x74,
+	0x6c, 0x18, 0x04, 0x20, 0x01, 0x28, 0x05, 0x52, 0x03, 0x74, 0x74, 0x6c, 0x22, 0x7e, 0x0a, 0x12,
+	0x52, 0x65, 0x63, 0x65, 0x69, 0x76, 0x65, 0x53, 0x4d, 0x53, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x12, 0x1b, 0x0a, 0x09, 0x63, 0x61, 0x63, 0x68, 0x65, 0x5f,
+	0x6b, 0x65, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x63, 0x61, 0x63, 0x68, 0x65,
+	0x4b, 0x65, 0x79, 0x12, 0x1b, 0x0a, 0x09, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x5f, 0x69, 0x64,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x49, 0x64,
+	0x12, 0x1a, 0x0a, 0x08, 0x61, 0x63, 0x63, 0x65, 0x70, 0x74, 0x65, 0x64, 0x18, 0x04, 0x20, 0x01,
+	0x28, 0x08, 0x52, 0x08, 0x61, 0x63, 0x63, 0x65, 0x70, 0x74, 0x65, 0x64, 0x22, 0x2b, 0x0a, 0x13,
+	0x47, 0x65, 0x74, 0x4c, 0x61, 0x74, 0x65, 0x73, 0x74, 0x53, 0x4d, 0x53, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x70, 0x68, 0x6f, 0x6e, 0x65, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x05, 0x70, 0x68, 0x6f, 0x6e, 0x65, 0x22, 0x65, 0x0a, 0x0e, 0x57, 0x61, 0x69,
+	0x74, 0x53, 0x4d, 0x53, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x70,
+	0x68, 0x6f, 0x6e, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x70, 0x68, 0x6f, 0x6e,
+	0x65, 0x12, 0x27, 0x0a, 0x0f, 0x74, 0x69, 0x6d, 0x65, 0x6f, 0x75, 0x74, 0x5f, 0x73, 0x65, 0x63,
+	0x6f, 0x6e, 0x64, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0e, 0x74, 0x69, 0x6d, 0x65,
+	0x6f, 0x75, 0x74, 0x53, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x61, 0x66,
+	0x74, 0x65, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x05, 0x61, 0x66, 0x74, 0x65, 0x72,
+	0x22, 0x28, 0x0a, 0x10, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x53, 0x4d, 0x53, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x70, 0x68, 0x6f, 0x6e, 0x65, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x05, 0x70, 0x68, 0x6f, 0x6e, 0x65, 0x22, 0x92, 0x01, 0x0a, 0x09, 0x53,
+	0x4d, 0x53, 0x52, 0x65, 0x63, 0x6f, 0x72, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x66, 0x72, 0x6f, 0x6d,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x66, 0x72, 0x6f, 0x6d, 0x12, 0x12, 0x0a, 0x04,
+	0x63, 0x6f, 0x64, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x63, 0x6f, 0x64, 0x65,
+	0x12, 0x1f, 0x0a, 0x0b, 0x72, 0x61, 0x77, 0x5f, 0x63, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x72, 0x61, 0x77, 0x43, 0x6f, 0x6e, 0x74, 0x65, 0x6e,
+	0x74, 0x12, 0x1f, 0x0a, 0x0b, 0x72, 0x65, 0x63, 0x65, 0x69, 0x76, 0x65, 0x64, 0x5f, 0x61, 0x74,
+	0x18, 0x04, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0a, 0x72, 0x65, 0x63, 0x65, 0x69, 0x76, 0x65, 0x64,
+	0x41, 0x74, 0x12, 0x1b, 0x0a, 0x09, 0x63, 0x61, 0x63, 0x68, 0x65, 0x5f, 0x6b, 0x65, 0x79, 0x18,
+	0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x63, 0x61, 0x63, 0x68, 0x65, 0x4b, 0x65, 0x79, 0x32,
+	0xcd, 0x02, 0x0a, 0x0c, 0x53, 0x4d, 0x53, 0x46, 0x6f, 0x72, 0x77, 0x61, 0x72, 0x64, 0x65, 0x72,
+	0x12, 0x55, 0x0a, 0x0a, 0x52, 0x65, 0x63, 0x65, 0x69, 0x76, 0x65, 0x53, 0x4d, 0x53, 0x12, 0x22,
+	0x2e, 0x73, 0x6d, 0x73, 0x66, 0x6f, 0x72, 0x77, 0x61, 0x72, 0x64, 0x65, 0x72, 0x2e, 0x76, 0x31,
+	0x2e, 0x52, 0x65, 0x63, 0x65, 0x69, 0x76, 0x65, 0x53, 0x4d, 0x53, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x1a, 0x23, 0x2e, 0x73, 0x6d, 0x73, 0x66, 0x6f, 0x72, 0x77, 0x61, 0x72, 0x64, 0x65,
+	0x72, 0x2e, 0x76, 0x31, 0x2e, 0x52, 0x65, 0x63, 0x65, 0x69, 0x76, 0x65, 0x53, 0x4d, 0x53, 0x52,
+	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x50, 0x0a, 0x0c, 0x47, 0x65, 0x74, 0x4c, 0x61,
+	0x74, 0x65, 0x73, 0x74, 0x53, 0x4d, 0x53, 0x12, 0x24, 0x2e, 0x73, 0x6d, 0x73, 0x66, 0x6f, 0x72,
+	0x77, 0x61, 0x72, 0x64, 0x65, 0x72, 0x2e, 0x76, 0x31, 0x2e, 0x47, 0x65, 0x74, 0x4c, 0x61, 0x74,
+	0x65, 0x73, 0x74, 0x53, 0x4d, 0x53, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1a, 0x2e,
+	0x73, 0x6d, 0x73, 0x66, 0x6f, 0x72, 0x77, 0x61, 0x72, 0x64, 0x65, 0x72, 0x2e, 0x76, 0x31, 0x2e,
+	0x53, 0x4d, 0x53, 0x52, 0x65, 0x63, 0x6f, 0x72, 0x64, 0x12, 0x46, 0x0a, 0x07, 0x57, 0x61, 0x69,
+	0x74, 0x53, 0x4d, 0x53, 0x12, 0x1f, 0x2e, 0x73, 0x6d, 0x73, 0x66, 0x6f, 0x72, 0x77, 0x61, 0x72,
+	0x64, 0x65, 0x72, 0x2e, 0x76, 0x31, 0x2e, 0x57, 0x61, 0x69, 0x74, 0x53, 0x4d, 0x53, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1a, 0x2e, 0x73, 0x6d, 0x73, 0x66, 0x6f, 0x72, 0x77, 0x61,
+	0x72, 0x64, 0x65, 0x72, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x4d, 0x53, 0x52, 0x65, 0x63, 0x6f, 0x72,
+	0x64, 0x12, 0x4c, 0x0a, 0x09, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x53, 0x4d, 0x53, 0x12, 0x21,
+	0x2e, 0x73, 0x6d, 0x73, 0x66, 0x6f, 0x72, 0x77, 0x61, 0x72, 0x64, 0x65, 0x72, 0x2e, 0x76, 0x31,
+	0x2e, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x53, 0x4d, 0x53, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x1a, 0x1a, 0x2e, 0x73, 0x6d, 0x73, 0x66, 0x6f, 0x72, 0x77, 0x61, 0x72, 0x64, 0x65, 0x72,
+	0x2e, 0x76, 0x31, 0x2e, 0x53, 0x4d, 0x53, 0x52, 0x65, 0x63, 0x6f, 0x72, 0x64, 0x30, 0x01, 0x42,
+	0x1b, 0x5a, 0x19, 0x73, 0x6d, 0x73, 0x2d, 0x66, 0x6f, 0x72, 0x77, 0x61, 0x72, 0x64, 0x65, 0x72,
+	0x2f, 0x73, 0x6d, 0x73, 0x70, 0x62, 0x3b, 0x73, 0x6d, 0x73, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_sms_forwarder_proto_rawDescOnce sync.Once
+	file_sms_forwarder_proto_rawDescData = file_sms_forwarder_proto_rawDesc
+)
+
+func file_sms_forwarder_proto_rawDescGZIP() []byte {
+	file_sms_forwarder_proto_rawDescOnce.Do(func() {
+		file_sms_forwarder_proto_rawDescData = protoimpl.X.CompressGZIP(file_sms_forwarder_proto_rawDescData)
+	})
+	return file_sms_forwarder_proto_rawDescData
+}
+
+var file_sms_forwarder_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_sms_forwarder_proto_goTypes = []any{
+	(*ReceiveSMSRequest)(nil),   // 0: smsforwarder.v1.ReceiveSMSRequest
+	(*ReceiveSMSResponse)(nil),  // 1: smsforwarder.v1.ReceiveSMSResponse
+	(*GetLatestSMSRequest)(nil), // 2: smsforwarder.v1.GetLatestSMSRequest
+	(*WaitSMSRequest)(nil),      // 3: smsforwarder.v1.WaitSMSRequest
+	(*StreamSMSRequest)(nil),    // 4: smsforwarder.v1.StreamSMSRequest
+	(*SMSRecord)(nil),           // 5: smsforwarder.v1.SMSRecord
+}
+var file_sms_forwarder_proto_depIdxs = []int32{
+	0, // 0: smsforwarder.v1.SMSForwarder.ReceiveSMS:input_type -> smsforwarder.v1.ReceiveSMSRequest
+	2, // 1: smsforwarder.v1.SMSForwarder.GetLatestSMS:input_type -> smsforwarder.v1.GetLatestSMSRequest
+	3, // 2: smsforwarder.v1.SMSForwarder.WaitSMS:input_type -> smsforwarder.v1.WaitSMSRequest
+	4, // 3: smsforwarder.v1.SMSForwarder.StreamSMS:input_type -> smsforwarder.v1.StreamSMSRequest
+	1, // 4: smsforwarder.v1.SMSForwarder.ReceiveSMS:output_type -> smsforwarder.v1.ReceiveSMSResponse
+	5, // 5: smsforwarder.v1.SMSForwarder.GetLatestSMS:output_type -> smsforwarder.v1.SMSRecord
+	5, // 6: smsforwarder.v1.SMSForwarder.WaitSMS:output_type -> smsforwarder.v1.SMSRecord
+	5, // 7: smsforwarder.v1.SMSForwarder.StreamSMS:output_type -> smsforwarder.v1.SMSRecord
+	4, // [4:8] is the sub-list for method output_type
+	0, // [0:4] is the sub-list for method input_type
+	0, // [0:0] is the sub-list for extension type_name
+	0, // [0:0] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_sms_forwarder_proto_init() }
+func file_sms_forwarder_proto_init() {
+	if File_sms_forwarder_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_sms_forwarder_proto_msgTypes[0].Exporter = func(v any, i int) any {
+			switch v := v.(*ReceiveSMSRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_sms_forwarder_proto_msgTypes[1].Exporter = func(v any, i int) any {
+			switch v := v.(*ReceiveSMSResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_sms_forwarder_proto_msgTypes[2].Exporter = func(v any, i int) any {
+			switch v := v.(*GetLatestSMSRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_sms_forwarder_proto_msgTypes[3].Exporter = func(v any, i int) any {
+			switch v := v.(*WaitSMSRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_sms_forwarder_proto_msgTypes[4].Exporter = func(v any, i int) any {
+			switch v := v.(*StreamSMSRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_sms_forwarder_proto_msgTypes[5].Exporter = func(v any, i int) any {
+			switch v := v.(*SMSRecord); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_sms_forwarder_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   6,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_sms_forwarder_proto_goTypes,
+		DependencyIndexes: file_sms_forwarder_proto_depIdxs,
+		MessageInfos:      file_sms_forwarder_proto_msgTypes,
+	}.Build()
+	File_sms_forwarder_proto = out.File
+	file_sms_forwarder_proto_rawDesc = nil
+	file_sms_forwarder_proto_goTypes = nil
+	file_sms_forwarder_proto_depIdxs = nil
+}
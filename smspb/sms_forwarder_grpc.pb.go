@@ -0,0 +1,258 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: sms_forwarder.proto
+
+// 短信转发服务的 gRPC 接口，与 REST 接口功能一致
+
+package smspb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	SMSForwarder_ReceiveSMS_FullMethodName   = "/smsforwarder.v1.SMSForwarder/ReceiveSMS"
+	SMSForwarder_GetLatestSMS_FullMethodName = "/smsforwarder.v1.SMSForwarder/GetLatestSMS"
+	SMSForwarder_WaitSMS_FullMethodName      = "/smsforwarder.v1.SMSForwarder/WaitSMS"
+	SMSForwarder_StreamSMS_FullMethodName    = "/smsforwarder.v1.SMSForwarder/StreamSMS"
+)
+
+// SMSForwarderClient is the client API for SMSForwarder service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type SMSForwarderClient interface {
+	// 接收短信，等同于 POST /api/receive_sms
+	ReceiveSMS(ctx context.Context, in *ReceiveSMSRequest, opts ...grpc.CallOption) (*ReceiveSMSResponse, error)
+	// 查询最新验证码，等同于 GET /api/latest_sms/:phone；不存在时返回 NOT_FOUND
+	GetLatestSMS(ctx context.Context, in *GetLatestSMSRequest, opts ...grpc.CallOption) (*SMSRecord, error)
+	// 等待新验证码，等同于 GET /api/wait_sms/:phone；超时返回 NOT_FOUND
+	WaitSMS(ctx context.Context, in *WaitSMSRequest, opts ...grpc.CallOption) (*SMSRecord, error)
+	// 推送号码此后收到的每条短信，等同于 GET /api/stream/:phone
+	StreamSMS(ctx context.Context, in *StreamSMSRequest, opts ...grpc.CallOption) (SMSForwarder_StreamSMSClient, error)
+}
+
+type sMSForwarderClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewSMSForwarderClient(cc grpc.ClientConnInterface) SMSForwarderClient {
+	return &sMSForwarderClient{cc}
+}
+
+func (c *sMSForwarderClient) ReceiveSMS(ctx context.Context, in *ReceiveSMSRequest, opts ...grpc.CallOption) (*ReceiveSMSResponse, error) {
+	out := new(ReceiveSMSResponse)
+	err := c.cc.Invoke(ctx, SMSForwarder_ReceiveSMS_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sMSForwarderClient) GetLatestSMS(ctx context.Context, in *GetLatestSMSRequest, opts ...grpc.CallOption) (*SMSRecord, error) {
+	out := new(SMSRecord)
+	err := c.cc.Invoke(ctx, SMSForwarder_GetLatestSMS_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sMSForwarderClient) WaitSMS(ctx context.Context, in *WaitSMSRequest, opts ...grpc.CallOption) (*SMSRecord, error) {
+	out := new(SMSRecord)
+	err := c.cc.Invoke(ctx, SMSForwarder_WaitSMS_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sMSForwarderClient) StreamSMS(ctx context.Context, in *StreamSMSRequest, opts ...grpc.CallOption) (SMSForwarder_StreamSMSClient, error) {
+	stream, err := c.cc.NewStream(ctx, &SMSForwarder_ServiceDesc.Streams[0], SMSForwarder_StreamSMS_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &sMSForwarderStreamSMSClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type SMSForwarder_StreamSMSClient interface {
+	Recv() (*SMSRecord, error)
+	grpc.ClientStream
+}
+
+type sMSForwarderStreamSMSClient struct {
+	grpc.ClientStream
+}
+
+func (x *sMSForwarderStreamSMSClient) Recv() (*SMSRecord, error) {
+	m := new(SMSRecord)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// SMSForwarderServer is the server API for SMSForwarder service.
+// All implementations must embed UnimplementedSMSForwarderServer
+// for forward compatibility
+type SMSForwarderServer interface {
+	// 接收短信，等同于 POST /api/receive_sms
+	ReceiveSMS(context.Context, *ReceiveSMSRequest) (*ReceiveSMSResponse, error)
+	// 查询最新验证码，等同于 GET /api/latest_sms/:phone；不存在时返回 NOT_FOUND
+	GetLatestSMS(context.Context, *GetLatestSMSRequest) (*SMSRecord, error)
+	// 等待新验证码，等同于 GET /api/wait_sms/:phone；超时返回 NOT_FOUND
+	WaitSMS(context.Context, *WaitSMSRequest) (*SMSRecord, error)
+	// 推送号码此后收到的每条短信，等同于 GET /api/stream/:phone
+	StreamSMS(*StreamSMSRequest, SMSForwarder_StreamSMSServer) error
+	mustEmbedUnimplementedSMSForwarderServer()
+}
+
+// UnimplementedSMSForwarderServer must be embedded to have forward compatible implementations.
+type UnimplementedSMSForwarderServer struct {
+}
+
+func (UnimplementedSMSForwarderServer) ReceiveSMS(context.Context, *ReceiveSMSRequest) (*ReceiveSMSResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReceiveSMS not implemented")
+}
+func (UnimplementedSMSForwarderServer) GetLatestSMS(context.Context, *GetLatestSMSRequest) (*SMSRecord, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetLatestSMS not implemented")
+}
+func (UnimplementedSMSForwarderServer) WaitSMS(context.Context, *WaitSMSRequest) (*SMSRecord, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method WaitSMS not implemented")
+}
+func (UnimplementedSMSForwarderServer) StreamSMS(*StreamSMSRequest, SMSForwarder_StreamSMSServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamSMS not implemented")
+}
+func (UnimplementedSMSForwarderServer) mustEmbedUnimplementedSMSForwarderServer() {}
+
+// UnsafeSMSForwarderServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to SMSForwarderServer will
+// result in compilation errors.
+type UnsafeSMSForwarderServer interface {
+	mustEmbedUnimplementedSMSForwarderServer()
+}
+
+func RegisterSMSForwarderServer(s grpc.ServiceRegistrar, srv SMSForwarderServer) {
+	s.RegisterService(&SMSForwarder_ServiceDesc, srv)
+}
+
+func _SMSForwarder_ReceiveSMS_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReceiveSMSRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SMSForwarderServer).ReceiveSMS(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SMSForwarder_ReceiveSMS_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SMSForwarderServer).ReceiveSMS(ctx, req.(*ReceiveSMSRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SMSForwarder_GetLatestSMS_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetLatestSMSRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SMSForwarderServer).GetLatestSMS(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SMSForwarder_GetLatestSMS_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SMSForwarderServer).GetLatestSMS(ctx, req.(*GetLatestSMSRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SMSForwarder_WaitSMS_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WaitSMSRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SMSForwarderServer).WaitSMS(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SMSForwarder_WaitSMS_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SMSForwarderServer).WaitSMS(ctx, req.(*WaitSMSRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SMSForwarder_StreamSMS_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamSMSRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(SMSForwarderServer).StreamSMS(m, &sMSForwarderStreamSMSServer{stream})
+}
+
+type SMSForwarder_StreamSMSServer interface {
+	Send(*SMSRecord) error
+	grpc.ServerStream
+}
+
+type sMSForwarderStreamSMSServer struct {
+	grpc.ServerStream
+}
+
+func (x *sMSForwarderStreamSMSServer) Send(m *SMSRecord) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// SMSForwarder_ServiceDesc is the grpc.ServiceDesc for SMSForwarder service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var SMSForwarder_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "smsforwarder.v1.SMSForwarder",
+	HandlerType: (*SMSForwarderServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ReceiveSMS",
+			Handler:    _SMSForwarder_ReceiveSMS_Handler,
+		},
+		{
+			MethodName: "GetLatestSMS",
+			Handler:    _SMSForwarder_GetLatestSMS_Handler,
+		},
+		{
+			MethodName: "WaitSMS",
+			Handler:    _SMSForwarder_WaitSMS_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamSMS",
+			Handler:       _SMSForwarder_StreamSMS_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "sms_forwarder.proto",
+}
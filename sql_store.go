@@ -0,0 +1,545 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+/* ---------- SQL 存储 ---------- */
+
+// sqlDialect 各数据库的差异部分；SQL 统一用 ? 占位，按 bindvar 改写
+type sqlDialect struct {
+	name         string
+	migrations   []string // 按顺序执行，只能追加不能修改已发布的条目
+	bindvar      func(n int) string
+	upsertLatest string // 写入号码最新记录，须保证乱序写入时不会用旧记录覆盖新记录
+	returningID  bool   // 驱动不支持 LastInsertId，需通过 RETURNING id 取自增主键
+}
+
+// SQLStore 基于 database/sql 的通用存储，SQLite、PostgreSQL、MySQL 共用
+type SQLStore struct {
+	db      *sql.DB
+	dialect *sqlDialect
+}
+
+// 所有方言共用的写入 / 查询语句
+const (
+	sqlInsertHistory = `INSERT INTO sms_history (phone, code, raw_content, received_at, cache_key, created_at, expires_at, retain_until, device)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	sqlSelectHistory = `SELECT h.id, h.phone, h.code, h.raw_content, h.received_at, h.cache_key, h.created_at, h.expires_at, h.device
+		FROM sms_history h`
+	// 只保留号码最近 N 条（最新记录指向的行除外）；子查询多套一层派生表，兼容 MySQL 不支持 IN (… LIMIT) 的限制
+	sqlTrimHistory = `DELETE FROM sms_history WHERE phone = ?
+		AND id NOT IN (SELECT k.id FROM (
+			SELECT id FROM sms_history WHERE phone = ? ORDER BY received_at DESC, id DESC LIMIT ?
+		) k)
+		AND id NOT IN (SELECT history_id FROM sms_latest WHERE phone = ?)`
+)
+
+// 将 ? 占位符改写为方言的写法（如 PostgreSQL 的 $1）
+func (s *SQLStore) rebind(query string) string {
+	if s.dialect.bindvar == nil {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString(s.dialect.bindvar(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// 执行尚未应用的迁移，返回当前 schema 版本
+func (s *SQLStore) migrate() (int, error) {
+	if _, err := s.db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		applied_at BIGINT  NOT NULL
+	)`); err != nil {
+		return 0, err
+	}
+
+	var current int
+	if err := s.db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return 0, err
+	}
+
+	for i := current; i < len(s.dialect.migrations); i++ {
+		version := i + 1
+		tx, err := s.db.Begin()
+		if err != nil {
+			return current, err
+		}
+		if _, err := tx.Exec(s.dialect.migrations[i]); err != nil {
+			tx.Rollback()
+			return current, fmt.Errorf("迁移 %d 执行失败: %w", version, err)
+		}
+		if _, err := tx.Exec(s.rebind(`INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`),
+			version, time.Now().UnixMilli()); err != nil {
+			tx.Rollback()
+			return current, err
+		}
+		if err := tx.Commit(); err != nil {
+			return current, err
+		}
+		current = version
+	}
+	return current, nil
+}
+
+// 插入一条历史记录，返回自增主键；args 对应 sqlInsertHistory 的各列
+func (s *SQLStore) insertHistory(ctx context.Context, tx *sql.Tx, args []any) (int64, error) {
+	var (
+		id  int64
+		err error
+	)
+	if s.dialect.returningID {
+		err = tx.QueryRowContext(ctx, s.rebind(sqlInsertHistory+" RETURNING id"), args...).Scan(&id)
+	} else {
+		var res sql.Result
+		if res, err = tx.ExecContext(ctx, s.rebind(sqlInsertHistory), args...); err == nil {
+			id, err = res.LastInsertId()
+		}
+	}
+	if err != nil {
+		return 0, fmt.Errorf("写入历史失败: %w", err)
+	}
+	return id, nil
+}
+
+// SaveSMS 在同一事务中写入历史记录并更新号码的最新记录
+func (s *SQLStore) SaveSMS(ctx context.Context, rec SMSRecord) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	var expiresAt int64
+	if rec.Code != "" {
+		expiresAt = now.Add(rec.ttl()).UnixMilli()
+	}
+	var retainUntil int64
+	if rec.HistoryTTL > 0 {
+		retainUntil = now.Add(rec.HistoryTTL).UnixMilli()
+	}
+	args := []any{rec.From, sealValue(rec.Code), sealValue(rec.RawContent), rec.ReceivedAt, rec.CacheKey, now.UnixMilli(), expiresAt, retainUntil, rec.Device}
+	id, err := s.insertHistory(ctx, tx, args)
+	if err != nil {
+		return err
+	}
+
+	if rec.Code != "" { // 最新记录只保存带验证码的短信，与 Redis 的 latest_sms 一致
+		if _, err := tx.ExecContext(ctx, s.rebind(s.dialect.upsertLatest), rec.From, id, rec.ReceivedAt); err != nil {
+			return fmt.Errorf("更新最新记录失败: %w", err)
+		}
+	}
+	if smsHistoryMax > 0 {
+		if _, err := tx.ExecContext(ctx, s.rebind(sqlTrimHistory), rec.From, rec.From, smsHistoryMax, rec.From); err != nil {
+			return fmt.Errorf("清理历史失败: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// 扫描查询结果
+func scanSMSRecords(rows *sql.Rows) ([]SMSRecord, error) {
+	defer rows.Close()
+	records := []SMSRecord{}
+	for rows.Next() {
+		var r SMSRecord
+		if err := rows.Scan(&r.ID, &r.From, &r.Code, &r.RawContent, &r.ReceivedAt, &r.CacheKey, &r.CreatedAt, &r.ExpiresAt, &r.Device); err != nil {
+			return nil, err
+		}
+		var err error
+		if r.Code, err = openValue(r.Code); err != nil {
+			return nil, err
+		}
+		if r.RawContent, err = openValue(r.RawContent); err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+	}
+	return records, rows.Err()
+}
+
+// GetLatest 查询号码最新的带验证码记录
+func (s *SQLStore) GetLatest(ctx context.Context, phone string) (*SMSRecord, error) {
+	rows, err := s.db.QueryContext(ctx, s.rebind(sqlSelectHistory+
+		` JOIN sms_latest l ON l.history_id = h.id WHERE l.phone = ?`), phone)
+	if err != nil {
+		return nil, err
+	}
+	records, err := scanSMSRecords(rows)
+	if err != nil || len(records) == 0 {
+		return nil, err
+	}
+	return &records[0], nil
+}
+
+// ListActivePhones 最新记录未过期的号码；cached 为未过期的历史条数，不含验证码的记录 expires_at 为 0
+func (s *SQLStore) ListActivePhones(ctx context.Context) ([]ActivePhone, error) {
+	now := time.Now().UnixMilli()
+	rows, err := s.db.QueryContext(ctx, s.rebind(`SELECT l.phone, h.received_at, h.expires_at,
+		(SELECT COUNT(*) FROM sms_history c WHERE c.phone = l.phone AND c.expires_at > ?)
+		FROM sms_latest l JOIN sms_history h ON h.id = l.history_id
+		WHERE h.expires_at > ? ORDER BY h.received_at DESC, l.phone`), now, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	phones := []ActivePhone{}
+	for rows.Next() {
+		var p ActivePhone
+		if err := rows.Scan(&p.Phone, &p.LatestReceivedAt, &p.ExpiresAt, &p.Cached); err != nil {
+			return nil, err
+		}
+		phones = append(phones, p)
+	}
+	return phones, rows.Err()
+}
+
+// GetHistory 按接收时间倒序查询号码的历史记录；before 不为 0 时只返回早于该时间的记录
+func (s *SQLStore) GetHistory(ctx context.Context, phone string, limit int, before int64) ([]SMSRecord, error) {
+	query := sqlSelectHistory + ` WHERE h.phone = ?`
+	args := []any{phone}
+	if before > 0 {
+		query += ` AND h.received_at < ?`
+		args = append(args, before)
+	}
+	query += ` ORDER BY h.received_at DESC, h.id DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := s.db.QueryContext(ctx, s.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanSMSRecords(rows)
+}
+
+// SearchFilter 历史搜索条件，零值表示不限制
+type SearchFilter struct {
+	From  string // 来源号码前缀
+	Exact bool   // From 需完全一致（启用号码哈希时只能精确匹配哈希）
+	Query string // 验证码或原始内容中包含的文本
+	Since int64  // 接收时间下限（含），毫秒
+	Until int64  // 接收时间上限（不含），毫秒
+
+	AfterAt, AfterID int64 // 翻页游标：只返回按 (received_at, id) 倒序排在该记录之后的记录
+}
+
+// 启用存储加密时最多解密比对的记录数，避免一次搜索扫描整张表
+const searchMaxScan = 10000
+
+// LIKE 模式转义，统一使用 ! 作为转义符，避免各数据库对反斜杠的处理差异
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+// search 按接收时间倒序返回最多 limit 条匹配的历史记录；
+// 启用存储加密时内容为密文，Query 改为分批解密后比对，最多扫描 searchMaxScan 条
+func (s *SQLStore) search(ctx context.Context, f SearchFilter, limit int) ([]SMSRecord, error) {
+	records := []SMSRecord{}
+	err := s.eachMatch(ctx, f, limit, searchMaxScan, func(rec SMSRecord) error {
+		records = append(records, rec)
+		if len(records) == limit {
+			return errStopIteration
+		}
+		return nil
+	})
+	return records, err
+}
+
+// FindByCode 复用搜索的分批遍历，按验证码完整匹配；启用存储加密时同样在服务端解密后比对
+func (s *SQLStore) FindByCode(ctx context.Context, code string, since int64, limit int) ([]SMSRecord, error) {
+	records := []SMSRecord{}
+	err := s.eachMatch(ctx, SearchFilter{Query: code, Since: since}, limit, searchMaxScan, func(rec SMSRecord) error {
+		if rec.Code != code {
+			return nil
+		}
+		records = append(records, rec)
+		if len(records) == limit {
+			return errStopIteration
+		}
+		return nil
+	})
+	return records, err
+}
+
+// 回调返回该错误时停止遍历，eachMatch 本身返回 nil
+var errStopIteration = errors.New("stop iteration")
+
+// eachMatch 按接收时间倒序分批遍历匹配的历史记录；maxScan > 0 时最多读取该条数
+func (s *SQLStore) eachMatch(ctx context.Context, f SearchFilter, batch, maxScan int, fn func(SMSRecord) error) error {
+	where := []string{"1 = 1"}
+	var args []any
+	switch {
+	case f.From != "" && f.Exact:
+		where = append(where, "h.phone = ?")
+		args = append(args, f.From)
+	case f.From != "":
+		where = append(where, "h.phone LIKE ? ESCAPE '!'")
+		args = append(args, likeEscaper.Replace(f.From)+"%")
+	}
+	if f.Since > 0 {
+		where = append(where, "h.received_at >= ?")
+		args = append(args, f.Since)
+	}
+	if f.Until > 0 {
+		where = append(where, "h.received_at < ?")
+		args = append(args, f.Until)
+	}
+	inSQL := f.Query != "" && storageAEAD == nil
+	if inSQL {
+		where = append(where, "(h.code LIKE ? ESCAPE '!' OR h.raw_content LIKE ? ESCAPE '!')")
+		pattern := "%" + likeEscaper.Replace(f.Query) + "%"
+		args = append(args, pattern, pattern)
+	} else if f.Query != "" && batch < 500 {
+		batch = 500 // 需要在服务端比对，每批多取一些
+	}
+
+	lastAt, lastID := f.AfterAt, f.AfterID // 分批读取的游标（received_at, id）
+	for scanned := 0; maxScan <= 0 || scanned < maxScan; {
+		query := sqlSelectHistory + " WHERE " + strings.Join(where, " AND ")
+		batchArgs := append([]any{}, args...)
+		if lastAt > 0 {
+			query += " AND (h.received_at < ? OR (h.received_at = ? AND h.id < ?))"
+			batchArgs = append(batchArgs, lastAt, lastAt, lastID)
+		}
+		query += " ORDER BY h.received_at DESC, h.id DESC LIMIT ?"
+		batchArgs = append(batchArgs, batch)
+
+		rows, err := s.db.QueryContext(ctx, s.rebind(query), batchArgs...)
+		if err != nil {
+			return err
+		}
+		found, err := scanSMSRecords(rows)
+		if err != nil {
+			return err
+		}
+		for _, rec := range found {
+			if inSQL || f.Query == "" || strings.Contains(rec.Code, f.Query) || strings.Contains(rec.RawContent, f.Query) {
+				if err := fn(rec); err == errStopIteration {
+					return nil
+				} else if err != nil {
+					return err
+				}
+			}
+		}
+		if len(found) < batch {
+			return nil
+		}
+		scanned += len(found)
+		lastAt, lastID = found[len(found)-1].ReceivedAt, found[len(found)-1].ID
+	}
+	return nil
+}
+
+// 按 id 顺序取出一批待归档记录：cutoff（毫秒）之前写入的，或保留规则设定的删除时间已过的
+func (s *SQLStore) archiveBatch(ctx context.Context, cutoff, now int64, limit int) ([]SMSRecord, error) {
+	rows, err := s.db.QueryContext(ctx, s.rebind(sqlSelectHistory+
+		` WHERE h.created_at < ? OR (h.retain_until > 0 AND h.retain_until <= ?) ORDER BY h.id LIMIT ?`), cutoff, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanSMSRecords(rows)
+}
+
+// 删除已归档的记录，指向这些记录的最新记录一并删除
+func (s *SQLStore) purgeArchived(ctx context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	in := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, s.rebind(`DELETE FROM sms_latest WHERE history_id IN (`+in+`)`), args...); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, s.rebind(`DELETE FROM sms_history WHERE id IN (`+in+`)`), args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// 删除保留规则设定的删除时间已过的记录，返回删除条数；未启用归档时使用
+func (s *SQLStore) purgeRetained(ctx context.Context, now int64) (int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, s.rebind(`DELETE FROM sms_latest WHERE history_id IN (
+		SELECT id FROM sms_history WHERE retain_until > 0 AND retain_until <= ?)`), now); err != nil {
+		return 0, err
+	}
+	res, err := tx.ExecContext(ctx, s.rebind(`DELETE FROM sms_history WHERE retain_until > 0 AND retain_until <= ?`), now)
+	if err != nil {
+		return 0, err
+	}
+	n, _ := res.RowsAffected()
+	return n, tx.Commit()
+}
+
+// 按接收时间升序遍历全部历史（供迁移使用），保证目标存储的最新记录为最后一条；
+// 没有缓存有效期的记录（不含验证码或导入的记录）ExpiresAt 置为 1，迁移到 Redis 时不再缓存
+func (s *SQLStore) eachRecord(ctx context.Context, fn func(SMSRecord) error) error {
+	var lastAt, lastID int64 = math.MinInt64, 0
+	for {
+		rows, err := s.db.QueryContext(ctx, s.rebind(sqlSelectHistory+
+			` WHERE h.received_at > ? OR (h.received_at = ? AND h.id > ?) ORDER BY h.received_at, h.id LIMIT ?`),
+			lastAt, lastAt, lastID, backupHistoryBatch)
+		if err != nil {
+			return err
+		}
+		records, err := scanSMSRecords(rows)
+		if err != nil {
+			return err
+		}
+		for _, rec := range records {
+			if rec.ExpiresAt == 0 {
+				rec.ExpiresAt = 1
+			}
+			if err := fn(rec); err != nil {
+				return err
+			}
+		}
+		if len(records) < backupHistoryBatch {
+			return nil
+		}
+		last := records[len(records)-1]
+		lastAt, lastID = last.ReceivedAt, last.ID
+	}
+}
+
+// 按 id 顺序分页读取全部历史，用于导出备份
+func (s *SQLStore) exportHistory(ctx context.Context, afterID int64, limit int) ([]SMSRecord, error) {
+	rows, err := s.db.QueryContext(ctx, s.rebind(sqlSelectHistory+` WHERE h.id > ? ORDER BY h.id LIMIT ?`), afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanSMSRecords(rows)
+}
+
+// 导入备份中的历史记录，保留原写入时间，重新分配 id；
+// 号码、接收时间、验证码和原始内容都相同的记录视为已存在，重复导入不会产生重复数据。
+// 启用加密时密文每次都不同，需解密后比较
+func (s *SQLStore) importHistory(ctx context.Context, records []SMSRecord) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	imported := 0
+	for _, rec := range records {
+		rows, err := tx.QueryContext(ctx, s.rebind(sqlSelectHistory+` WHERE h.phone = ? AND h.received_at = ?`),
+			rec.From, rec.ReceivedAt)
+		if err != nil {
+			return imported, err
+		}
+		existing, err := scanSMSRecords(rows)
+		if err != nil {
+			return imported, err
+		}
+		duplicate := false
+		for _, e := range existing {
+			if e.Code == rec.Code && e.RawContent == rec.RawContent {
+				duplicate = true
+				break
+			}
+		}
+		if duplicate {
+			continue
+		}
+
+		createdAt := rec.CreatedAt
+		if createdAt == 0 {
+			createdAt = time.Now().UnixMilli()
+		}
+		// 导入的记录不设缓存有效期，Redis 未命中时不会从 SQL 返回
+		args := []any{rec.From, sealValue(rec.Code), sealValue(rec.RawContent), rec.ReceivedAt, rec.CacheKey, createdAt, int64(0), int64(0), rec.Device}
+		id, err := s.insertHistory(ctx, tx, args)
+		if err != nil {
+			return imported, err
+		}
+		if rec.Code != "" {
+			if _, err := tx.ExecContext(ctx, s.rebind(s.dialect.upsertLatest), rec.From, id, rec.ReceivedAt); err != nil {
+				return imported, fmt.Errorf("更新最新记录失败: %w", err)
+			}
+		}
+		imported++
+	}
+	return imported, tx.Commit()
+}
+
+// ConsumeLatest 按 history_id 条件删除最新记录，并发请求中只有删除成功的一个拿到记录；已过期时不删除，返回 nil
+func (s *SQLStore) ConsumeLatest(ctx context.Context, phone string) (*SMSRecord, error) {
+	rec, err := s.GetLatest(ctx, phone)
+	if err != nil || rec == nil || rec.ExpiresAt <= time.Now().UnixMilli() {
+		return nil, err
+	}
+	res, err := s.db.ExecContext(ctx, s.rebind(`DELETE FROM sms_latest WHERE phone = ? AND history_id = ?`), phone, rec.ID)
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 { // 已被其他请求消费，或期间写入了新记录
+		return nil, nil
+	}
+	return rec, nil
+}
+
+// DeleteRecord 删除号码在 receivedAt 收到的历史记录，指向它的最新记录一并删除
+func (s *SQLStore) DeleteRecord(ctx context.Context, phone string, receivedAt int64) (bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, s.rebind(`DELETE FROM sms_latest WHERE phone = ? AND history_id IN `+
+		`(SELECT id FROM sms_history WHERE phone = ? AND received_at = ?)`), phone, phone, receivedAt); err != nil {
+		return false, err
+	}
+	res, err := tx.ExecContext(ctx, s.rebind(`DELETE FROM sms_history WHERE phone = ? AND received_at = ?`), phone, receivedAt)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, tx.Commit()
+}
+
+// Delete 删除号码的历史记录和最新记录
+func (s *SQLStore) Delete(ctx context.Context, phone string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, s.rebind(`DELETE FROM sms_latest WHERE phone = ?`), phone); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, s.rebind(`DELETE FROM sms_history WHERE phone = ?`), phone); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"database/sql"
+
+	_ "modernc.org/sqlite" // 纯 Go 实现，无需 CGO
+)
+
+/* ---------- SQLite 历史存储 ---------- */
+
+var sqliteDialect = &sqlDialect{
+	name: "SQLite",
+	migrations: []string{
+		// 1: 短信历史表
+		`CREATE TABLE sms_history (
+			id          INTEGER PRIMARY KEY AUTOINCREMENT,
+			phone       TEXT    NOT NULL,
+			code        TEXT    NOT NULL DEFAULT '',
+			raw_content TEXT    NOT NULL,
+			received_at INTEGER NOT NULL,
+			cache_key   TEXT    NOT NULL DEFAULT '',
+			created_at  INTEGER NOT NULL
+		)`,
+		// 2: 按号码查询历史的索引
+		`CREATE INDEX idx_sms_history_phone_time ON sms_history (phone, received_at DESC)`,
+		// 3: 各号码的最新记录
+		`CREATE TABLE sms_latest (
+			phone       TEXT    PRIMARY KEY,
+			history_id  INTEGER NOT NULL,
+			received_at INTEGER NOT NULL
+		)`,
+		// 4: 缓存有效期，Redis 未命中时据此判断 SQL 中的最新记录是否仍有效
+		`ALTER TABLE sms_history ADD COLUMN expires_at BIGINT NOT NULL DEFAULT 0`,
+		// 5: 归档按写入时间取旧记录
+		`CREATE INDEX idx_sms_history_created ON sms_history (created_at)`,
+		// 6: 按来源号码保留规则计算的删除时间，0 表示不过期
+		`ALTER TABLE sms_history ADD COLUMN retain_until BIGINT NOT NULL DEFAULT 0`,
+		// 7: 上报短信的设备 ID，未使用设备令牌时为空
+		`ALTER TABLE sms_history ADD COLUMN device TEXT NOT NULL DEFAULT ''`,
+		// 8: 号码别名
+		`CREATE TABLE phone_aliases (
+			alias      TEXT    NOT NULL PRIMARY KEY,
+			phone      TEXT    NOT NULL,
+			updated_at INTEGER NOT NULL
+		)`,
+	},
+	upsertLatest: `INSERT INTO sms_latest (phone, history_id, received_at) VALUES (?, ?, ?)
+		ON CONFLICT (phone) DO UPDATE SET history_id = excluded.history_id, received_at = excluded.received_at
+		WHERE excluded.received_at >= sms_latest.received_at`,
+}
+
+// 打开 SQLite 数据库文件
+func openSQLiteStore(path string) (*SQLStore, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1) // SQLite 只允许单个写连接，串行化避免 SQLITE_BUSY
+	for _, pragma := range []string{"PRAGMA journal_mode=WAL", "PRAGMA busy_timeout=5000"} {
+		if _, err := db.Exec(pragma); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	return &SQLStore{db: db, dialect: sqliteDialect}, nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+)
+
+/* ---------- 号码统计 ---------- */
+
+// PhoneStats 单个号码的接收统计
+type PhoneStats struct {
+	Phone              string `json:"phone"`
+	Received           int64  `json:"received"`            // 收到的短信总数
+	ExtractionFailures int64  `json:"extraction_failures"` // 未提取到验证码的短信数
+	LastSeen           int64  `json:"last_seen,omitempty"` // 最近一条短信的接收时间（毫秒时间戳）
+}
+
+// 统计 HASH 的字段
+const (
+	statsFieldReceived = "received"
+	statsFieldFailures = "extraction_failures"
+	statsFieldLastSeen = "last_seen"
+)
+
+// 统计保留时长，最近一次收到短信后顺延；0 表示不过期
+var smsStatsTTL = 30 * 24 * time.Hour
+
+// 号码的统计 HASH
+func statsKey(phone string) string {
+	return redisKey("sms_stats:" + phone)
+}
+
+func initStats() {
+	smsStatsTTL = getEnvDuration("SMS_STATS_TTL", 30*24*time.Hour)
+}
+
+// 接收短信时更新计数器，未使用 Redis 时不统计；写入失败只记录日志
+func recordSMSStats(phone string, receivedAt int64, extracted bool) {
+	if rdb == nil {
+		return
+	}
+	ctx := context.Background()
+	key := statsKey(phoneKey(phone))
+	_, err := rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HIncrBy(ctx, key, statsFieldReceived, 1)
+		if !extracted {
+			pipe.HIncrBy(ctx, key, statsFieldFailures, 1)
+		}
+		pipe.HSet(ctx, key, statsFieldLastSeen, receivedAt)
+		if smsStatsTTL > 0 {
+			pipe.Expire(ctx, key, smsStatsTTL)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("更新号码 %s 的统计失败: %v", phone, err)
+	}
+}
+
+// GET /api/stats/:phone
+func getPhoneStats(c *gin.Context) {
+	if rdb == nil {
+		respondError(c, http.StatusNotImplemented, ErrRedisRequired, "号码统计需要使用 Redis 存储", nil)
+		return
+	}
+	phone := resolvePhoneAlias(c.Request.Context(), c.Param("phone"))
+	values, err := rdb.HGetAll(c.Request.Context(), statsKey(phoneKey(phone))).Result()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrInternal, "查询失败", err)
+		return
+	}
+	if len(values) == 0 {
+		respondError(c, http.StatusNotFound, ErrNotFound, "该手机号没有统计记录", nil)
+		return
+	}
+	stats := PhoneStats{Phone: phone}
+	stats.Received, _ = strconv.ParseInt(values[statsFieldReceived], 10, 64)
+	stats.ExtractionFailures, _ = strconv.ParseInt(values[statsFieldFailures], 10, 64)
+	stats.LastSeen, _ = strconv.ParseInt(values[statsFieldLastSeen], 10, 64)
+	c.JSON(http.StatusOK, gin.H{"status": "success", "data": stats})
+}
+
+// GET /api/phones
+// 列出当前有验证码的号码及各号码未过期的验证码条数，供运维查看各号码的接收情况
+func listActivePhonesHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	phones, err := store.ListActivePhones(ctx)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrInternal, "查询失败", err)
+		return
+	}
+	// 附上指向各号码的别名，读取失败不影响列表
+	if aliases, err := listAliases(ctx); err != nil {
+		log.Printf("读取号码别名失败: %v", err)
+	} else if len(aliases) > 0 {
+		byPhone := make(map[string][]string)
+		for _, a := range aliases {
+			key := phoneKey(a.Phone)
+			byPhone[key] = append(byPhone[key], a.Alias)
+		}
+		for i := range phones {
+			phones[i].Aliases = byPhone[phones[i].Phone]
+		}
+	}
+	codes := 0
+	for _, p := range phones {
+		codes += p.Cached
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   gin.H{"total": len(phones), "codes": codes, "items": phones},
+	})
+}
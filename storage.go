@@ -0,0 +1,481 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+/* ---------- 短信存储抽象 ---------- */
+
+// SMSRecord 存储层中的一条短信记录
+type SMSRecord struct {
+	ID         int64  `json:"id,omitempty"` // 仅 SQL 后端有自增主键
+	From       string `json:"from"`
+	Code       string `json:"code"`                  // 不含验证码的短信为空
+	RawContent string `json:"raw_content,omitempty"` // 早期版本写入 Redis 的记录没有原始内容
+	ReceivedAt int64  `json:"received_at"`
+	CacheKey   string `json:"cache_key,omitempty"`
+	CreatedAt  int64  `json:"created_at,omitempty"`
+	Device     string `json:"device,omitempty"` // 上报短信的设备 ID，见 devices.go
+
+	TTL        time.Duration `json:"-"` // 缓存有效期，为 0 时使用 SMS_TTL
+	HistoryTTL time.Duration `json:"-"` // 历史保留时长，由保留规则设置；为 0 时 Redis 使用 SMS_HISTORY_TTL，SQL 不过期
+	ExpiresAt  int64         `json:"-"` // 缓存过期时间（毫秒），从 SQL 读取或迁移时填充；写入时不为 0 则沿用该时间
+}
+
+// 每个号码最多保留的历史条数，0 表示不限制
+var smsHistoryMax int
+
+// 记录的缓存有效期；沿用 ExpiresAt 时可能 <= 0，表示已过期不再缓存
+func (r SMSRecord) ttl() time.Duration {
+	if r.ExpiresAt > 0 {
+		return time.Until(time.UnixMilli(r.ExpiresAt))
+	}
+	if r.TTL > 0 {
+		return r.TTL
+	}
+	return currentTTLSettings().Default
+}
+
+// 记录在 Redis 历史中的保留时长
+func (r SMSRecord) historyTTL() time.Duration {
+	if r.HistoryTTL > 0 {
+		return r.HistoryTTL
+	}
+	return smsHistoryTTL
+}
+
+// 接收短信时请求指定的有效期（秒），不超过 SMS_TTL_MAX；未指定时返回 0 表示使用默认值
+func requestedSMSTTL(seconds int) time.Duration {
+	if seconds <= 0 {
+		return 0
+	}
+	ttl := time.Duration(seconds) * time.Second
+	if max := currentTTLSettings().Max; ttl > max {
+		return max
+	}
+	return ttl
+}
+
+// Storage 短信存储接口，Redis、SQL 等后端各实现一次，处理函数只依赖该接口
+type Storage interface {
+	SaveSMS(ctx context.Context, rec SMSRecord) error
+	GetLatest(ctx context.Context, phone string) (*SMSRecord, error) // 不存在时返回 nil, nil
+	GetHistory(ctx context.Context, phone string, limit int, before int64) ([]SMSRecord, error)
+	Delete(ctx context.Context, phone string) error // 删除号码的全部记录
+	// 删除号码在 receivedAt 收到的一条记录，是最新记录时 GetLatest 随之返回 nil；返回是否找到该记录
+	DeleteRecord(ctx context.Context, phone string, receivedAt int64) (bool, error)
+	// 原子地取出并删除最新记录：并发调用时只有一个能拿到，之后 GetLatest 返回 nil；历史记录保留
+	ConsumeLatest(ctx context.Context, phone string) (*SMSRecord, error)
+	// 按验证码反查接收时间不早于 since 的记录（含已消费的历史），按接收时间倒序最多 limit 条
+	FindByCode(ctx context.Context, code string, since int64, limit int) ([]SMSRecord, error)
+	// 列出当前有未过期验证码（GetLatest 有结果）的号码，按最新验证码的接收时间倒序
+	ListActivePhones(ctx context.Context) ([]ActivePhone, error)
+}
+
+// ActivePhone 当前缓存中有验证码的号码
+type ActivePhone struct {
+	Phone            string   `json:"phone"`                // 存储中的号码，启用号码哈希时为哈希值
+	Cached           int      `json:"cached"`               // 未过期的验证码条数，含比最新一条更早的
+	LatestReceivedAt int64    `json:"latest_received_at"`   // 最新验证码的接收时间（毫秒时间戳）
+	ExpiresAt        int64    `json:"expires_at,omitempty"` // 最新验证码的过期时间（毫秒时间戳）
+	Aliases          []string `json:"aliases,omitempty"`    // 指向该号码的别名
+}
+
+// 按最新验证码的接收时间倒序，相同时按号码排序
+func sortActivePhones(phones []ActivePhone) {
+	sort.Slice(phones, func(i, j int) bool {
+		if phones[i].LatestReceivedAt != phones[j].LatestReceivedAt {
+			return phones[i].LatestReceivedAt > phones[j].LatestReceivedAt
+		}
+		return phones[i].Phone < phones[j].Phone
+	})
+}
+
+// 在可全量遍历的存储中按验证码查找，按接收时间倒序最多 limit 条
+func findByCodeScan(ctx context.Context, src migrationSource, code string, since int64, limit int) ([]SMSRecord, error) {
+	records := []SMSRecord{}
+	err := src.eachRecord(ctx, func(rec SMSRecord) error {
+		if rec.Code == code && rec.ReceivedAt >= since {
+			records = append(records, rec)
+		}
+		return nil
+	})
+	sort.Slice(records, func(i, j int) bool { return records[i].ReceivedAt > records[j].ReceivedAt })
+	if len(records) > limit {
+		records = records[:limit]
+	}
+	return records, err
+}
+
+// 在可全量遍历的存储中统计各号码未过期的验证码；号码是否列出及最新记录以 latest（该存储的 GetLatest）为准
+func listActivePhonesScan(ctx context.Context, src migrationSource, latest func(context.Context, string) (*SMSRecord, error)) ([]ActivePhone, error) {
+	now := time.Now().UnixMilli()
+	counts := map[string]int{}
+	if err := src.eachRecord(ctx, func(rec SMSRecord) error {
+		if rec.Code != "" && rec.ExpiresAt > now {
+			counts[rec.From]++
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	phones := []ActivePhone{}
+	for phone, n := range counts {
+		rec, err := latest(ctx, phone)
+		if err != nil {
+			return nil, err
+		} else if rec == nil { // 最新一条已被取出
+			continue
+		}
+		phones = append(phones, ActivePhone{Phone: phone, Cached: n, LatestReceivedAt: rec.ReceivedAt, ExpiresAt: rec.ExpiresAt})
+	}
+	sortActivePhones(phones)
+	return phones, nil
+}
+
+// 处理函数使用的存储，由 initStorage 根据 STORAGE_BACKEND 选择
+var store Storage
+
+// 短信在缓存中的 key（不含 KEY_PREFIX），同时作为转发投递状态的标识
+func smsCacheKey(phone string, receivedAt int64) string {
+	return fmt.Sprintf("sms:%s:%d", redisHashTag(phone), receivedAt)
+}
+
+// 从短信的 cache key 中解析号码和接收时间，格式不正确时返回 false
+func parseSMSCacheKey(key string) (phone string, receivedAt int64, ok bool) {
+	rest, found := strings.CutPrefix(key, "sms:")
+	i := strings.LastIndexByte(rest, ':')
+	if !found || i <= 0 {
+		return "", 0, false
+	}
+	receivedAt, err := strconv.ParseInt(rest[i+1:], 10, 64)
+	if err != nil {
+		return "", 0, false
+	}
+	phone = strings.TrimSuffix(strings.TrimPrefix(rest[:i], "{"), "}") // 集群模式下的 hash tag
+	return phone, receivedAt, phone != ""
+}
+
+// 存储后端，STORAGE_BACKEND 未配置时若配置了 SQLITE_PATH 视为 sqlite
+func storageBackend() string {
+	backend := strings.ToLower(getEnvWithDefault("STORAGE_BACKEND", ""))
+	if backend == "" && getEnvWithDefault("SQLITE_PATH", "") != "" {
+		backend = "sqlite"
+	}
+	return backend
+}
+
+// 是否需要连接 Redis；bbolt、etcd 模式下完全不依赖 Redis，重试队列、投递状态等功能随之关闭
+func redisRequired() bool {
+	backend := storageBackend()
+	return backend != "bbolt" && backend != "etcd"
+}
+
+// 读取各后端共用的有效期与历史条数配置
+func loadStorageConfig() {
+	setTTLSettings(TTLSettings{
+		Default: getEnvDuration("SMS_TTL", 2*time.Minute),
+		Max:     getEnvDuration("SMS_TTL_MAX", 30*time.Minute),
+	})
+	smsHistoryTTL = getEnvDuration("SMS_HISTORY_TTL", 24*time.Hour)
+	smsHistoryMax, _ = strconv.Atoi(getEnvWithDefault("SMS_HISTORY_MAX", "0"))
+}
+
+// 初始化存储：
+//   - bbolt：单文件嵌入式存储，不使用 Redis
+//   - etcd：以 etcd 为主存储，不使用 Redis
+//   - 其余情况使用 Redis 缓存最新验证码（Redis 不可用时降级到内存）；
+//     STORAGE_BACKEND 为 SQL 后端时同时持久化历史
+func initStorage() {
+	loadStorageConfig()
+
+	backend := storageBackend()
+	if backend == "bbolt" {
+		path := getEnvWithDefault("BBOLT_PATH", "sms.bolt")
+		bs, err := openBoltStorage(path, getEnvDuration("BBOLT_SWEEP_INTERVAL", 30*time.Second))
+		if err != nil {
+			log.Fatalf("bbolt存储初始化失败: %v", err)
+		}
+		store = bs
+		log.Printf("bbolt存储已启用: %s", path)
+		return
+	}
+	if backend == "etcd" {
+		store = &EtcdStorage{client: etcdClient, prefix: etcdPrefix}
+		log.Printf("etcd存储已启用")
+		return
+	}
+
+	var cache Storage = &RedisStorage{client: rdb}
+	if memoryFallbackEnabled() {
+		fb := newFallbackStorage(cache, func(ctx context.Context) error { return rdb.Ping(ctx).Err() })
+		if err := fb.ping(context.Background()); err != nil {
+			fb.markDown(err)
+		}
+		cache = fb
+	}
+
+	if backend == "" || backend == "redis" {
+		store = cache
+		return
+	}
+	durable, version, err := openSQLBackend(backend)
+	if err != nil {
+		log.Fatalf("%s历史存储初始化失败: %v", backend, err)
+	}
+	store = &cachedStorage{
+		cache:           cache,
+		durable:         durable,
+		durableRequired: getEnvWithDefault("STORAGE_DURABLE_REQUIRED", "false") == "true",
+	}
+	log.Printf("%s历史存储已启用 (schema 版本: %d)", durable.dialect.name, version)
+}
+
+// 当前启用的 SQL 历史存储，未启用时返回 nil
+func sqlHistoryStore() *SQLStore {
+	s := store
+	if lc, ok := s.(*latestCache); ok {
+		s = lc.Storage
+	}
+	if cs, ok := s.(*cachedStorage); ok {
+		s, _ := cs.durable.(*SQLStore)
+		return s
+	}
+	return nil
+}
+
+// 按后端名称打开 SQL 存储并执行 schema 迁移，返回当前 schema 版本
+func openSQLBackend(backend string) (*SQLStore, int, error) {
+	var (
+		s   *SQLStore
+		err error
+	)
+	switch backend {
+	case "sqlite":
+		s, err = openSQLiteStore(getEnvWithDefault("SQLITE_PATH", "sms.db"))
+	case "postgres", "postgresql":
+		s, err = openPostgresStore(getEnvWithDefault("POSTGRES_DSN", ""))
+	case "mysql", "mariadb":
+		s, err = openMySQLStore(getEnvWithDefault("MYSQL_DSN", ""))
+	default:
+		return nil, 0, fmt.Errorf("未知的存储后端: %s", backend)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	version, err := s.migrate()
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s迁移失败: %w", s.dialect.name, err)
+	}
+	return s, version, nil
+}
+
+// cachedStorage 组合存储：写入同时落 Redis（快速查询最新验证码）和 SQL（持久化全部历史），
+// Redis 未命中（如 Redis 重启丢数据）时从 SQL 读取仍在有效期内的最新记录并回填缓存
+type cachedStorage struct {
+	cache   Storage
+	durable Storage
+
+	durableRequired bool // SQL 写入失败时是否让接收失败
+}
+
+// SaveSMS 先写缓存，再写历史；默认历史写入失败只记录日志不影响接收
+func (s *cachedStorage) SaveSMS(ctx context.Context, rec SMSRecord) error {
+	if err := s.cache.SaveSMS(ctx, rec); err != nil {
+		return err
+	}
+	if err := s.durable.SaveSMS(ctx, rec); err != nil {
+		if s.durableRequired {
+			return fmt.Errorf("写入短信历史失败: %w", err)
+		}
+		log.Printf("写入短信历史失败: %v", err)
+	}
+	return nil
+}
+
+func (s *cachedStorage) GetLatest(ctx context.Context, phone string) (*SMSRecord, error) {
+	rec, err := s.cache.GetLatest(ctx, phone)
+	if err != nil || rec != nil {
+		return rec, err
+	}
+
+	rec, err = s.durable.GetLatest(ctx, phone)
+	if err != nil || rec == nil {
+		return nil, err
+	}
+	remaining := time.Until(time.UnixMilli(rec.ExpiresAt))
+	if remaining <= 0 { // 已过期，与直接查 Redis 的结果保持一致
+		return nil, nil
+	}
+	if err := s.cache.SaveSMS(ctx, *rec); err != nil { // ExpiresAt 沿用 SQL 中的过期时间
+		log.Printf("回填缓存失败: %v", err)
+	}
+	return rec, nil
+}
+
+func (s *cachedStorage) GetHistory(ctx context.Context, phone string, limit int, before int64) ([]SMSRecord, error) {
+	return s.durable.GetHistory(ctx, phone, limit, before)
+}
+
+func (s *cachedStorage) Delete(ctx context.Context, phone string) error {
+	if err := s.cache.Delete(ctx, phone); err != nil {
+		return err
+	}
+	return s.durable.Delete(ctx, phone)
+}
+
+func (s *cachedStorage) DeleteRecord(ctx context.Context, phone string, receivedAt int64) (bool, error) {
+	cached, err := s.cache.DeleteRecord(ctx, phone, receivedAt)
+	if err != nil {
+		return false, err
+	}
+	durable, err := s.durable.DeleteRecord(ctx, phone, receivedAt)
+	return cached || durable, err
+}
+
+// FindByCode 以 SQL 历史为准，历史保留时间比缓存长
+func (s *cachedStorage) FindByCode(ctx context.Context, code string, since int64, limit int) ([]SMSRecord, error) {
+	return s.durable.FindByCode(ctx, code, since, limit)
+}
+
+// ListActivePhones 以 SQL 为准，缓存未命中时 GetLatest 同样按 SQL 中的过期时间返回
+func (s *cachedStorage) ListActivePhones(ctx context.Context) ([]ActivePhone, error) {
+	return s.durable.ListActivePhones(ctx)
+}
+
+// 历史查询 offset 的上限，更深的翻页使用 before
+const historyMaxOffset = 1000
+
+// ConsumeLatest 以 SQL 的条件删除决定唯一的消费者，再清除缓存；
+// SQL 中没有可消费的记录时才采用缓存的结果，且该记录不在 SQL 历史中（SQL 写入失败），
+// 否则说明已被其他请求经 SQL 消费，避免两个请求拿到同一个验证码
+func (s *cachedStorage) ConsumeLatest(ctx context.Context, phone string) (*SMSRecord, error) {
+	rec, err := s.durable.ConsumeLatest(ctx, phone)
+	if err != nil {
+		return nil, err
+	}
+	cached, err := s.cache.ConsumeLatest(ctx, phone)
+	if err != nil {
+		log.Printf("清除缓存的最新记录失败: %v", err)
+	}
+	if rec != nil || cached == nil {
+		return rec, nil
+	}
+	history, err := s.durable.GetHistory(ctx, phone, 1, cached.ReceivedAt+1)
+	if err != nil {
+		return nil, err
+	}
+	if len(history) > 0 && history[0].ReceivedAt == cached.ReceivedAt {
+		return nil, nil
+	}
+	return cached, nil
+}
+
+// GET /api/sms/:phone/history?limit=20&offset=0&before=<毫秒时间戳>
+// 或 ?limit=20&cursor=<上一页的 next_cursor>
+func getSMSHistory(c *gin.Context) {
+	phone := resolvePhoneAlias(c.Request.Context(), c.Param("phone"))
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 {
+		respondError(c, http.StatusBadRequest, ErrInvalidArgument, "limit 参数错误", nil)
+		return
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		respondError(c, http.StatusBadRequest, ErrInvalidArgument, "offset 参数错误", nil)
+		return
+	} else if offset > historyMaxOffset {
+		respondError(c, http.StatusBadRequest, ErrInvalidArgument, "offset 参数错误", fmt.Sprintf("offset 不能超过 %d，请使用 before 翻页", historyMaxOffset))
+		return
+	}
+	before, err := strconv.ParseInt(c.DefaultQuery("before", "0"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrInvalidArgument, "before 参数错误", nil)
+		return
+	}
+	cursor, ok := bindCursor(c, cursorHistory)
+	if !ok {
+		return
+	}
+	if cursor != nil && (offset > 0 || before > 0) {
+		respondError(c, http.StatusBadRequest, ErrInvalidCursor, "cursor 不能与 offset、before 同时使用", nil)
+		return
+	}
+
+	// 各后端只支持 before 游标，offset 在取出的结果中跳过；多取一条用于判断是否还有下一页
+	fetch := offset + limit + 1
+	if cursor != nil { // 从游标时间（含）开始读取，再跳过同一时间已返回的记录
+		before, fetch = cursor.At+1, limit+1+cursorTieSlack
+	}
+	records, err := store.GetHistory(c.Request.Context(), phoneKey(phone), fetch, before)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrInternal, "查询失败", err)
+		return
+	}
+	if cursor != nil {
+		records = cursor.after(records)
+	} else if offset < len(records) {
+		records = records[offset:]
+	} else {
+		records = []SMSRecord{}
+	}
+	hasMore := len(records) > limit
+	if hasMore {
+		records = records[:limit]
+	}
+	for i := range records {
+		records[i].From = phone
+	}
+
+	pagination := gin.H{"limit": limit, "offset": offset, "has_more": hasMore}
+	if hasMore {
+		pagination["next_before"] = records[len(records)-1].ReceivedAt
+		pagination["next_cursor"] = recordCursor(cursorHistory, records[len(records)-1])
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success", "data": records, "pagination": pagination})
+}
+
+// DELETE /api/sms/:phone
+// 删除号码的全部缓存和历史记录，例如测试用完验证码后清理或出于隐私要求删除
+func deleteSMS(c *gin.Context) {
+	phone := resolvePhoneAlias(c.Request.Context(), c.Param("phone"))
+	if err := store.Delete(c.Request.Context(), phoneKey(phone)); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrInternal, "删除失败", err)
+		return
+	}
+	log.Printf("已删除号码 %s 的全部短信记录", phone)
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+// DELETE /api/sms_cache/:cache_key
+// 按接收接口返回的 cache_key 删除一条记录；删除的是最新记录时，查询最新短信随之返回 404
+func deleteSMSByCacheKey(c *gin.Context) {
+	cacheKey := c.Param("cache_key")
+	phone, receivedAt, ok := parseSMSCacheKey(cacheKey)
+	if !ok {
+		respondError(c, http.StatusBadRequest, ErrInvalidArgument, "cache_key 格式错误", nil)
+		return
+	}
+	found, err := store.DeleteRecord(c.Request.Context(), phone, receivedAt)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrInternal, "删除失败", err)
+		return
+	} else if !found {
+		respondError(c, http.StatusNotFound, ErrSMSNotFound, "未找到该短信记录", nil)
+		return
+	}
+	log.Printf("已删除短信记录 %s", cacheKey)
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
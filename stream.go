@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+/* ---------- SSE 推送新短信 ---------- */
+
+// SSE 心跳间隔，避免代理因连接空闲断开
+const streamHeartbeatInterval = 15 * time.Second
+
+// 每个订阅的缓冲条数，客户端读取过慢时丢弃新消息而不阻塞接收
+const streamBufferSize = 16
+
+// smsStreamHub 按号码向 SSE 订阅者广播新短信
+type smsStreamHub struct {
+	mu   sync.Mutex
+	subs map[string]map[chan SMSRecord]struct{}
+}
+
+var smsStreams = &smsStreamHub{subs: make(map[string]map[chan SMSRecord]struct{})}
+
+func (h *smsStreamHub) subscribe(phone string) chan SMSRecord {
+	ch := make(chan SMSRecord, streamBufferSize)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.subs[phone] == nil {
+		h.subs[phone] = make(map[chan SMSRecord]struct{})
+	}
+	h.subs[phone][ch] = struct{}{}
+	return ch
+}
+
+func (h *smsStreamHub) unsubscribe(phone string, ch chan SMSRecord) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.subs[phone], ch)
+	if len(h.subs[phone]) == 0 {
+		delete(h.subs, phone)
+	}
+}
+
+func (h *smsStreamHub) publish(rec SMSRecord) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subs[rec.From] {
+		select {
+		case ch <- rec:
+		default:
+			log.Printf("SSE 订阅者读取过慢，丢弃短信: %s", rec.CacheKey)
+		}
+	}
+}
+
+// 本实例保存或收到其他实例的新短信后调用，唤醒长轮询请求并推送给 SSE 订阅者
+func notifyNewSMS(rec SMSRecord) {
+	if rec.Code != "" {
+		smsWaiters.notify(rec.From)
+	}
+	smsStreams.publish(rec)
+}
+
+// GET /api/stream/:phone
+// 以 SSE 推送号码此后收到的每条短信（包括不含验证码的），事件名为 sms，id 为接收时间
+func streamSMS(c *gin.Context) {
+	phone := resolvePhoneAlias(c.Request.Context(), c.Param("phone"))
+	key := phoneKey(phone)
+	ch := smsStreams.subscribe(key)
+	defer smsStreams.unsubscribe(key, ch)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // 关闭 nginx 缓冲
+	heartbeat := time.NewTicker(streamHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	fmt.Fprint(c.Writer, ": connected\n\n")
+	c.Writer.Flush()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case rec := <-ch:
+			rec.From = phone // 启用号码哈希时返回请求的号码
+			data, _ := json.Marshal(rec)
+			fmt.Fprintf(w, "id: %d\nevent: sms\ndata: %s\n\n", rec.ReceivedAt, data)
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+		case <-c.Request.Context().Done():
+			return false
+		}
+		return true
+	})
+}
@@ -0,0 +1,373 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+)
+
+/* ---------- 按号码订阅回调 ---------- */
+
+// Subscription 某个号码的回调订阅：收到该号码的验证码时 POST 到 CallbackURL，
+// 请求头带 HMAC-SHA256 签名，密钥为 Secret
+type Subscription struct {
+	ID          string `json:"id"`
+	Phone       string `json:"phone"`
+	CallbackURL string `json:"callback_url"`
+	Secret      string `json:"secret,omitempty"` // 只在创建时返回
+	CreatedAt   int64  `json:"created_at"`
+}
+
+// SubscriptionEvent 回调请求体
+type SubscriptionEvent struct {
+	Event          string `json:"event"` // 固定为 sms.received
+	SubscriptionID string `json:"subscription_id"`
+	Phone          string `json:"phone"`
+	Code           string `json:"code"`
+	RawContent     string `json:"raw_content"`
+	ReceivedAt     int64  `json:"received_at"`
+	CacheKey       string `json:"cache_key"`
+}
+
+// 回调签名请求头：签名内容为 "<时间戳>.<请求体>"
+const (
+	headerSubscriptionSignature = "X-SMS-Signature" // sha256=<hex>
+	headerSubscriptionTimestamp = "X-SMS-Timestamp" // 秒级时间戳
+)
+
+var (
+	subscriptionMaxPerPhone = 10
+	subscriptionRetries     = 3
+	subscriptionBackoff     = time.Second
+	subscriptionClient      = &http.Client{Timeout: 5 * time.Second}
+
+	// 允许指向内网地址的回调主机，来自 SUBSCRIPTION_ALLOWED_HOSTS
+	subscriptionAllowedHosts = make(map[string]bool)
+)
+
+// 回调地址解析到内网、本机或链路本地地址
+var errCallbackPrivate = errors.New("callback_url 不能指向内网、本机或链路本地地址")
+
+// 未使用 Redis 时订阅只保存在本进程内，重启后需重新注册
+var memorySubscriptions = struct {
+	sync.RWMutex
+	m map[string]map[string]Subscription // 号码 → 订阅 ID → 订阅
+}{m: make(map[string]map[string]Subscription)}
+
+// 号码的订阅 HASH，field 为订阅 ID，值为订阅 JSON（启用存储加密时为密文）
+func subscriptionsKey(phone string) string {
+	return redisKey("subscriptions:" + phone)
+}
+
+// 订阅 ID → 号码的索引，用于按 ID 删除
+func subscriptionIndexKey() string {
+	return redisKey("subscription_phones")
+}
+
+func initSubscriptions() {
+	subscriptionMaxPerPhone, _ = strconv.Atoi(getEnvWithDefault("SUBSCRIPTION_MAX_PER_PHONE", "10"))
+	subscriptionRetries, _ = strconv.Atoi(getEnvWithDefault("SUBSCRIPTION_MAX_RETRIES", "3"))
+	subscriptionBackoff = getEnvDuration("SUBSCRIPTION_RETRY_BACKOFF", time.Second)
+	subscriptionAllowedHosts = make(map[string]bool)
+	for _, host := range splitAndTrim(getEnvWithDefault("SUBSCRIPTION_ALLOWED_HOSTS", "")) {
+		subscriptionAllowedHosts[strings.ToLower(host)] = true
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil // 经代理时无法校验实际连接的地址
+	transport.DialContext = dialCallback
+	subscriptionClient = &http.Client{Timeout: getEnvDuration("SUBSCRIPTION_TIMEOUT", 5*time.Second), Transport: transport}
+}
+
+// 不允许回调的目标地址，防止借回调访问内网服务（SSRF）
+func blockedCallbackIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsUnspecified() || ip.IsMulticast()
+}
+
+// 回调连接在解析域名后再校验实际连接的地址，避免创建订阅后域名改为解析到内网地址；
+// SUBSCRIPTION_ALLOWED_HOSTS 中的主机不校验
+func dialCallback(ctx context.Context, network, addr string) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if !subscriptionAllowedHosts[strings.ToLower(host)] {
+		dialer.ControlContext = func(_ context.Context, _, address string, _ syscall.RawConn) error {
+			ip, _, _ := net.SplitHostPort(address)
+			if parsed := net.ParseIP(ip); parsed == nil || blockedCallbackIP(parsed) {
+				return fmt.Errorf("%w: %s", errCallbackPrivate, ip)
+			}
+			return nil
+		}
+	}
+	return dialer.DialContext(ctx, network, addr)
+}
+
+// 随机生成的十六进制字符串
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// 读取号码的全部订阅，phone 为存储中的号码（启用号码哈希时为哈希值）
+func listSubscriptions(ctx context.Context, phone string) ([]Subscription, error) {
+	subs := []Subscription{}
+	if rdb == nil {
+		memorySubscriptions.RLock()
+		defer memorySubscriptions.RUnlock()
+		for _, sub := range memorySubscriptions.m[phone] {
+			subs = append(subs, sub)
+		}
+		sort.Slice(subs, func(i, j int) bool { return subs[i].CreatedAt < subs[j].CreatedAt })
+		return subs, nil
+	}
+	values, err := rdb.HGetAll(ctx, subscriptionsKey(phone)).Result()
+	if err != nil {
+		return nil, err
+	}
+	for id, v := range values {
+		var sub Subscription
+		if err := openJSON(v, &sub); err != nil {
+			log.Printf("跳过无法解析的订阅 %s: %v", id, err)
+			continue
+		}
+		subs = append(subs, sub)
+	}
+	sort.Slice(subs, func(i, j int) bool { return subs[i].CreatedAt < subs[j].CreatedAt })
+	return subs, nil
+}
+
+func saveSubscription(ctx context.Context, phone string, sub Subscription) error {
+	if rdb == nil {
+		memorySubscriptions.Lock()
+		defer memorySubscriptions.Unlock()
+		if memorySubscriptions.m[phone] == nil {
+			memorySubscriptions.m[phone] = make(map[string]Subscription)
+		}
+		memorySubscriptions.m[phone][sub.ID] = sub
+		return nil
+	}
+	// 两个 key 不在同一个 slot，不使用事务
+	_, err := rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, subscriptionsKey(phone), sub.ID, sealJSON(sub))
+		pipe.HSet(ctx, subscriptionIndexKey(), sub.ID, phone)
+		return nil
+	})
+	return err
+}
+
+// 按 ID 删除订阅，不存在时返回 false
+func deleteSubscription(ctx context.Context, id string) (bool, error) {
+	if rdb == nil {
+		memorySubscriptions.Lock()
+		defer memorySubscriptions.Unlock()
+		for phone, subs := range memorySubscriptions.m {
+			if _, ok := subs[id]; ok {
+				delete(subs, id)
+				if len(subs) == 0 {
+					delete(memorySubscriptions.m, phone)
+				}
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	phone, err := rdb.HGet(ctx, subscriptionIndexKey(), id).Result()
+	if err == redis.Nil {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	_, err = rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HDel(ctx, subscriptionsKey(phone), id)
+		pipe.HDel(ctx, subscriptionIndexKey(), id)
+		return nil
+	})
+	return err == nil, err
+}
+
+// 计算回调签名：HMAC-SHA256(secret, "<时间戳>.<请求体>")
+func signSubscriptionPayload(secret, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// 收到验证码后异步回调该号码的全部订阅，回调失败只记录日志，不影响接收和转发
+func notifySubscribers(phone string, msg ForwardMessage) {
+	subs, err := listSubscriptions(context.Background(), phoneKey(phone))
+	if err != nil {
+		log.Printf("读取号码 %s 的订阅失败: %v", phone, err)
+		return
+	}
+	for _, sub := range subs {
+		go deliverSubscription(sub, SubscriptionEvent{
+			Event:          "sms.received",
+			SubscriptionID: sub.ID,
+			Phone:          phone,
+			Code:           msg.Code,
+			RawContent:     msg.RawContent,
+			ReceivedAt:     msg.ReceivedAt,
+			CacheKey:       msg.CacheKey,
+		})
+	}
+}
+
+// 投递一次回调，5xx 与连接失败时复用 Webhook 转发的退避重试
+func deliverSubscription(sub Subscription, event SubscriptionEvent) {
+	payload, _ := json.Marshal(event)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	w := &WebhookForwarder{
+		Headers: map[string]string{
+			headerSubscriptionTimestamp: timestamp,
+			headerSubscriptionSignature: signSubscriptionPayload(sub.Secret, timestamp, payload),
+		},
+		MaxRetries: subscriptionRetries,
+		Backoff:    subscriptionBackoff,
+		client:     subscriptionClient,
+	}
+	if err := w.sendWithRetry(sub.CallbackURL, payload, w.MaxRetries); err != nil {
+		log.Printf("订阅回调失败 (%s %s): %v", sub.ID, sub.CallbackURL, err)
+	}
+}
+
+/* ---------- 订阅接口 ---------- */
+
+// 创建订阅的请求体，未传 secret 时随机生成
+type subscriptionRequest struct {
+	Phone       string `json:"phone" binding:"required"`
+	CallbackURL string `json:"callback_url" binding:"required"`
+	Secret      string `json:"secret"`
+}
+
+// 回调地址必须是 http(s) 绝对地址，且不能解析到内网地址（SUBSCRIPTION_ALLOWED_HOSTS 中的主机除外）
+func validateCallbackURL(ctx context.Context, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("callback_url 必须是 http(s) 地址: %s", raw)
+	}
+	host := u.Hostname()
+	if subscriptionAllowedHosts[strings.ToLower(host)] {
+		return nil
+	}
+	ips := []net.IP{net.ParseIP(host)}
+	if ips[0] == nil {
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return fmt.Errorf("callback_url 的主机无法解析: %s", host)
+		}
+		ips = ips[:0]
+		for _, a := range addrs {
+			ips = append(ips, a.IP)
+		}
+	}
+	for _, ip := range ips {
+		if blockedCallbackIP(ip) {
+			return fmt.Errorf("%w: %s", errCallbackPrivate, host)
+		}
+	}
+	return nil
+}
+
+// POST /api/subscriptions
+// 响应中的 secret 只返回这一次，用于校验回调请求的签名
+func createSubscription(c *gin.Context) {
+	var req subscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, ErrInvalidArgument, "参数错误", err)
+		return
+	}
+	if err := validateCallbackURL(c.Request.Context(), req.CallbackURL); err != nil {
+		respondError(c, http.StatusBadRequest, ErrInvalidArgument, "参数错误", err)
+		return
+	}
+	ctx := c.Request.Context()
+	req.Phone = resolvePhoneAlias(ctx, req.Phone) // 订阅按实际号码匹配收到的短信
+	phone := phoneKey(req.Phone)
+	existing, err := listSubscriptions(ctx, phone)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrInternal, "读取订阅失败", err)
+		return
+	}
+	if subscriptionMaxPerPhone > 0 && len(existing) >= subscriptionMaxPerPhone {
+		respondError(c, http.StatusBadRequest, ErrSubscriptionLimit, fmt.Sprintf("每个号码最多 %d 个订阅", subscriptionMaxPerPhone), nil)
+		return
+	}
+
+	sub := Subscription{
+		ID:          randomHex(8),
+		Phone:       req.Phone,
+		CallbackURL: req.CallbackURL,
+		Secret:      req.Secret,
+		CreatedAt:   time.Now().UnixMilli(),
+	}
+	if sub.Secret == "" {
+		sub.Secret = randomHex(16)
+	}
+	if phoneHashKey != nil { // 隐私模式下不保存原始号码
+		sub.Phone = phone
+	}
+	if err := saveSubscription(ctx, phone, sub); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrInternal, "保存订阅失败", err)
+		return
+	}
+	log.Printf("新增订阅 %s - 号码:%s 回调:%s", sub.ID, req.Phone, sub.CallbackURL)
+	sub.Phone = req.Phone
+	c.JSON(http.StatusCreated, gin.H{"status": "success", "data": sub})
+}
+
+// GET /api/subscriptions?phone=13800138000
+func listSubscriptionsHandler(c *gin.Context) {
+	phone := c.Query("phone")
+	if phone == "" {
+		respondError(c, http.StatusBadRequest, ErrInvalidArgument, "phone 参数不能为空", nil)
+		return
+	}
+	phone = resolvePhoneAlias(c.Request.Context(), phone)
+	subs, err := listSubscriptions(c.Request.Context(), phoneKey(phone))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrInternal, "读取订阅失败", err)
+		return
+	}
+	for i := range subs {
+		subs[i].Phone, subs[i].Secret = phone, ""
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success", "data": subs})
+}
+
+// DELETE /api/subscriptions/:id
+func deleteSubscriptionHandler(c *gin.Context) {
+	found, err := deleteSubscription(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrInternal, "删除订阅失败", err)
+		return
+	}
+	if !found {
+		respondError(c, http.StatusNotFound, ErrNotFound, "订阅不存在", nil)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+/* ---------- 转发消息模板 ---------- */
+
+// 通用默认模板，各通道可在创建时提供自己的默认值
+const (
+	defaultTitleTemplate = "验证码 {{.Code}}"
+	defaultBodyTemplate  = "新验证码: {{.Code}}\n来源: {{.From}}\n时间: {{.Time}}"
+)
+
+// MessageTemplate 转发通道的消息模板（标题 + 正文），基于 text/template
+type MessageTemplate struct {
+	title *template.Template
+	body  *template.Template
+}
+
+// templateFileEntry 模板文件中单个通道的配置
+type templateFileEntry struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+var (
+	templateFileOnce    sync.Once
+	templateFileEntries map[string]templateFileEntry
+
+	// 模板中可用的辅助函数
+	templateFuncs = template.FuncMap{
+		// mask 隐藏号码中间部分，如 138****8000
+		"mask": func(s string) string {
+			r := []rune(s)
+			if len(r) <= 7 {
+				return s
+			}
+			return string(r[:3]) + strings.Repeat("*", len(r)-7) + string(r[len(r)-4:])
+		},
+		// truncate 截断过长的文本
+		"truncate": func(n int, s string) string {
+			r := []rune(s)
+			if len(r) <= n {
+				return s
+			}
+			return string(r[:n]) + "…"
+		},
+	}
+
+	// 启动时用于校验模板的示例消息
+	sampleForwardMessage = ForwardMessage{
+		From:       "13800138000",
+		Code:       "123456",
+		RawContent: "您的验证码是：123456，5分钟内有效",
+		ReceivedAt: 1648888888888,
+		CacheKey:   "sms:13800138000:1648888888888",
+	}
+)
+
+// 读取 MESSAGE_TEMPLATES_FILE 指定的 JSON 模板文件（只读取一次）
+func loadTemplateFile() map[string]templateFileEntry {
+	templateFileOnce.Do(func() {
+		path := getEnvWithDefault("MESSAGE_TEMPLATES_FILE", "")
+		if path == "" {
+			return
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			log.Fatalf("读取模板文件 %s 失败: %v", path, err)
+		}
+		if err := json.Unmarshal(data, &templateFileEntries); err != nil {
+			log.Fatalf("解析模板文件 %s 失败: %v", path, err)
+		}
+		log.Printf("已加载消息模板文件: %s", path)
+	})
+	return templateFileEntries
+}
+
+// 按优先级选择模板文本：通道环境变量 > 模板文件通道项 > 全局环境变量 > 模板文件 default 项 > 通道默认值
+func pickTemplateText(channelEnv, channelFile, globalEnv, defaultFile, fallback string) string {
+	for _, v := range []string{os.Getenv(channelEnv), channelFile, os.Getenv(globalEnv), defaultFile} {
+		if v != "" {
+			return strings.ReplaceAll(v, `\n`, "\n") // 允许 .env 中用 \n 换行
+		}
+	}
+	return fallback
+}
+
+// 解析并用示例消息试渲染，确保启动时就发现模板错误
+func mustParseTemplate(name, text string) *template.Template {
+	tmpl, err := template.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		log.Fatalf("消息模板 %s 解析失败: %v", name, err)
+	}
+	if err := tmpl.Execute(&bytes.Buffer{}, sampleForwardMessage); err != nil {
+		log.Fatalf("消息模板 %s 校验失败: %v", name, err)
+	}
+	return tmpl
+}
+
+// loadMessageTemplate 加载通道的标题和正文模板。
+// channel 为通道标识（如 telegram），对应环境变量 TELEGRAM_TITLE_TEMPLATE / TELEGRAM_TEMPLATE。
+// 各通道在 setup 方法中调用，环境变量配置和 Apprise URL 创建的实例都要经过 setup，否则没有模板可渲染
+func loadMessageTemplate(channel, defaultTitle, defaultBody string) *MessageTemplate {
+	file := loadTemplateFile()
+	prefix := strings.ToUpper(channel)
+
+	titleText := pickTemplateText(prefix+"_TITLE_TEMPLATE", file[channel].Title,
+		"MESSAGE_TITLE_TEMPLATE", file["default"].Title, defaultTitle)
+	bodyText := pickTemplateText(prefix+"_TEMPLATE", file[channel].Body,
+		"MESSAGE_TEMPLATE", file["default"].Body, defaultBody)
+
+	return &MessageTemplate{
+		title: mustParseTemplate(channel+".title", titleText),
+		body:  mustParseTemplate(channel+".body", bodyText),
+	}
+}
+
+// Render 渲染标题和正文
+func (t *MessageTemplate) Render(msg ForwardMessage) (title, body string, err error) {
+	var buf bytes.Buffer
+	if err := t.title.Execute(&buf, msg); err != nil {
+		return "", "", fmt.Errorf("渲染标题模板失败: %w", err)
+	}
+	title = buf.String()
+
+	buf.Reset()
+	if err := t.body.Execute(&buf, msg); err != nil {
+		return "", "", fmt.Errorf("渲染正文模板失败: %w", err)
+	}
+	return title, buf.String(), nil
+}
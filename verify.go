@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+)
+
+/* ---------- 验证码校验 ---------- */
+
+// VerifyRequest 校验请求：后端只需确认用户提交的验证码是否正确，不必读出验证码
+type VerifyRequest struct {
+	Phone   string `json:"phone" binding:"required"`
+	Code    string `json:"code" binding:"required"`
+	Consume bool   `json:"consume"` // 校验通过后取出验证码，同一验证码只能通过一次
+}
+
+// 同一条验证码允许的失败次数，超过后返回 429，直到收到新的验证码；0 为不限制
+var verifyMaxAttempts = 5
+
+// 未使用 Redis 时失败次数只记录在本进程内
+var memoryVerifyAttempts = struct {
+	sync.Mutex
+	m map[string]verifyAttempts
+}{m: make(map[string]verifyAttempts)}
+
+type verifyAttempts struct {
+	count     int64
+	expiresAt time.Time
+}
+
+func initVerify() {
+	verifyMaxAttempts, _ = strconv.Atoi(getEnvWithDefault("VERIFY_MAX_ATTEMPTS", "5"))
+}
+
+// 失败次数按验证码计数（号码 + 接收时间），收到新验证码后重新计数；
+// 过期时间取可指定的最大有效期，保证不早于验证码本身过期
+func verifyAttemptsKey(phone string, receivedAt int64) string {
+	return redisKey("verify_attempts:" + phone + ":" + strconv.FormatInt(receivedAt, 10))
+}
+
+// 读取失败次数及计数重置时间，尚未失败过时重置时间为零值
+func getVerifyFailures(ctx context.Context, key string) (int64, time.Time, error) {
+	if rdb == nil {
+		memoryVerifyAttempts.Lock()
+		defer memoryVerifyAttempts.Unlock()
+		a, ok := memoryVerifyAttempts.m[key]
+		if !ok || time.Now().After(a.expiresAt) {
+			delete(memoryVerifyAttempts.m, key)
+			return 0, time.Time{}, nil
+		}
+		return a.count, a.expiresAt, nil
+	}
+	var get *redis.StringCmd
+	var pttl *redis.DurationCmd
+	_, err := rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		get = pipe.Get(ctx, key)
+		pttl = pipe.PTTL(ctx, key)
+		return nil
+	})
+	if err == redis.Nil {
+		return 0, time.Time{}, nil
+	} else if err != nil {
+		return 0, time.Time{}, err
+	}
+	n, err := get.Int64()
+	return n, time.Now().Add(pttl.Val()), err
+}
+
+// 记录一次失败，返回新的失败次数及计数重置时间；计数从第一次失败开始，窗口为可指定的最大有效期
+func recordVerifyFailure(ctx context.Context, key string) (int64, time.Time) {
+	ttl := currentTTLSettings().Max
+	now := time.Now()
+	if rdb == nil {
+		memoryVerifyAttempts.Lock()
+		defer memoryVerifyAttempts.Unlock()
+		if len(memoryVerifyAttempts.m) >= 10000 { // 清理已过期的计数，避免无限增长
+			for k, v := range memoryVerifyAttempts.m {
+				if now.After(v.expiresAt) {
+					delete(memoryVerifyAttempts.m, k)
+				}
+			}
+		}
+		a := memoryVerifyAttempts.m[key]
+		if a.count == 0 || now.After(a.expiresAt) {
+			a = verifyAttempts{expiresAt: now.Add(ttl)}
+		}
+		a.count++
+		memoryVerifyAttempts.m[key] = a
+		return a.count, a.expiresAt
+	}
+	var incr *redis.IntCmd
+	var pttl *redis.DurationCmd
+	if _, err := rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		incr = pipe.Incr(ctx, key)
+		pttl = pipe.PTTL(ctx, key)
+		return nil
+	}); err != nil {
+		log.Printf("记录验证失败次数失败: %v", err)
+		return 0, now.Add(ttl)
+	}
+	if pttl.Val() <= 0 { // 第一次失败，开始计时
+		rdb.PExpire(ctx, key, ttl)
+		return incr.Val(), now.Add(ttl)
+	}
+	return incr.Val(), now.Add(pttl.Val())
+}
+
+// 失败次数对应的限流状态
+func verifyRateLimit(failures int64, reset time.Time) RateLimitInfo {
+	if reset.IsZero() {
+		reset = time.Now().Add(currentTTLSettings().Max)
+	}
+	return RateLimitInfo{Limit: verifyMaxAttempts, Remaining: verifyMaxAttempts - int(failures), Reset: reset}
+}
+
+// POST /api/verify_code {"phone": "13800138000", "code": "123456", "consume": true}
+// 校验提交的验证码是否与该号码当前缓存的验证码一致；未收到验证码或已过期时同样返回 valid=false
+func verifyCode(c *gin.Context) {
+	var req VerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, ErrInvalidArgument, "参数错误", err)
+		return
+	}
+	ctx := c.Request.Context()
+	req.Phone = resolvePhoneAlias(ctx, req.Phone)
+	phone := phoneKey(req.Phone)
+
+	rec, err := store.GetLatest(ctx, phone)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrInternal, "查询失败", err)
+		return
+	}
+	result := gin.H{"phone": req.Phone, "valid": false, "consumed": false}
+	if rec == nil {
+		c.JSON(http.StatusOK, gin.H{"status": "success", "data": result})
+		return
+	}
+
+	// 限制同一条验证码的失败次数，响应头带 X-RateLimit-*
+	attemptsKey := verifyAttemptsKey(phone, rec.ReceivedAt)
+	if verifyMaxAttempts > 0 {
+		failures, reset, err := getVerifyFailures(ctx, attemptsKey)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, ErrInternal, "查询失败", err)
+			return
+		}
+		if failures >= int64(verifyMaxAttempts) {
+			respondRateLimited(c, verifyRateLimit(failures, reset), "验证失败次数过多，请重新获取验证码")
+			return
+		}
+		setRateLimitHeaders(c, verifyRateLimit(failures, reset))
+	}
+
+	if subtle.ConstantTimeCompare([]byte(req.Code), []byte(rec.Code)) != 1 {
+		if verifyMaxAttempts > 0 {
+			setRateLimitHeaders(c, verifyRateLimit(recordVerifyFailure(ctx, attemptsKey)))
+		}
+		log.Printf("验证码校验失败 - 来源:%s", req.Phone)
+		c.JSON(http.StatusOK, gin.H{"status": "success", "data": result})
+		return
+	}
+
+	if req.Consume {
+		// 取出时可能已被其他请求消费或被更新的验证码替换，只有取出的仍是这条验证码才算通过
+		consumed, err := store.ConsumeLatest(ctx, phone)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, ErrInternal, "查询失败", err)
+			return
+		}
+		if consumed == nil || consumed.ReceivedAt != rec.ReceivedAt || consumed.Code != rec.Code {
+			c.JSON(http.StatusOK, gin.H{"status": "success", "data": result})
+			return
+		}
+		result["consumed"] = true
+	}
+	result["valid"] = true
+	log.Printf("验证码校验通过 - 来源:%s 已消费:%v", req.Phone, req.Consume)
+	c.JSON(http.StatusOK, gin.H{"status": "success", "data": result})
+}
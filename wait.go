@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+/* ---------- 长轮询等待新验证码 ---------- */
+
+// 未收到通知时重新查询存储的间隔，兜底未启用事件广播时其他实例写入的短信
+const waitSMSPollInterval = time.Second
+
+// smsWaitHub 按号码通知等待中的请求：每个号码一个通道，有新短信时关闭并替换
+type smsWaitHub struct {
+	mu      sync.Mutex
+	chans   map[string]chan struct{}
+	waiting map[string]int // 号码的等待请求数，降为 0 时删除通道
+}
+
+var smsWaiters = &smsWaitHub{chans: make(map[string]chan struct{}), waiting: make(map[string]int)}
+
+// 登记一个等待请求，结束时需调用 leave
+func (h *smsWaitHub) join(phone string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.waiting[phone]++
+}
+
+func (h *smsWaitHub) leave(phone string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.waiting[phone]--; h.waiting[phone] <= 0 {
+		delete(h.waiting, phone)
+		delete(h.chans, phone)
+	}
+}
+
+// 返回号码的下一次通知通道，需在查询存储之前获取，避免错过两者之间写入的短信
+func (h *smsWaitHub) wait(phone string) <-chan struct{} {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ch, ok := h.chans[phone]
+	if !ok {
+		ch = make(chan struct{})
+		h.chans[phone] = ch
+	}
+	return ch
+}
+
+// 唤醒号码的全部等待请求；无人等待时不做任何事
+func (h *smsWaitHub) notify(phone string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if ch, ok := h.chans[phone]; ok {
+		close(ch)
+		delete(h.chans, phone)
+	}
+}
+
+// GET /api/wait_sms/:phone?timeout=60&after=<毫秒时间戳>
+// 阻塞直到号码收到接收时间晚于 after 的验证码（after 默认为请求时间）或超时；超时返回 404
+func waitSMS(c *gin.Context) {
+	phone := resolvePhoneAlias(c.Request.Context(), c.Param("phone"))
+	timeoutSec, err := strconv.Atoi(c.DefaultQuery("timeout", "30"))
+	if err != nil || timeoutSec <= 0 {
+		respondError(c, http.StatusBadRequest, ErrInvalidArgument, "timeout 参数错误", nil)
+		return
+	}
+	after := time.Now().UnixMilli()
+	if s := c.Query("after"); s != "" {
+		if after, err = strconv.ParseInt(s, 10, 64); err != nil {
+			respondError(c, http.StatusBadRequest, ErrInvalidArgument, "after 参数错误", nil)
+			return
+		}
+	}
+
+	rec, err := waitForSMS(c.Request.Context(), phone, after, time.Duration(timeoutSec)*time.Second)
+	if err != nil {
+		if c.Request.Context().Err() == nil {
+			respondError(c, http.StatusInternalServerError, ErrInternal, "查询失败", err)
+		}
+		return
+	} else if rec == nil {
+		respondError(c, http.StatusNotFound, ErrWaitTimeout, "等待超时，未收到新的验证码", nil)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success", "data": smsResponse(c, phone, rec)})
+}
+
+// 等待号码收到接收时间晚于 after 的验证码，超时返回 nil, nil；timeout 不超过 WAIT_SMS_MAX_TIMEOUT。
+// REST 与 gRPC 接口共用，返回的记录 From 为存储中的号码（启用号码哈希时为哈希）
+func waitForSMS(ctx context.Context, phone string, after int64, timeout time.Duration) (*SMSRecord, error) {
+	if max := getEnvDuration("WAIT_SMS_MAX_TIMEOUT", 2*time.Minute); timeout > max {
+		timeout = max
+	}
+	key := phoneKey(phone)
+	smsWaiters.join(key)
+	defer smsWaiters.leave(key)
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	poll := time.NewTicker(waitSMSPollInterval)
+	defer poll.Stop()
+	for {
+		notified := smsWaiters.wait(key)
+		rec, err := store.GetLatest(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		if rec != nil && rec.ReceivedAt > after {
+			return rec, nil
+		}
+
+		select {
+		case <-notified:
+		case <-poll.C:
+		case <-deadline.C:
+			return nil, nil
+		case <-ctx.Done(): // 客户端已断开
+			return nil, ctx.Err()
+		}
+	}
+}